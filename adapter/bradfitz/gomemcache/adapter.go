@@ -0,0 +1,200 @@
+package adapter
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"time"
+
+	"github.com/bradfitz/gomemcache/memcache"
+
+	"github.com/dtrugman/cachehit/codec"
+	"github.com/dtrugman/cachehit/internal"
+)
+
+const (
+	DefaultExpiration = 0 * time.Second
+
+	// relativeExpirationLimit is the longest expiration memcached accepts as relative,
+	// longer ones are interpreted as unix timestamps.
+	relativeExpirationLimit = 30 * 24 * time.Hour
+)
+
+type options struct {
+	expiration time.Duration
+	codec      any
+
+	compression          codec.Compression
+	compressionThreshold int
+
+	isNotFound internal.NotFoundClassifier
+}
+
+func defaultOptions() *options {
+	return &options{
+		expiration: DefaultExpiration,
+	}
+}
+
+func compileOptions(opts ...Option) *options {
+	o := defaultOptions()
+
+	for _, opt := range opts {
+		opt(o)
+	}
+
+	return o
+}
+
+type Option func(*options)
+
+// WithExpiration configures the adapter to expire values after the specified
+// amount of time. Memcached expirations have a resolution of a second,
+// so shorter expirations are rounded up.
+func WithExpiration(expiration time.Duration) Option {
+	return func(o *options) {
+		o.expiration = expiration
+	}
+}
+
+// WithCodec configures the adapter to serialize values using the specified codec.
+// The codec value type must match the adapter value type.
+// Defaults to codec.Default.
+func WithCodec[V any](c codec.Codec[V]) Option {
+	return func(o *options) {
+		o.codec = c
+	}
+}
+
+// WithCompression configures the adapter to compress values whose encoded size
+// is at least threshold bytes (see codec.Compressed).
+func WithCompression(compression codec.Compression, threshold int) Option {
+	return func(o *options) {
+		o.compression = compression
+		o.compressionThreshold = threshold
+	}
+}
+
+// WithNotFoundClassifier configures the adapter to return ErrNotFound for errors
+// classified as not found by the specified classifier, e.g. errors returned by
+// proxies for missing keys, or by codecs for tombstone values.
+func WithNotFoundClassifier(isNotFound internal.NotFoundClassifier) Option {
+	return func(o *options) {
+		o.isNotFound = isNotFound
+	}
+}
+
+type Memcache[K comparable, V any] struct {
+	underlying *memcache.Client
+	expiration time.Duration
+	codec      codec.Codec[V]
+	isNotFound internal.NotFoundClassifier
+}
+
+// From creates a new Memcached adapter over the specified client.
+// Keys are formatted using %v, and must be valid memcached keys
+// (up to 250 bytes, without spaces or control characters).
+func From[K comparable, V any](
+	underlying *memcache.Client,
+	opts ...Option,
+) (*Memcache[K, V], error) {
+	o := compileOptions(opts...)
+
+	var valueCodec codec.Codec[V]
+	if o.codec == nil {
+		valueCodec = codec.Default[V]()
+	} else if c, ok := o.codec.(codec.Codec[V]); ok {
+		valueCodec = c
+	} else {
+		var v V
+		return nil, fmt.Errorf("options: codec: expected codec for %T: found %T", v, o.codec)
+	}
+
+	// Always wrap, so compressed values can be read even if compression is disabled
+	valueCodec = codec.Compressed(valueCodec, o.compression, o.compressionThreshold)
+
+	return &Memcache[K, V]{
+		underlying: underlying,
+		expiration: o.expiration,
+		codec:      valueCodec,
+		isNotFound: o.isNotFound,
+	}, nil
+}
+
+func (m *Memcache[K, V]) notFound(err error) bool {
+	return err != nil && m.isNotFound != nil && m.isNotFound(err)
+}
+
+// memcacheExpiration converts the specified expiration to the memcached format.
+func memcacheExpiration(expiration time.Duration, now time.Time) int32 {
+	if expiration <= 0 {
+		return 0
+	}
+
+	if expiration > relativeExpirationLimit {
+		return int32(min(now.Add(expiration).Unix(), math.MaxInt32))
+	}
+
+	return int32((expiration + time.Second - 1) / time.Second)
+}
+
+func (m *Memcache[K, V]) Get(_ context.Context, key K) (V, error) {
+	var zero V
+
+	keyStr := fmt.Sprintf("%v", key)
+
+	item, err := m.underlying.Get(keyStr)
+	if errors.Is(err, memcache.ErrCacheMiss) || m.notFound(err) {
+		return zero, internal.ErrNotFound
+	} else if err != nil {
+		return zero, fmt.Errorf("get: %w", err)
+	}
+
+	value, err := m.codec.Decode(item.Value)
+	if m.notFound(err) {
+		return zero, internal.ErrNotFound
+	} else if err != nil {
+		return zero, fmt.Errorf("parse value: %w", err)
+	}
+
+	return value, nil
+}
+
+func (m *Memcache[K, V]) Set(ctx context.Context, key K, value V) error {
+	return m.SetWithTTL(ctx, key, value, m.expiration)
+}
+
+// SetWithTTL stores the value using the specified expiration instead of the configured one.
+func (m *Memcache[K, V]) SetWithTTL(_ context.Context, key K, value V, ttl time.Duration) error {
+	keyStr := fmt.Sprintf("%v", key)
+
+	rawValue, err := m.codec.Encode(value)
+	if err != nil {
+		return fmt.Errorf("marshal value: %w", err)
+	}
+
+	item := &memcache.Item{
+		Key:        keyStr,
+		Value:      rawValue,
+		Expiration: memcacheExpiration(ttl, time.Now()),
+	}
+
+	if err := m.underlying.Set(item); err != nil {
+		return fmt.Errorf("set: %w", err)
+	}
+
+	return nil
+}
+
+func (m *Memcache[K, V]) Delete(_ context.Context, key K) error {
+	keyStr := fmt.Sprintf("%v", key)
+
+	// Deleting a missing key isn't an error, same as in other adapters
+	err := m.underlying.Delete(keyStr)
+	if err != nil && !errors.Is(err, memcache.ErrCacheMiss) {
+		return fmt.Errorf("delete: %w", err)
+	}
+
+	return nil
+}
@@ -0,0 +1,243 @@
+package adapter
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/bradfitz/gomemcache/memcache"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/require"
+
+	"github.com/dtrugman/cachehit"
+	"github.com/dtrugman/cachehit/codec"
+	"github.com/dtrugman/cachehit/example/resource"
+)
+
+func TestFrom(t *testing.T) {
+	client := memcache.New("127.0.0.1:11211")
+
+	adapter, err := From[string, int](client)
+	require.NoError(t, err)
+	require.NotNil(t, adapter)
+	require.Equal(t, client, adapter.underlying)
+	require.Equal(t, DefaultExpiration, adapter.expiration)
+	require.Equal(t, codec.Compressed(codec.Default[int](), codec.CompressionNone, 0), adapter.codec)
+}
+
+func TestFrom_WithOptions(t *testing.T) {
+	client := memcache.New("127.0.0.1:11211")
+
+	adapter, err := From[string, int](client,
+		WithExpiration(time.Minute),
+		WithCodec(codec.Gob[int]()),
+		WithCompression(codec.CompressionZstd, 1024))
+	require.NoError(t, err)
+	require.NotNil(t, adapter)
+	require.Equal(t, time.Minute, adapter.expiration)
+	require.Equal(t, codec.Compressed(codec.Gob[int](), codec.CompressionZstd, 1024), adapter.codec)
+}
+
+func TestFrom_WithCodecTypeMismatch(t *testing.T) {
+	client := memcache.New("127.0.0.1:11211")
+
+	_, err := From[string, int](client, WithCodec(codec.Gob[string]()))
+	require.ErrorContains(t, err, "codec")
+}
+
+func TestMemcache_Cache(t *testing.T) {
+	var _ cachehit.Cache[string, int] = (*Memcache[string, int])(nil)
+	var _ cachehit.Deleter[string] = (*Memcache[string, int])(nil)
+	var _ cachehit.TTLSetter[string, int] = (*Memcache[string, int])(nil)
+}
+
+func TestMemcacheExpiration(t *testing.T) {
+	now := time.Unix(1700000000, 0)
+
+	tests := []struct {
+		expiration time.Duration
+		expected   int32
+	}{
+		{0, 0},
+		{-time.Second, 0},
+		{time.Millisecond, 1},
+		{time.Second, 1},
+		{1500 * time.Millisecond, 2},
+		{time.Hour, 3600},
+		{30 * 24 * time.Hour, 30 * 24 * 3600},
+		{31 * 24 * time.Hour, int32(now.Unix()) + 31*24*3600},
+	}
+
+	for _, test := range tests {
+		require.Equal(t, test.expected, memcacheExpiration(test.expiration, now), test.expiration)
+	}
+}
+
+func TestMemcache_NotFoundClassifier(t *testing.T) {
+	ctx := context.Background()
+
+	disconnectedClient := memcache.New("localhost:9999")
+
+	adapter, err := From[string, string](disconnectedClient)
+	require.NoError(t, err)
+	_, err = adapter.Get(ctx, uuid.New().String())
+	require.Error(t, err)
+	require.NotErrorIs(t, err, cachehit.ErrNotFound)
+
+	adapter, err = From[string, string](disconnectedClient, WithNotFoundClassifier(func(err error) bool {
+		return true
+	}))
+	require.NoError(t, err)
+	_, err = adapter.Get(ctx, uuid.New().String())
+	require.ErrorIs(t, err, cachehit.ErrNotFound)
+}
+
+func TestMemcache_Operations(t *testing.T) {
+	ctx := context.Background()
+
+	instance, err := resource.MemcachedRun(ctx)
+	require.NoError(t, err)
+
+	t.Cleanup(func() {
+		require.NoError(t, instance.Cleanup())
+	})
+
+	client, err := resource.MemcachedConn(instance.Addr)
+	require.NoError(t, err)
+
+	t.Cleanup(func() {
+		client.Close()
+	})
+
+	t.Run("SetAndGet", func(t *testing.T) {
+		key := uuid.New().String()
+		adapter, err := From[string, string](client)
+		require.NoError(t, err)
+
+		require.NoError(t, adapter.Set(ctx, key, "value1"))
+
+		value, err := adapter.Get(ctx, key)
+		require.NoError(t, err)
+		require.Equal(t, "value1", value)
+	})
+
+	t.Run("GetNonExistent", func(t *testing.T) {
+		adapter, err := From[string, string](client)
+		require.NoError(t, err)
+
+		_, err = adapter.Get(ctx, uuid.New().String())
+		require.ErrorIs(t, err, cachehit.ErrNotFound)
+	})
+
+	t.Run("Struct", func(t *testing.T) {
+		type user struct {
+			Name string
+			Age  int
+		}
+
+		key := uuid.New().String()
+		adapter, err := From[string, user](client)
+		require.NoError(t, err)
+
+		require.NoError(t, adapter.Set(ctx, key, user{Name: "name", Age: 42}))
+
+		value, err := adapter.Get(ctx, key)
+		require.NoError(t, err)
+		require.Equal(t, user{Name: "name", Age: 42}, value)
+	})
+
+	t.Run("IntKey", func(t *testing.T) {
+		adapter, err := From[int, string](client)
+		require.NoError(t, err)
+
+		require.NoError(t, adapter.Set(ctx, 123456789, "value1"))
+
+		value, err := adapter.Get(ctx, 123456789)
+		require.NoError(t, err)
+		require.Equal(t, "value1", value)
+
+		item, err := client.Get("123456789")
+		require.NoError(t, err)
+		require.Equal(t, "value1", string(item.Value))
+	})
+
+	t.Run("Compression", func(t *testing.T) {
+		key := uuid.New().String()
+		adapter, err := From[string, string](client, WithCompression(codec.CompressionGzip, 64))
+		require.NoError(t, err)
+
+		value := strings.Repeat("compressible ", 100)
+		require.NoError(t, adapter.Set(ctx, key, value))
+
+		item, err := client.Get(key)
+		require.NoError(t, err)
+		require.Less(t, len(item.Value), len(value))
+
+		actual, err := adapter.Get(ctx, key)
+		require.NoError(t, err)
+		require.Equal(t, value, actual)
+	})
+
+	t.Run("ParseError", func(t *testing.T) {
+		key := uuid.New().String()
+		adapter, err := From[string, int](client)
+		require.NoError(t, err)
+
+		require.NoError(t, client.Set(&memcache.Item{Key: key, Value: []byte("not a number")}))
+
+		_, err = adapter.Get(ctx, key)
+		require.ErrorContains(t, err, "parse value")
+	})
+
+	t.Run("MalformedKey", func(t *testing.T) {
+		adapter, err := From[string, string](client)
+		require.NoError(t, err)
+
+		err = adapter.Set(ctx, "key with spaces", "value1")
+		require.ErrorIs(t, err, memcache.ErrMalformedKey)
+	})
+
+	t.Run("Delete", func(t *testing.T) {
+		key := uuid.New().String()
+		adapter, err := From[string, string](client)
+		require.NoError(t, err)
+
+		require.NoError(t, adapter.Set(ctx, key, "value1"))
+		require.NoError(t, adapter.Delete(ctx, key))
+		require.NoError(t, adapter.Delete(ctx, key))
+
+		_, err = adapter.Get(ctx, key)
+		require.ErrorIs(t, err, cachehit.ErrNotFound)
+	})
+
+	t.Run("Expiration", func(t *testing.T) {
+		key := uuid.New().String()
+		adapter, err := From[string, string](client, WithExpiration(1*time.Second))
+		require.NoError(t, err)
+
+		require.NoError(t, adapter.Set(ctx, key, "value1"))
+
+		value, err := adapter.Get(ctx, key)
+		require.NoError(t, err)
+		require.Equal(t, "value1", value)
+
+		time.Sleep(2100 * time.Millisecond)
+
+		_, err = adapter.Get(ctx, key)
+		require.ErrorIs(t, err, cachehit.ErrNotFound)
+	})
+
+	t.Run("SetWithTTL", func(t *testing.T) {
+		key := uuid.New().String()
+		adapter, err := From[string, string](client)
+		require.NoError(t, err)
+
+		require.NoError(t, adapter.SetWithTTL(ctx, key, "value1", 1*time.Second))
+
+		time.Sleep(2100 * time.Millisecond)
+
+		_, err = adapter.Get(ctx, key)
+		require.ErrorIs(t, err, cachehit.ErrNotFound)
+	})
+}
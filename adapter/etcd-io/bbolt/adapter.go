@@ -0,0 +1,538 @@
+package adapter
+
+import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"os"
+	"sync"
+	"time"
+
+	bolt "go.etcd.io/bbolt"
+
+	"github.com/dtrugman/cachehit/codec"
+	"github.com/dtrugman/cachehit/internal"
+)
+
+const (
+	DefaultExpiration         = 0 * time.Second
+	DefaultMaxEntries         = 0
+	DefaultCompactionInterval = 0 * time.Second
+)
+
+var (
+	// valuesBucket maps keys to records, and its sequence holds the number of entries.
+	valuesBucket = []byte("values")
+	// orderBucket maps write sequence numbers to keys, oldest first.
+	orderBucket = []byte("order")
+)
+
+// recordHeaderSize is the expiration time in unix milliseconds (0 if never),
+// followed by the write sequence number of the entry.
+const recordHeaderSize = 16
+
+// compactionSuffix is appended to the database path while it is being compacted.
+const compactionSuffix = ".compact"
+
+type options struct {
+	expiration         time.Duration
+	maxEntries         int
+	compactionInterval time.Duration
+	codec              any
+
+	compression          codec.Compression
+	compressionThreshold int
+
+	isNotFound internal.NotFoundClassifier
+
+	errorCallback func(err error)
+}
+
+func (o *options) Validate() error {
+	if o.maxEntries < 0 {
+		return fmt.Errorf("max entries must not be negative")
+	}
+
+	if o.compactionInterval < time.Duration(0) {
+		return fmt.Errorf("compaction interval must not be negative")
+	}
+
+	return nil
+}
+
+func defaultOptions() *options {
+	return &options{
+		expiration:         DefaultExpiration,
+		maxEntries:         DefaultMaxEntries,
+		compactionInterval: DefaultCompactionInterval,
+	}
+}
+
+func compileOptions(opts ...Option) *options {
+	o := defaultOptions()
+
+	for _, opt := range opts {
+		opt(o)
+	}
+
+	return o
+}
+
+type Option func(*options)
+
+// WithExpiration configures the adapter to expire values after the specified
+// amount of time. Expired values are removed by Compact.
+func WithExpiration(expiration time.Duration) Option {
+	return func(o *options) {
+		o.expiration = expiration
+	}
+}
+
+// WithMaxEntries configures the adapter to hold up to N entries,
+// evicting the oldest written ones first. Defaults to no limit.
+func WithMaxEntries(maxEntries int) Option {
+	return func(o *options) {
+		o.maxEntries = maxEntries
+	}
+}
+
+// WithCompactionInterval configures the adapter to call Compact periodically
+// in the background. Defaults to never.
+func WithCompactionInterval(interval time.Duration) Option {
+	return func(o *options) {
+		o.compactionInterval = interval
+	}
+}
+
+// WithCodec configures the adapter to serialize values using the specified codec.
+// The codec value type must match the adapter value type.
+// Defaults to codec.Default.
+func WithCodec[V any](c codec.Codec[V]) Option {
+	return func(o *options) {
+		o.codec = c
+	}
+}
+
+// WithCompression configures the adapter to compress values whose encoded size
+// is at least threshold bytes (see codec.Compressed).
+func WithCompression(compression codec.Compression, threshold int) Option {
+	return func(o *options) {
+		o.compression = compression
+		o.compressionThreshold = threshold
+	}
+}
+
+// WithErrorCallback configures the adapter to call the specified callback
+// synchronously when background compaction fails.
+func WithErrorCallback(errorCallback func(err error)) Option {
+	return func(o *options) {
+		o.errorCallback = errorCallback
+	}
+}
+
+// WithNotFoundClassifier configures the adapter to return ErrNotFound for decode
+// errors classified as not found by the specified classifier, e.g. errors returned
+// by codecs for tombstone values.
+func WithNotFoundClassifier(isNotFound internal.NotFoundClassifier) Option {
+	return func(o *options) {
+		o.isNotFound = isNotFound
+	}
+}
+
+// Bolt is a cache stored on disk using bbolt, so its entries survive restarts.
+// Every write is committed in its own transaction, and is durable once Set returns.
+type Bolt[K comparable, V any] struct {
+	mu sync.RWMutex
+	db *bolt.DB
+
+	path       string
+	expiration time.Duration
+	maxEntries int
+	codec      codec.Codec[V]
+
+	isNotFound internal.NotFoundClassifier
+
+	errorCallback func(err error)
+
+	done      chan struct{}
+	closeOnce sync.Once
+	wg        sync.WaitGroup
+
+	now func() time.Time
+}
+
+// Open opens (or creates) the cache stored in the specified file.
+// The file is locked, so it can't be shared between processes.
+func Open[K comparable, V any](path string, opts ...Option) (*Bolt[K, V], error) {
+	o := compileOptions(opts...)
+	if err := o.Validate(); err != nil {
+		return nil, fmt.Errorf("options: %w", err)
+	}
+
+	var valueCodec codec.Codec[V]
+	if o.codec == nil {
+		valueCodec = codec.Default[V]()
+	} else if c, ok := o.codec.(codec.Codec[V]); ok {
+		valueCodec = c
+	} else {
+		var v V
+		return nil, fmt.Errorf("options: codec: expected codec for %T: found %T", v, o.codec)
+	}
+
+	// Always wrap, so compressed values can be read even if compression is disabled
+	valueCodec = codec.Compressed(valueCodec, o.compression, o.compressionThreshold)
+
+	// Leftover of a compaction that didn't complete, the database itself is intact
+	if err := os.Remove(path + compactionSuffix); err != nil && !errors.Is(err, os.ErrNotExist) {
+		return nil, fmt.Errorf("remove compaction leftover: %w", err)
+	}
+
+	db, err := open(path)
+	if err != nil {
+		return nil, err
+	}
+
+	b := &Bolt[K, V]{
+		db: db,
+
+		path:       path,
+		expiration: o.expiration,
+		maxEntries: o.maxEntries,
+		codec:      valueCodec,
+
+		isNotFound: o.isNotFound,
+
+		errorCallback: o.errorCallback,
+
+		done: make(chan struct{}),
+
+		now: time.Now,
+	}
+
+	if o.compactionInterval > 0 {
+		b.wg.Add(1)
+		go b.compactionWorker(o.compactionInterval)
+	}
+
+	return b, nil
+}
+
+func open(path string) (*bolt.DB, error) {
+	db, err := bolt.Open(path, 0600, &bolt.Options{Timeout: time.Second})
+	if err != nil {
+		return nil, fmt.Errorf("open: %w", err)
+	}
+
+	err = db.Update(func(tx *bolt.Tx) error {
+		for _, name := range [][]byte{valuesBucket, orderBucket} {
+			if _, err := tx.CreateBucketIfNotExists(name); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		db.Close()
+		return nil, fmt.Errorf("create buckets: %w", err)
+	}
+
+	return db, nil
+}
+
+func (b *Bolt[K, V]) reportError(err error) {
+	if b.errorCallback != nil {
+		b.errorCallback(err)
+	}
+}
+
+func (b *Bolt[K, V]) compactionWorker(interval time.Duration) {
+	defer b.wg.Done()
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-b.done:
+			return
+		case <-ticker.C:
+			if err := b.Compact(context.Background()); err != nil {
+				b.reportError(fmt.Errorf("compact: %w", err))
+			}
+		}
+	}
+}
+
+func (b *Bolt[K, V]) view(fn func(values, order *bolt.Bucket) error) error {
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+
+	if b.db == nil {
+		return internal.ErrClosed
+	}
+
+	return b.db.View(func(tx *bolt.Tx) error {
+		return fn(tx.Bucket(valuesBucket), tx.Bucket(orderBucket))
+	})
+}
+
+func (b *Bolt[K, V]) update(fn func(values, order *bolt.Bucket) error) error {
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+
+	if b.db == nil {
+		return internal.ErrClosed
+	}
+
+	return b.db.Update(func(tx *bolt.Tx) error {
+		return fn(tx.Bucket(valuesBucket), tx.Bucket(orderBucket))
+	})
+}
+
+func (b *Bolt[K, V]) expired(record []byte, now time.Time) bool {
+	expiresAt := int64(binary.BigEndian.Uint64(record[:8]))
+	return expiresAt != 0 && now.UnixMilli() >= expiresAt
+}
+
+// remove deletes the specified entry, given its record.
+func remove(values, order *bolt.Bucket, key []byte, record []byte) error {
+	// The record is owned by the bucket, and must not be used once modified
+	seq := bytes.Clone(record[8:recordHeaderSize])
+	key = bytes.Clone(key)
+
+	if err := order.Delete(seq); err != nil {
+		return err
+	}
+
+	if err := values.Delete(key); err != nil {
+		return err
+	}
+
+	return values.SetSequence(values.Sequence() - 1)
+}
+
+func (b *Bolt[K, V]) Get(_ context.Context, key K) (V, error) {
+	var zero V
+
+	keyBytes := fmt.Appendf(nil, "%v", key)
+
+	var rawValue []byte
+	err := b.view(func(values, order *bolt.Bucket) error {
+		record := values.Get(keyBytes)
+		if record == nil || b.expired(record, b.now()) {
+			return internal.ErrNotFound
+		}
+
+		// Only valid during the transaction
+		rawValue = bytes.Clone(record[recordHeaderSize:])
+		return nil
+	})
+	if errors.Is(err, internal.ErrNotFound) {
+		return zero, err
+	} else if err != nil {
+		return zero, fmt.Errorf("get: %w", err)
+	}
+
+	value, err := b.codec.Decode(rawValue)
+	if err != nil && b.isNotFound != nil && b.isNotFound(err) {
+		return zero, internal.ErrNotFound
+	} else if err != nil {
+		return zero, fmt.Errorf("parse value: %w", err)
+	}
+
+	return value, nil
+}
+
+func (b *Bolt[K, V]) Set(ctx context.Context, key K, value V) error {
+	return b.SetWithTTL(ctx, key, value, b.expiration)
+}
+
+// SetWithTTL stores the value using the specified expiration instead of the configured one.
+func (b *Bolt[K, V]) SetWithTTL(_ context.Context, key K, value V, ttl time.Duration) error {
+	keyBytes := fmt.Appendf(nil, "%v", key)
+
+	rawValue, err := b.codec.Encode(value)
+	if err != nil {
+		return fmt.Errorf("marshal value: %w", err)
+	}
+
+	var expiresAt int64
+	if ttl > 0 {
+		expiresAt = b.now().Add(ttl).UnixMilli()
+	}
+
+	err = b.update(func(values, order *bolt.Bucket) error {
+		if existing := values.Get(keyBytes); existing != nil {
+			if err := remove(values, order, keyBytes, existing); err != nil {
+				return err
+			}
+		}
+
+		seq, err := order.NextSequence()
+		if err != nil {
+			return err
+		}
+
+		record := make([]byte, 0, recordHeaderSize+len(rawValue))
+		record = binary.BigEndian.AppendUint64(record, uint64(expiresAt))
+		record = binary.BigEndian.AppendUint64(record, seq)
+		record = append(record, rawValue...)
+
+		if err := order.Put(record[8:recordHeaderSize], keyBytes); err != nil {
+			return err
+		}
+
+		if err := values.Put(keyBytes, record); err != nil {
+			return err
+		}
+
+		if err := values.SetSequence(values.Sequence() + 1); err != nil {
+			return err
+		}
+
+		// Evict the oldest written entries
+		for b.maxEntries > 0 && values.Sequence() > uint64(b.maxEntries) {
+			_, oldest := order.Cursor().First()
+			if err := remove(values, order, oldest, values.Get(oldest)); err != nil {
+				return err
+			}
+		}
+
+		return nil
+	})
+	if err != nil {
+		return fmt.Errorf("set: %w", err)
+	}
+
+	return nil
+}
+
+func (b *Bolt[K, V]) Delete(_ context.Context, key K) error {
+	keyBytes := fmt.Appendf(nil, "%v", key)
+
+	err := b.update(func(values, order *bolt.Bucket) error {
+		record := values.Get(keyBytes)
+		if record == nil {
+			return nil
+		}
+		return remove(values, order, keyBytes, record)
+	})
+	if err != nil {
+		return fmt.Errorf("delete: %w", err)
+	}
+
+	return nil
+}
+
+// Len returns the number of entries, including expired ones that weren't compacted yet.
+func (b *Bolt[K, V]) Len() (int, error) {
+	var n int
+	err := b.view(func(values, order *bolt.Bucket) error {
+		n = int(values.Sequence())
+		return nil
+	})
+	return n, err
+}
+
+// Compact removes expired entries, and if at least half of the file is unused,
+// rewrites it to reclaim the disk space (the file never shrinks otherwise).
+// Other operations are blocked while the file is rewritten.
+func (b *Bolt[K, V]) Compact(_ context.Context) error {
+	now := b.now()
+	err := b.update(func(values, order *bolt.Bucket) error {
+		var expired [][]byte
+		err := values.ForEach(func(key, record []byte) error {
+			if b.expired(record, now) {
+				expired = append(expired, bytes.Clone(key))
+			}
+			return nil
+		})
+		if err != nil {
+			return err
+		}
+
+		for _, key := range expired {
+			if err := remove(values, order, key, values.Get(key)); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return fmt.Errorf("remove expired: %w", err)
+	}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if b.db == nil {
+		return internal.ErrClosed
+	}
+
+	info, err := os.Stat(b.path)
+	if err != nil {
+		return fmt.Errorf("stat: %w", err)
+	}
+
+	if stats := b.db.Stats(); int64(stats.FreeAlloc) < info.Size()/2 {
+		return nil
+	}
+
+	return b.rewrite()
+}
+
+// rewrite copies the live data to a new file, and atomically replaces the current one.
+// The new file is kept open while it replaces the current one, so the cache remains
+// usable no matter which step fails. Must be called while holding the write lock.
+func (b *Bolt[K, V]) rewrite() error {
+	compactPath := b.path + compactionSuffix
+
+	dst, err := bolt.Open(compactPath, 0600, nil)
+	if err != nil {
+		return fmt.Errorf("open compacted: %w", err)
+	}
+
+	if err := bolt.Compact(dst, b.db, 0); err != nil {
+		dst.Close()
+		os.Remove(compactPath)
+		return fmt.Errorf("compact: %w", err)
+	}
+
+	if err := os.Rename(compactPath, b.path); err != nil {
+		// The original file is intact, keep using it
+		dst.Close()
+		os.Remove(compactPath)
+		return fmt.Errorf("rename: %w", err)
+	}
+
+	// The original file was replaced, its data is only kept until it's closed
+	previous := b.db
+	b.db = dst
+
+	if err := previous.Close(); err != nil {
+		return fmt.Errorf("close: %w", err)
+	}
+
+	return nil
+}
+
+// Close stops background compaction and closes the underlying file.
+func (b *Bolt[K, V]) Close() error {
+	b.closeOnce.Do(func() {
+		close(b.done)
+	})
+	b.wg.Wait()
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if b.db == nil {
+		return nil
+	}
+
+	err := b.db.Close()
+	b.db = nil
+	return err
+}
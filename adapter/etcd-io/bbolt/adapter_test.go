@@ -0,0 +1,375 @@
+package adapter
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	bolt "go.etcd.io/bbolt"
+
+	"github.com/dtrugman/cachehit"
+	"github.com/dtrugman/cachehit/codec"
+)
+
+func openTest[V any](t *testing.T, path string, opts ...Option) *Bolt[string, V] {
+	t.Helper()
+
+	cache, err := Open[string, V](path, opts...)
+	require.NoError(t, err)
+
+	t.Cleanup(func() {
+		require.NoError(t, cache.Close())
+	})
+
+	return cache
+}
+
+func TestOpen(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "cache.db")
+
+	cache := openTest[int](t, path)
+	require.Equal(t, DefaultExpiration, cache.expiration)
+	require.Equal(t, DefaultMaxEntries, cache.maxEntries)
+	require.Equal(t, codec.Compressed(codec.Default[int](), codec.CompressionNone, 0), cache.codec)
+}
+
+func TestOpen_WithInvalidOptions(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "cache.db")
+
+	_, err := Open[string, int](path, WithMaxEntries(-1))
+	require.ErrorContains(t, err, "max entries")
+
+	_, err = Open[string, int](path, WithCompactionInterval(-time.Second))
+	require.ErrorContains(t, err, "compaction interval")
+
+	_, err = Open[string, int](path, WithCodec(codec.Gob[string]()))
+	require.ErrorContains(t, err, "codec")
+}
+
+func TestOpen_CompactionLeftover(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "cache.db")
+	require.NoError(t, os.WriteFile(path+compactionSuffix, []byte("partial"), 0600))
+
+	openTest[int](t, path)
+
+	_, err := os.Stat(path + compactionSuffix)
+	require.ErrorIs(t, err, os.ErrNotExist)
+}
+
+func TestBolt_Cache(t *testing.T) {
+	var _ cachehit.Cache[string, int] = (*Bolt[string, int])(nil)
+	var _ cachehit.Deleter[string] = (*Bolt[string, int])(nil)
+	var _ cachehit.TTLSetter[string, int] = (*Bolt[string, int])(nil)
+}
+
+func TestBolt_Operations(t *testing.T) {
+	ctx := t.Context()
+
+	cache := openTest[string](t, filepath.Join(t.TempDir(), "cache.db"))
+
+	t.Run("SetAndGet", func(t *testing.T) {
+		require.NoError(t, cache.Set(ctx, "key1", "value1"))
+
+		value, err := cache.Get(ctx, "key1")
+		require.NoError(t, err)
+		require.Equal(t, "value1", value)
+	})
+
+	t.Run("GetNonExistent", func(t *testing.T) {
+		_, err := cache.Get(ctx, "missing")
+		require.ErrorIs(t, err, cachehit.ErrNotFound)
+	})
+
+	t.Run("Overwrite", func(t *testing.T) {
+		before, err := cache.Len()
+		require.NoError(t, err)
+
+		require.NoError(t, cache.Set(ctx, "key2", "value1"))
+		require.NoError(t, cache.Set(ctx, "key2", "value2"))
+
+		value, err := cache.Get(ctx, "key2")
+		require.NoError(t, err)
+		require.Equal(t, "value2", value)
+
+		after, err := cache.Len()
+		require.NoError(t, err)
+		require.Equal(t, before+1, after)
+	})
+
+	t.Run("Delete", func(t *testing.T) {
+		require.NoError(t, cache.Set(ctx, "key3", "value1"))
+		require.NoError(t, cache.Delete(ctx, "key3"))
+		require.NoError(t, cache.Delete(ctx, "key3"))
+
+		_, err := cache.Get(ctx, "key3")
+		require.ErrorIs(t, err, cachehit.ErrNotFound)
+	})
+}
+
+func TestBolt_Struct(t *testing.T) {
+	ctx := t.Context()
+
+	type user struct {
+		Name string
+		Age  int
+	}
+
+	cache := openTest[user](t, filepath.Join(t.TempDir(), "cache.db"),
+		WithCompression(codec.CompressionSnappy, 0))
+
+	require.NoError(t, cache.Set(ctx, "key", user{Name: "name", Age: 42}))
+
+	value, err := cache.Get(ctx, "key")
+	require.NoError(t, err)
+	require.Equal(t, user{Name: "name", Age: 42}, value)
+}
+
+func TestBolt_ParseError(t *testing.T) {
+	ctx := t.Context()
+
+	path := filepath.Join(t.TempDir(), "cache.db")
+
+	strings := openTest[string](t, path)
+	require.NoError(t, strings.Set(ctx, "key", "not a number"))
+	require.NoError(t, strings.Close())
+
+	ints := openTest[int](t, path)
+
+	_, err := ints.Get(ctx, "key")
+	require.ErrorContains(t, err, "parse value")
+}
+
+func TestBolt_NotFoundClassifier(t *testing.T) {
+	ctx := t.Context()
+
+	path := filepath.Join(t.TempDir(), "cache.db")
+
+	strings := openTest[string](t, path)
+	require.NoError(t, strings.Set(ctx, "key", "not a number"))
+	require.NoError(t, strings.Close())
+
+	ints := openTest[int](t, path, WithNotFoundClassifier(func(err error) bool {
+		return true
+	}))
+
+	_, err := ints.Get(ctx, "key")
+	require.ErrorIs(t, err, cachehit.ErrNotFound)
+}
+
+func TestBolt_Persistence(t *testing.T) {
+	ctx := t.Context()
+
+	path := filepath.Join(t.TempDir(), "cache.db")
+
+	cache := openTest[string](t, path)
+	require.NoError(t, cache.Set(ctx, "key", "value1"))
+	require.NoError(t, cache.Close())
+
+	_, err := cache.Get(ctx, "key")
+	require.ErrorIs(t, err, cachehit.ErrClosed)
+
+	reopened := openTest[string](t, path)
+
+	value, err := reopened.Get(ctx, "key")
+	require.NoError(t, err)
+	require.Equal(t, "value1", value)
+}
+
+func TestBolt_ConcurrentClose(t *testing.T) {
+	cache := openTest[string](t, filepath.Join(t.TempDir(), "cache.db"),
+		WithCompactionInterval(time.Hour))
+
+	var wg sync.WaitGroup
+	for range 10 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			require.NoError(t, cache.Close())
+		}()
+	}
+	wg.Wait()
+}
+
+func TestBolt_Expiration(t *testing.T) {
+	ctx := t.Context()
+
+	cache := openTest[string](t, filepath.Join(t.TempDir(), "cache.db"), WithExpiration(time.Minute))
+
+	now := time.Now()
+	cache.now = func() time.Time { return now }
+
+	require.NoError(t, cache.Set(ctx, "key", "value1"))
+
+	now = now.Add(time.Minute - time.Millisecond)
+	value, err := cache.Get(ctx, "key")
+	require.NoError(t, err)
+	require.Equal(t, "value1", value)
+
+	now = now.Add(time.Millisecond)
+	_, err = cache.Get(ctx, "key")
+	require.ErrorIs(t, err, cachehit.ErrNotFound)
+
+	// Expired entries are kept until compacted
+	n, err := cache.Len()
+	require.NoError(t, err)
+	require.Equal(t, 1, n)
+
+	require.NoError(t, cache.Compact(ctx))
+
+	n, err = cache.Len()
+	require.NoError(t, err)
+	require.Zero(t, n)
+}
+
+func TestBolt_SetWithTTL(t *testing.T) {
+	ctx := t.Context()
+
+	cache := openTest[string](t, filepath.Join(t.TempDir(), "cache.db"), WithExpiration(time.Hour))
+
+	now := time.Now()
+	cache.now = func() time.Time { return now }
+
+	require.NoError(t, cache.SetWithTTL(ctx, "key", "value1", time.Minute))
+
+	now = now.Add(time.Minute - time.Millisecond)
+	value, err := cache.Get(ctx, "key")
+	require.NoError(t, err)
+	require.Equal(t, "value1", value)
+
+	now = now.Add(time.Millisecond)
+	_, err = cache.Get(ctx, "key")
+	require.ErrorIs(t, err, cachehit.ErrNotFound)
+}
+
+func TestBolt_MaxEntries(t *testing.T) {
+	ctx := t.Context()
+
+	cache := openTest[int](t, filepath.Join(t.TempDir(), "cache.db"), WithMaxEntries(3))
+
+	for i := range 3 {
+		require.NoError(t, cache.Set(ctx, fmt.Sprintf("key%d", i), i))
+	}
+
+	// Rewriting an entry makes it the newest
+	require.NoError(t, cache.Set(ctx, "key0", 0))
+	require.NoError(t, cache.Set(ctx, "key3", 3))
+
+	n, err := cache.Len()
+	require.NoError(t, err)
+	require.Equal(t, 3, n)
+
+	_, err = cache.Get(ctx, "key1")
+	require.ErrorIs(t, err, cachehit.ErrNotFound)
+
+	for _, key := range []string{"key0", "key2", "key3"} {
+		_, err := cache.Get(ctx, key)
+		require.NoError(t, err)
+	}
+}
+
+func TestBolt_Compact(t *testing.T) {
+	ctx := t.Context()
+
+	path := filepath.Join(t.TempDir(), "cache.db")
+	cache := openTest[string](t, path)
+
+	value := strings.Repeat("v", 1024)
+	for i := range 1000 {
+		require.NoError(t, cache.Set(ctx, fmt.Sprintf("key%d", i), value))
+	}
+	for i := range 990 {
+		require.NoError(t, cache.Delete(ctx, fmt.Sprintf("key%d", i)))
+	}
+
+	before, err := os.Stat(path)
+	require.NoError(t, err)
+
+	require.NoError(t, cache.Compact(ctx))
+
+	after, err := os.Stat(path)
+	require.NoError(t, err)
+	require.Less(t, after.Size(), before.Size())
+
+	for i := 990; i < 1000; i++ {
+		actual, err := cache.Get(ctx, fmt.Sprintf("key%d", i))
+		require.NoError(t, err)
+		require.Equal(t, value, actual)
+	}
+
+	// Still usable after the file was replaced
+	require.NoError(t, cache.Set(ctx, "key", "value1"))
+
+	n, err := cache.Len()
+	require.NoError(t, err)
+	require.Equal(t, 11, n)
+}
+
+func TestBolt_CompactionInterval(t *testing.T) {
+	ctx := t.Context()
+
+	cache := openTest[string](t, filepath.Join(t.TempDir(), "cache.db"),
+		WithExpiration(time.Millisecond),
+		WithCompactionInterval(10*time.Millisecond))
+
+	require.NoError(t, cache.Set(ctx, "key", "value1"))
+
+	require.Eventually(t, func() bool {
+		n, err := cache.Len()
+		return err == nil && n == 0
+	}, time.Second, 10*time.Millisecond)
+}
+
+func TestBolt_Locked(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "cache.db")
+	openTest[string](t, path)
+
+	_, err := bolt.Open(path, 0600, &bolt.Options{Timeout: 10 * time.Millisecond})
+	require.Error(t, err)
+}
+
+func TestBolt_LookThrough(t *testing.T) {
+	ctx := t.Context()
+
+	path := filepath.Join(t.TempDir(), "cache.db")
+
+	var fetches atomic.Int32
+	repo := repositoryFunc[string, string](func(ctx context.Context, key string) (string, error) {
+		fetches.Add(1)
+		return "value1", nil
+	})
+
+	newSWR := func(disk *Bolt[string, string]) *cachehit.SWR[string, string] {
+		lookthrough, err := cachehit.NewLookThrough(disk, repo)
+		require.NoError(t, err)
+
+		swr, err := cachehit.NewSWR(16, lookthrough, time.Minute, time.Hour)
+		require.NoError(t, err)
+		return swr
+	}
+
+	disk := openTest[string](t, path)
+
+	value, err := newSWR(disk).Get(ctx, "key")
+	require.NoError(t, err)
+	require.Equal(t, "value1", value)
+	require.NoError(t, disk.Close())
+
+	// After a restart, the in-memory tier is empty but the disk tier is warm
+	value, err = newSWR(openTest[string](t, path)).Get(ctx, "key")
+	require.NoError(t, err)
+	require.Equal(t, "value1", value)
+	require.EqualValues(t, 1, fetches.Load())
+}
+
+type repositoryFunc[K comparable, V any] func(ctx context.Context, key K) (V, error)
+
+func (f repositoryFunc[K, V]) Get(ctx context.Context, key K) (V, error) {
+	return f(ctx, key)
+}
@@ -29,3 +29,25 @@ func (a *LRU[K, V]) Set(_ context.Context, key K, value V) error {
 	_ = a.underlying.Add(key, value)
 	return nil
 }
+
+func (a *LRU[K, V]) Delete(_ context.Context, key K) error {
+	_ = a.underlying.Remove(key)
+	return nil
+}
+
+// Range calls fn for every entry, from the least to the most recently used,
+// until fn returns false. Entries aren't marked as used.
+func (a *LRU[K, V]) Range(_ context.Context, fn func(key K, value V) bool) error {
+	for _, key := range a.underlying.Keys() {
+		// Might have been removed in the meantime
+		value, ok := a.underlying.Peek(key)
+		if !ok {
+			continue
+		}
+
+		if !fn(key, value) {
+			break
+		}
+	}
+	return nil
+}
@@ -78,3 +78,54 @@ func TestLRU_SetEviction(t *testing.T) {
 	require.True(t, ok)
 	require.Equal(t, "value3", value)
 }
+
+func TestLRU_Delete(t *testing.T) {
+	cache, err := lru.New[string, string](10)
+	require.NoError(t, err)
+
+	adapter := From(cache)
+	ctx := context.Background()
+
+	cache.Add("key1", "value1")
+
+	require.NoError(t, adapter.Delete(ctx, "key1"))
+	require.NoError(t, adapter.Delete(ctx, "nonexistent"))
+
+	_, ok := cache.Get("key1")
+	require.False(t, ok)
+}
+
+func TestLRU_Range(t *testing.T) {
+	cache, err := lru.New[string, string](10)
+	require.NoError(t, err)
+
+	adapter := From(cache)
+	ctx := context.Background()
+
+	cache.Add("key1", "value1")
+	cache.Add("key2", "value2")
+	cache.Add("key3", "value3")
+	cache.Get("key1")
+
+	keys := make([]string, 0, 3)
+	err = adapter.Range(ctx, func(key string, value string) bool {
+		require.Equal(t, "value"+key[len("key"):], value)
+		keys = append(keys, key)
+		return true
+	})
+	require.NoError(t, err)
+	require.Equal(t, []string{"key2", "key3", "key1"}, keys)
+
+	// Range doesn't mark entries as used
+	_, oldest, ok := cache.GetOldest()
+	require.True(t, ok)
+	require.Equal(t, "value2", oldest)
+
+	keys = keys[:0]
+	err = adapter.Range(ctx, func(key string, value string) bool {
+		keys = append(keys, key)
+		return false
+	})
+	require.NoError(t, err)
+	require.Equal(t, []string{"key2"}, keys)
+}
@@ -2,22 +2,63 @@ package adapter
 
 import (
 	"context"
-	"encoding/json"
 	"errors"
 	"fmt"
-	"strconv"
 	"time"
 
+	"github.com/dtrugman/cachehit/codec"
 	"github.com/dtrugman/cachehit/internal"
 	"github.com/redis/go-redis/v9"
 )
 
 const (
 	DefaultExpiration = 0 * time.Second
+
+	QuarantinePrefix     = "quarantine:"
+	QuarantineExpiration = 24 * time.Hour
+)
+
+// CorruptionPolicy controls how the adapter handles stored values that can't be decoded.
+type CorruptionPolicy int
+
+const (
+	// CorruptionFail returns a parse error, leaving the value in place.
+	CorruptionFail CorruptionPolicy = iota
+	// CorruptionDelete deletes the value and treats it as missing.
+	CorruptionDelete
+	// CorruptionQuarantine moves the value to QuarantinePrefix+key for inspection,
+	// where it expires after QuarantineExpiration, and treats it as missing.
+	CorruptionQuarantine
 )
 
+// compareAndDeleteScript deletes a key only if it still holds the specified value.
+var compareAndDeleteScript = redis.NewScript(`
+if redis.call("GET", KEYS[1]) == ARGV[1] then
+	return redis.call("DEL", KEYS[1])
+end
+return 0
+`)
+
 type options struct {
 	expiration time.Duration
+	codec      any
+
+	compression          codec.Compression
+	compressionThreshold int
+
+	versioned        bool
+	schemaVersion    uint32
+	deleteMismatched bool
+
+	checksum             bool
+	allowMissingChecksum bool
+	corruptionPolicy     CorruptionPolicy
+	errorCallback        func(err error)
+
+	isNotFound internal.NotFoundClassifier
+
+	slidingExpiration time.Duration
+	maxAge            time.Duration
 }
 
 func defaultOptions() *options {
@@ -44,19 +85,180 @@ func WithExpiration(expiration time.Duration) Option {
 	}
 }
 
+// WithCodec configures the adapter to serialize values using the specified codec.
+// The codec value type must match the adapter value type.
+// Defaults to codec.Default.
+func WithCodec[V any](c codec.Codec[V]) Option {
+	return func(o *options) {
+		o.codec = c
+	}
+}
+
+// WithCompression configures the adapter to compress values whose encoded size
+// is at least threshold bytes. Compressed values are self-describing, so values
+// stored using different settings (or none at all) can always be read.
+func WithCompression(compression codec.Compression, threshold int) Option {
+	return func(o *options) {
+		o.compression = compression
+		o.compressionThreshold = threshold
+	}
+}
+
+// WithSchemaVersion configures the adapter to store values in an envelope holding
+// the specified schema version and the write time (see codec.Versioned).
+// Values of any other version, including ones stored before versioning was enabled
+// (considered version 0), are treated as missing. If deleteMismatched is set,
+// they are also deleted, unless they were overwritten in the meantime.
+func WithSchemaVersion(version uint32, deleteMismatched bool) Option {
+	return func(o *options) {
+		o.versioned = true
+		o.schemaVersion = version
+		o.deleteMismatched = deleteMismatched
+	}
+}
+
+// WithChecksum configures the adapter to store a checksum alongside values
+// (see codec.Checksummed), so truncated or modified values are detected as corrupt.
+// Values stored without a checksum are corrupt as well, unless allowMissing is set,
+// e.g. while values stored before checksums were enabled are still around.
+func WithChecksum(allowMissing bool) Option {
+	return func(o *options) {
+		o.checksum = true
+		o.allowMissingChecksum = allowMissing
+	}
+}
+
+// WithCorruptionPolicy configures how the adapter handles values that can't be decoded.
+// Defaults to CorruptionFail.
+func WithCorruptionPolicy(policy CorruptionPolicy) Option {
+	return func(o *options) {
+		o.corruptionPolicy = policy
+	}
+}
+
+// WithErrorCallback configures the adapter to call the specified callback
+// synchronously for every corrupt value it encounters, regardless of the corruption policy.
+func WithErrorCallback(errorCallback func(err error)) Option {
+	return func(o *options) {
+		o.errorCallback = errorCallback
+	}
+}
+
+// WithNotFoundClassifier configures the adapter to return ErrNotFound for errors
+// classified as not found by the specified classifier, e.g. errors returned by
+// proxies for missing keys, or by codecs for tombstone values.
+// Such values aren't considered corrupt.
+func WithNotFoundClassifier(isNotFound internal.NotFoundClassifier) Option {
+	return func(o *options) {
+		o.isNotFound = isNotFound
+	}
+}
+
 type Redis[K comparable, V any] struct {
-	underlying *redis.Client
+	underlying redis.UniversalClient
 	expiration time.Duration
+	codec      codec.Codec[V]
+
+	// envelope wraps encoded values along with their deadline, if any
+	envelope codec.Codec[[]byte]
+
+	deleteMismatched bool
+
+	corruptionPolicy CorruptionPolicy
+	errorCallback    func(err error)
+
+	isNotFound internal.NotFoundClassifier
+
+	slidingExpiration time.Duration
+	maxAge            time.Duration
 }
 
+// From creates a new Redis adapter over the specified client.
+// Any go-redis client can be used, e.g. redis.Client (including failover
+// clients for Sentinel), redis.ClusterClient or redis.Ring.
 func From[K comparable, V any](
-	underlying *redis.Client,
+	underlying redis.UniversalClient,
 	opts ...Option,
-) *Redis[K, V] {
+) (*Redis[K, V], error) {
 	o := compileOptions(opts...)
+
+	var valueCodec codec.Codec[V]
+	if o.codec == nil {
+		valueCodec = codec.Default[V]()
+	} else if c, ok := o.codec.(codec.Codec[V]); ok {
+		valueCodec = c
+	} else {
+		var v V
+		return nil, fmt.Errorf("options: codec: expected codec for %T: found %T", v, o.codec)
+	}
+
+	// Always wrap, so compressed values can be read even if compression is disabled
+	valueCodec = codec.Compressed(valueCodec, o.compression, o.compressionThreshold)
+
+	// Outermost, so mismatched versions are detected without decompressing
+	if o.versioned {
+		valueCodec = codec.Versioned(valueCodec, o.schemaVersion)
+	}
+
+	// Covers the whole stored value, including the version envelope and the deadline
+	envelope := codec.Bytes()
+	if o.checksum {
+		envelope = codec.Checksummed(envelope, o.allowMissingChecksum)
+	}
+
 	return &Redis[K, V]{
 		underlying: underlying,
 		expiration: o.expiration,
+		codec:      valueCodec,
+		envelope:   envelope,
+
+		deleteMismatched: o.deleteMismatched,
+
+		corruptionPolicy: o.corruptionPolicy,
+		errorCallback:    o.errorCallback,
+
+		isNotFound: o.isNotFound,
+
+		slidingExpiration: o.slidingExpiration,
+		maxAge:            o.maxAge,
+	}, nil
+}
+
+// over returns a copy of the adapter, using the specified client.
+func (r *Redis[K, V]) over(underlying redis.UniversalClient) *Redis[K, V] {
+	copied := *r
+	copied.underlying = underlying
+	return &copied
+}
+
+func (r *Redis[K, V]) notFound(err error) bool {
+	return err != nil && r.isNotFound != nil && r.isNotFound(err)
+}
+
+// corrupt handles a value that failed to decode according to the corruption policy,
+// and returns the error the caller should return for it.
+func (r *Redis[K, V]) corrupt(ctx context.Context, keyStr string, rawValue []byte, err error) error {
+	if r.errorCallback != nil {
+		r.errorCallback(fmt.Errorf("corrupt value: %s: %w", keyStr, err))
+	}
+
+	switch r.corruptionPolicy {
+	case CorruptionDelete:
+		// Best effort, the value is ignored either way
+		_ = compareAndDeleteScript.Run(ctx, r.underlying, []string{keyStr}, rawValue).Err()
+		return internal.ErrNotFound
+
+	case CorruptionQuarantine:
+		// Not atomic, as the keys may reside in different slots
+		_, _ = r.underlying.Pipelined(ctx, func(pipe redis.Pipeliner) error {
+			pipe.Set(ctx, QuarantinePrefix+keyStr, rawValue, QuarantineExpiration)
+			compareAndDeleteScript.Eval(ctx, pipe, []string{keyStr}, rawValue)
+			return nil
+		})
+		return internal.ErrNotFound
+
+	default:
+		return fmt.Errorf("parse value: %w", err)
 	}
 }
 
@@ -65,164 +267,285 @@ func (r *Redis[K, V]) Get(ctx context.Context, key K) (V, error) {
 
 	keyStr := fmt.Sprintf("%v", key)
 
-	cmd := r.underlying.Get(ctx, keyStr)
-	err := cmd.Err()
-	if errors.Is(err, redis.Nil) {
+	var cmd *redis.StringCmd
+	if r.slidingExpiration > 0 {
+		cmd = r.underlying.GetEx(ctx, keyStr, r.slidingExpiration)
+	} else {
+		cmd = r.underlying.Get(ctx, keyStr)
+	}
+
+	rawValue, err := cmd.Bytes()
+	if errors.Is(err, redis.Nil) || r.notFound(err) {
 		return zero, internal.ErrNotFound
 	} else if err != nil {
 		return zero, fmt.Errorf("get: %w", err)
 	}
-	rawValue := cmd.Val()
-
-	var value V
-
-	switch ptr := any(&value).(type) {
-	case *string:
-		*ptr = rawValue
-	case *bool:
-		parsed, parseErr := strconv.ParseBool(rawValue)
-		if parseErr != nil {
-			err = parseErr
-		} else {
-			*ptr = parsed
-		}
-	case *int:
-		parsed, parseErr := strconv.Atoi(rawValue)
-		if parseErr != nil {
-			err = parseErr
-		} else {
-			*ptr = parsed
-		}
-	case *int8:
-		parsed, parseErr := strconv.ParseInt(rawValue, 10, 8)
-		if parseErr != nil {
-			err = parseErr
-		} else {
-			*ptr = int8(parsed)
-		}
-	case *int16:
-		parsed, parseErr := strconv.ParseInt(rawValue, 10, 16)
-		if parseErr != nil {
-			err = parseErr
-		} else {
-			*ptr = int16(parsed)
-		}
-	case *int32:
-		parsed, parseErr := strconv.ParseInt(rawValue, 10, 32)
-		if parseErr != nil {
-			err = parseErr
-		} else {
-			*ptr = int32(parsed)
-		}
-	case *int64:
-		parsed, parseErr := strconv.ParseInt(rawValue, 10, 64)
-		if parseErr != nil {
-			err = parseErr
-		} else {
-			*ptr = parsed
-		}
-	case *uint:
-		parsed, parseErr := strconv.ParseUint(rawValue, 10, 0)
-		if parseErr != nil {
-			err = parseErr
-		} else {
-			*ptr = uint(parsed)
-		}
-	case *uint8:
-		parsed, parseErr := strconv.ParseUint(rawValue, 10, 8)
-		if parseErr != nil {
-			err = parseErr
-		} else {
-			*ptr = uint8(parsed)
-		}
-	case *uint16:
-		parsed, parseErr := strconv.ParseUint(rawValue, 10, 16)
-		if parseErr != nil {
-			err = parseErr
-		} else {
-			*ptr = uint16(parsed)
-		}
-	case *uint32:
-		parsed, parseErr := strconv.ParseUint(rawValue, 10, 32)
-		if parseErr != nil {
-			err = parseErr
-		} else {
-			*ptr = uint32(parsed)
-		}
-	case *uint64:
-		parsed, parseErr := strconv.ParseUint(rawValue, 10, 64)
-		if parseErr != nil {
-			err = parseErr
-		} else {
-			*ptr = parsed
-		}
-	case *float32:
-		parsed, parseErr := strconv.ParseFloat(rawValue, 32)
-		if parseErr != nil {
-			err = parseErr
-		} else {
-			*ptr = float32(parsed)
-		}
-	case *float64:
-		parsed, parseErr := strconv.ParseFloat(rawValue, 64)
-		if parseErr != nil {
-			err = parseErr
-		} else {
-			*ptr = parsed
+
+	deadline, data, err := r.unwrap(rawValue)
+	if err != nil {
+		return zero, r.corrupt(ctx, keyStr, rawValue, err)
+	}
+
+	if !deadline.IsZero() {
+		if r.extended(deadline) {
+			// Best effort, a deadline in the past deletes the key
+			_ = r.underlying.PExpireAt(ctx, keyStr, deadline).Err()
+		}
+
+		if !time.Now().Before(deadline) {
+			return zero, internal.ErrNotFound
 		}
-	default:
-		err = json.Unmarshal([]byte(rawValue), &value)
 	}
 
-	if err != nil {
-		return zero, fmt.Errorf("parse value: %w", err)
+	value, err := r.codec.Decode(data)
+	if errors.Is(err, codec.ErrVersionMismatch) {
+		if r.deleteMismatched {
+			// Best effort, the value is ignored either way
+			_ = compareAndDeleteScript.Run(ctx, r.underlying, []string{keyStr}, rawValue).Err()
+		}
+		return zero, internal.ErrNotFound
+	} else if r.notFound(err) {
+		return zero, internal.ErrNotFound
+	} else if err != nil {
+		return zero, r.corrupt(ctx, keyStr, rawValue, err)
 	}
 
 	return value, nil
 }
 
 func (r *Redis[K, V]) Set(ctx context.Context, key K, value V) error {
+	return r.SetWithTTL(ctx, key, value, r.expiration)
+}
+
+// SetWithTTL stores the value using the specified expiration instead of the
+// configured one, still capped by the maximum age (see WithSlidingExpiration).
+func (r *Redis[K, V]) SetWithTTL(ctx context.Context, key K, value V, ttl time.Duration) error {
+	keyStr := fmt.Sprintf("%v", key)
+
+	rawValue, err := r.encode(value)
+	if err != nil {
+		return fmt.Errorf("marshal value: %w", err)
+	}
+
+	cmd := r.underlying.Set(ctx, keyStr, rawValue, r.capExpiration(ttl))
+	return cmd.Err()
+}
+
+func (r *Redis[K, V]) Delete(ctx context.Context, key K) error {
 	keyStr := fmt.Sprintf("%v", key)
 
-	var valueStr string
-
-	switch v := any(value).(type) {
-	case string:
-		valueStr = v
-	case bool:
-		valueStr = strconv.FormatBool(v)
-	case int:
-		valueStr = strconv.Itoa(v)
-	case int8:
-		valueStr = strconv.FormatInt(int64(v), 10)
-	case int16:
-		valueStr = strconv.FormatInt(int64(v), 10)
-	case int32:
-		valueStr = strconv.FormatInt(int64(v), 10)
-	case int64:
-		valueStr = strconv.FormatInt(v, 10)
-	case uint:
-		valueStr = strconv.FormatUint(uint64(v), 10)
-	case uint8:
-		valueStr = strconv.FormatUint(uint64(v), 10)
-	case uint16:
-		valueStr = strconv.FormatUint(uint64(v), 10)
-	case uint32:
-		valueStr = strconv.FormatUint(uint64(v), 10)
-	case uint64:
-		valueStr = strconv.FormatUint(v, 10)
-	case float32:
-		valueStr = strconv.FormatFloat(float64(v), 'f', -1, 32)
-	case float64:
-		valueStr = strconv.FormatFloat(v, 'f', -1, 64)
+	cmd := r.underlying.Del(ctx, keyStr)
+	return cmd.Err()
+}
+
+// groups splits the specified keys into groups of indices,
+// where each group can be fetched using a single MGET command.
+func (r *Redis[K, V]) groups(keys []string) [][]int {
+	switch r.underlying.(type) {
+	case *redis.ClusterClient:
+		// MGET requires all keys to reside in the same slot
+		bySlot := make(map[int][]int)
+		order := make([]int, 0)
+		for i, key := range keys {
+			s := slot(key)
+			if _, exists := bySlot[s]; !exists {
+				order = append(order, s)
+			}
+			bySlot[s] = append(bySlot[s], i)
+		}
+
+		groups := make([][]int, 0, len(order))
+		for _, s := range order {
+			groups = append(groups, bySlot[s])
+		}
+		return groups
+
+	case *redis.Ring:
+		// Ring shards keys using its own hashing, fetch each key separately
+		groups := make([][]int, 0, len(keys))
+		for i := range keys {
+			groups = append(groups, []int{i})
+		}
+		return groups
+
 	default:
-		valueBytes, err := json.Marshal(value)
+		group := make([]int, 0, len(keys))
+		for i := range keys {
+			group = append(group, i)
+		}
+		return [][]int{group}
+	}
+}
+
+// mget fetches the raw values of the specified keys using pipelined MGET commands.
+func (r *Redis[K, V]) mget(ctx context.Context, keyStrs []string) ([]any, error) {
+	groups := r.groups(keyStrs)
+	cmds := make([]*redis.SliceCmd, 0, len(groups))
+
+	_, err := r.underlying.Pipelined(ctx, func(pipe redis.Pipeliner) error {
+		for _, group := range groups {
+			groupKeys := make([]string, 0, len(group))
+			for _, i := range group {
+				groupKeys = append(groupKeys, keyStrs[i])
+			}
+			cmds = append(cmds, pipe.MGet(ctx, groupKeys...))
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("mget: %w", err)
+	}
+
+	rawValues := make([]any, len(keyStrs))
+	for g, group := range groups {
+		groupValues := cmds[g].Val()
+		for j, i := range group {
+			rawValues[i] = groupValues[j]
+		}
+	}
+
+	return rawValues, nil
+}
+
+// getex fetches the raw values of the specified keys using pipelined GETEX commands,
+// resetting their expiration, as MGET doesn't support it.
+func (r *Redis[K, V]) getex(ctx context.Context, keyStrs []string) ([]any, error) {
+	cmds := make([]*redis.StringCmd, 0, len(keyStrs))
+
+	_, err := r.underlying.Pipelined(ctx, func(pipe redis.Pipeliner) error {
+		for _, keyStr := range keyStrs {
+			cmds = append(cmds, pipe.GetEx(ctx, keyStr, r.slidingExpiration))
+		}
+		return nil
+	})
+	if err != nil && !errors.Is(err, redis.Nil) {
+		return nil, fmt.Errorf("getex: %w", err)
+	}
+
+	rawValues := make([]any, len(keyStrs))
+	for i, cmd := range cmds {
+		if rawValue, err := cmd.Result(); err == nil {
+			rawValues[i] = rawValue
+		}
+	}
+
+	return rawValues, nil
+}
+
+// GetMany fetches multiple keys using MGET, in a single pipelined round trip.
+// On cluster clients, keys are grouped by slot into multiple MGET commands.
+// With sliding expiration, keys are fetched using GETEX instead.
+func (r *Redis[K, V]) GetMany(ctx context.Context, keys []K) (map[K]internal.Result[V], error) {
+	results := make(map[K]internal.Result[V], len(keys))
+	if len(keys) == 0 {
+		return results, nil
+	}
+
+	keyStrs := make([]string, 0, len(keys))
+	for _, key := range keys {
+		keyStrs = append(keyStrs, fmt.Sprintf("%v", key))
+	}
+
+	var rawValues []any
+	var err error
+	if r.slidingExpiration > 0 {
+		rawValues, err = r.getex(ctx, keyStrs)
+	} else {
+		rawValues, err = r.mget(ctx, keyStrs)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	mismatched := make(map[string]string)
+	deadlines := make(map[string]time.Time)
+	for i, key := range keys {
+		rawValue, ok := rawValues[i].(string)
+		if !ok {
+			results[key] = internal.Result[V]{Err: internal.ErrNotFound}
+			continue
+		}
+
+		deadline, data, err := r.unwrap([]byte(rawValue))
 		if err != nil {
-			return fmt.Errorf("marshal value: %w", err)
+			results[key] = internal.Result[V]{Err: r.corrupt(ctx, keyStrs[i], []byte(rawValue), err)}
+			continue
 		}
-		valueStr = string(valueBytes)
+
+		if !deadline.IsZero() {
+			if r.extended(deadline) {
+				deadlines[keyStrs[i]] = deadline
+			}
+
+			if !time.Now().Before(deadline) {
+				results[key] = internal.Result[V]{Err: internal.ErrNotFound}
+				continue
+			}
+		}
+
+		value, err := r.codec.Decode(data)
+		if errors.Is(err, codec.ErrVersionMismatch) {
+			mismatched[keyStrs[i]] = rawValue
+			results[key] = internal.Result[V]{Err: internal.ErrNotFound}
+			continue
+		} else if r.notFound(err) {
+			results[key] = internal.Result[V]{Err: internal.ErrNotFound}
+			continue
+		} else if err != nil {
+			results[key] = internal.Result[V]{Err: r.corrupt(ctx, keyStrs[i], []byte(rawValue), err)}
+			continue
+		}
+
+		results[key] = internal.Result[V]{Value: value}
 	}
 
-	cmd := r.underlying.Set(ctx, keyStr, valueStr, r.expiration)
-	return cmd.Err()
+	if len(deadlines) > 0 {
+		// Best effort, a deadline in the past deletes the key
+		_, _ = r.underlying.Pipelined(ctx, func(pipe redis.Pipeliner) error {
+			for keyStr, deadline := range deadlines {
+				pipe.PExpireAt(ctx, keyStr, deadline)
+			}
+			return nil
+		})
+	}
+
+	if r.deleteMismatched && len(mismatched) > 0 {
+		// Best effort, the values are ignored either way
+		_, _ = r.underlying.Pipelined(ctx, func(pipe redis.Pipeliner) error {
+			for keyStr, rawValue := range mismatched {
+				compareAndDeleteScript.Eval(ctx, pipe, []string{keyStr}, rawValue)
+			}
+			return nil
+		})
+	}
+
+	return results, nil
+}
+
+// SetMany stores multiple values using pipelined SET commands, in a single round trip.
+func (r *Redis[K, V]) SetMany(ctx context.Context, values map[K]V) error {
+	if len(values) == 0 {
+		return nil
+	}
+
+	rawValues := make(map[string][]byte, len(values))
+	for key, value := range values {
+		rawValue, err := r.encode(value)
+		if err != nil {
+			return fmt.Errorf("marshal value: %v: %w", key, err)
+		}
+		rawValues[fmt.Sprintf("%v", key)] = rawValue
+	}
+
+	_, err := r.underlying.Pipelined(ctx, func(pipe redis.Pipeliner) error {
+		for keyStr, rawValue := range rawValues {
+			pipe.Set(ctx, keyStr, rawValue, r.writeExpiration())
+		}
+		return nil
+	})
+	if err != nil {
+		return fmt.Errorf("set: %w", err)
+	}
+
+	return nil
 }
@@ -2,6 +2,10 @@ package adapter
 
 import (
 	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"sync/atomic"
 	"testing"
 	"time"
 
@@ -9,40 +13,239 @@ import (
 	"github.com/redis/go-redis/v9"
 	"github.com/stretchr/testify/require"
 
+	"github.com/dtrugman/cachehit"
+	"github.com/dtrugman/cachehit/codec"
 	"github.com/dtrugman/cachehit/example/resource"
 )
 
 func TestFrom(t *testing.T) {
 	client := redis.NewClient(&redis.Options{})
 
-	adapter := From[string, int](client)
+	adapter, err := From[string, int](client)
+	require.NoError(t, err)
 	require.NotNil(t, adapter)
 	require.Equal(t, client, adapter.underlying)
 	require.Equal(t, DefaultExpiration, adapter.expiration)
+	require.Equal(t, codec.Compressed(codec.Default[int](), codec.CompressionNone, 0), adapter.codec)
+}
+
+func TestFrom_UniversalClient(t *testing.T) {
+	t.Run("Cluster", func(t *testing.T) {
+		client := redis.NewClusterClient(&redis.ClusterOptions{})
+
+		adapter, err := From[string, int](client)
+		require.NoError(t, err)
+		require.NotNil(t, adapter)
+		require.Equal(t, client, adapter.underlying)
+	})
+
+	t.Run("Sentinel", func(t *testing.T) {
+		client := redis.NewFailoverClient(&redis.FailoverOptions{MasterName: "master"})
+
+		adapter, err := From[string, int](client)
+		require.NoError(t, err)
+		require.NotNil(t, adapter)
+		require.Equal(t, client, adapter.underlying)
+	})
+
+	t.Run("Ring", func(t *testing.T) {
+		client := redis.NewRing(&redis.RingOptions{})
+
+		adapter, err := From[string, int](client)
+		require.NoError(t, err)
+		require.NotNil(t, adapter)
+		require.Equal(t, client, adapter.underlying)
+	})
 }
 
 func TestFrom_WithExpiration(t *testing.T) {
 	client := redis.NewClient(&redis.Options{})
 
 	expiration := 5 * time.Second
-	adapter := From[string, int](client, WithExpiration(expiration))
+	adapter, err := From[string, int](client, WithExpiration(expiration))
+	require.NoError(t, err)
 	require.NotNil(t, adapter)
 	require.Equal(t, expiration, adapter.expiration)
 }
 
+func TestFrom_WithCodec(t *testing.T) {
+	client := redis.NewClient(&redis.Options{})
+
+	adapter, err := From[string, int](client, WithCodec(codec.Gob[int]()))
+	require.NoError(t, err)
+	require.NotNil(t, adapter)
+	require.Equal(t, codec.Compressed(codec.Gob[int](), codec.CompressionNone, 0), adapter.codec)
+}
+
+func TestFrom_WithCompression(t *testing.T) {
+	client := redis.NewClient(&redis.Options{})
+
+	adapter, err := From[string, int](client, WithCompression(codec.CompressionZstd, 1024))
+	require.NoError(t, err)
+	require.NotNil(t, adapter)
+	require.Equal(t, codec.Compressed(codec.Default[int](), codec.CompressionZstd, 1024), adapter.codec)
+}
+
+func TestFrom_WithSchemaVersion(t *testing.T) {
+	client := redis.NewClient(&redis.Options{})
+
+	adapter, err := From[string, int](client, WithSchemaVersion(2, true))
+	require.NoError(t, err)
+	require.NotNil(t, adapter)
+	require.True(t, adapter.deleteMismatched)
+
+	expected := codec.Versioned(codec.Compressed(codec.Default[int](), codec.CompressionNone, 0), 2)
+	require.Equal(t, expected, adapter.codec)
+}
+
+func TestFrom_WithChecksum(t *testing.T) {
+	client := redis.NewClient(&redis.Options{})
+
+	adapter, err := From[string, int](client, WithChecksum(false), WithCorruptionPolicy(CorruptionDelete))
+	require.NoError(t, err)
+	require.NotNil(t, adapter)
+	require.Equal(t, CorruptionDelete, adapter.corruptionPolicy)
+
+	require.Equal(t, codec.Compressed(codec.Default[int](), codec.CompressionNone, 0), adapter.codec)
+	require.Equal(t, codec.Checksummed(codec.Bytes(), false), adapter.envelope)
+}
+
+func TestFrom_WithSlidingExpiration(t *testing.T) {
+	client := redis.NewClient(&redis.Options{})
+
+	adapter, err := From[string, int](client, WithSlidingExpiration(time.Minute, time.Hour))
+	require.NoError(t, err)
+	require.NotNil(t, adapter)
+	require.Equal(t, time.Minute, adapter.slidingExpiration)
+	require.Equal(t, time.Hour, adapter.maxAge)
+}
+
+func TestRedis_WriteExpiration(t *testing.T) {
+	client := redis.NewClient(&redis.Options{})
+
+	tests := []struct {
+		expiration time.Duration
+		maxAge     time.Duration
+		expected   time.Duration
+	}{
+		{0, 0, 0},
+		{time.Minute, 0, time.Minute},
+		{0, time.Hour, time.Hour},
+		{time.Minute, time.Hour, time.Minute},
+		{2 * time.Hour, time.Hour, time.Hour},
+	}
+
+	for _, test := range tests {
+		adapter, err := From[string, int](client,
+			WithExpiration(test.expiration),
+			WithSlidingExpiration(time.Minute, test.maxAge))
+		require.NoError(t, err)
+		require.Equal(t, test.expected, adapter.writeExpiration())
+	}
+}
+
+func TestFrom_WithCodecTypeMismatch(t *testing.T) {
+	client := redis.NewClient(&redis.Options{})
+
+	_, err := From[string, int](client, WithCodec(codec.Gob[string]()))
+	require.ErrorContains(t, err, "codec")
+}
+
+func TestRedis_BatchCache(t *testing.T) {
+	var _ cachehit.BatchCache[string, int] = (*Redis[string, int])(nil)
+	var _ cachehit.TTLSetter[string, int] = (*Redis[string, int])(nil)
+}
+
+func TestNewLease_InvalidTTL(t *testing.T) {
+	client := redis.NewClient(&redis.Options{})
+
+	_, err := NewLease(client, "lease:", 0)
+	require.Error(t, err)
+
+	_, err = NewLease(client, "lease:", time.Minute, LeaseWithOutcomeTTL(0))
+	require.Error(t, err)
+}
+
+func TestLease_Lease(t *testing.T) {
+	var _ cachehit.Lease = (*Lease)(nil)
+}
+
+type repositoryFunc[K comparable, V any] func(ctx context.Context, key K) (V, error)
+
+func (f repositoryFunc[K, V]) Get(ctx context.Context, key K) (V, error) {
+	return f(ctx, key)
+}
+
+func TestSWRStore_Cache(t *testing.T) {
+	var _ cachehit.Cache[string, *cachehit.SWREntry[string]] = (*SWRStore[string, string])(nil)
+}
+
+func TestNewSWRStore_SlidingExpiration(t *testing.T) {
+	client := redis.NewClient(&redis.Options{})
+
+	_, err := NewSWRStore[string, string](client, WithSlidingExpiration(time.Minute, 0))
+	require.ErrorContains(t, err, "sliding expiration")
+
+	_, err = NewSWRStore[string, string](client, WithSlidingExpiration(0, time.Minute))
+	require.NoError(t, err)
+}
+
+func TestBus_InvalidationBus(t *testing.T) {
+	var _ cachehit.InvalidationBus = (*Bus)(nil)
+}
+
+func TestRedis_Groups(t *testing.T) {
+	keys := []string{"{a}.1", "{b}.1", "{a}.2", "{c}.1"}
+
+	t.Run("Client", func(t *testing.T) {
+		adapter, err := From[string, int](redis.NewClient(&redis.Options{}))
+		require.NoError(t, err)
+		require.Equal(t, [][]int{{0, 1, 2, 3}}, adapter.groups(keys))
+	})
+
+	t.Run("Cluster", func(t *testing.T) {
+		adapter, err := From[string, int](redis.NewClusterClient(&redis.ClusterOptions{}))
+		require.NoError(t, err)
+		require.Equal(t, [][]int{{0, 2}, {1}, {3}}, adapter.groups(keys))
+	})
+
+	t.Run("Ring", func(t *testing.T) {
+		adapter, err := From[string, int](redis.NewRing(&redis.RingOptions{}))
+		require.NoError(t, err)
+		require.Equal(t, [][]int{{0}, {1}, {2}, {3}}, adapter.groups(keys))
+	})
+}
+
 func TestRedis_Error(t *testing.T) {
 	ctx := context.Background()
 
 	disconnectedClient := redis.NewClient(&redis.Options{
 		Addr: "localhost:9999",
 	})
-	adapter := From[string, string](disconnectedClient)
+	adapter, err := From[string, string](disconnectedClient)
+	require.NoError(t, err)
 
 	key := uuid.New().String()
-	_, err := adapter.Get(ctx, key)
+	_, err = adapter.Get(ctx, key)
 	require.Error(t, err)
 }
 
+func TestRedis_NotFoundClassifier(t *testing.T) {
+	ctx := context.Background()
+
+	disconnectedClient := redis.NewClient(&redis.Options{
+		Addr: "localhost:9999",
+	})
+	adapter, err := From[string, string](disconnectedClient, WithNotFoundClassifier(func(err error) bool {
+		return true
+	}))
+	require.NoError(t, err)
+
+	key := uuid.New().String()
+	_, err = adapter.Get(ctx, key)
+	require.ErrorIs(t, err, cachehit.ErrNotFound)
+}
+
 func TestRedis_Operations(t *testing.T) {
 	ctx := context.Background()
 
@@ -60,9 +263,36 @@ func TestRedis_Operations(t *testing.T) {
 		client.Close()
 	})
 
+	testOperations(t, client)
+}
+
+func TestRedisCluster_Operations(t *testing.T) {
+	ctx := context.Background()
+
+	instance, err := resource.RedisClusterRun(ctx, 3)
+	require.NoError(t, err)
+
+	t.Cleanup(func() {
+		require.NoError(t, instance.Cleanup())
+	})
+
+	client, err := resource.RedisClusterConn(ctx, instance.Addrs)
+	require.NoError(t, err)
+
+	t.Cleanup(func() {
+		client.Close()
+	})
+
+	testOperations(t, client)
+}
+
+func testOperations(t *testing.T, client redis.UniversalClient) {
+	ctx := context.Background()
+
 	t.Run("String", func(t *testing.T) {
 		key := uuid.New().String()
-		adapter := From[string, string](client)
+		adapter, err := From[string, string](client)
+		require.NoError(t, err)
 
 		adapter.Set(ctx, key, "value1")
 
@@ -91,7 +321,8 @@ func TestRedis_Operations(t *testing.T) {
 
 	t.Run("Int", func(t *testing.T) {
 		key := uuid.New().String()
-		adapter := From[string, int](client)
+		adapter, err := From[string, int](client)
+		require.NoError(t, err)
 
 		adapter.Set(ctx, key, 42)
 
@@ -106,7 +337,8 @@ func TestRedis_Operations(t *testing.T) {
 
 	t.Run("Int8", func(t *testing.T) {
 		key := uuid.New().String()
-		adapter := From[string, int8](client)
+		adapter, err := From[string, int8](client)
+		require.NoError(t, err)
 
 		adapter.Set(ctx, key, int8(127))
 
@@ -121,7 +353,8 @@ func TestRedis_Operations(t *testing.T) {
 
 	t.Run("Int16", func(t *testing.T) {
 		key := uuid.New().String()
-		adapter := From[string, int16](client)
+		adapter, err := From[string, int16](client)
+		require.NoError(t, err)
 
 		adapter.Set(ctx, key, int16(32767))
 
@@ -136,7 +369,8 @@ func TestRedis_Operations(t *testing.T) {
 
 	t.Run("Int32", func(t *testing.T) {
 		key := uuid.New().String()
-		adapter := From[string, int32](client)
+		adapter, err := From[string, int32](client)
+		require.NoError(t, err)
 
 		adapter.Set(ctx, key, int32(2147483647))
 
@@ -151,7 +385,8 @@ func TestRedis_Operations(t *testing.T) {
 
 	t.Run("Int64", func(t *testing.T) {
 		key := uuid.New().String()
-		adapter := From[string, int64](client)
+		adapter, err := From[string, int64](client)
+		require.NoError(t, err)
 
 		adapter.Set(ctx, key, int64(9223372036854775807))
 
@@ -166,7 +401,8 @@ func TestRedis_Operations(t *testing.T) {
 
 	t.Run("Uint", func(t *testing.T) {
 		key := uuid.New().String()
-		adapter := From[string, uint](client)
+		adapter, err := From[string, uint](client)
+		require.NoError(t, err)
 
 		adapter.Set(ctx, key, uint(42))
 
@@ -181,7 +417,8 @@ func TestRedis_Operations(t *testing.T) {
 
 	t.Run("Uint8", func(t *testing.T) {
 		key := uuid.New().String()
-		adapter := From[string, uint8](client)
+		adapter, err := From[string, uint8](client)
+		require.NoError(t, err)
 
 		adapter.Set(ctx, key, uint8(255))
 
@@ -196,7 +433,8 @@ func TestRedis_Operations(t *testing.T) {
 
 	t.Run("Uint16", func(t *testing.T) {
 		key := uuid.New().String()
-		adapter := From[string, uint16](client)
+		adapter, err := From[string, uint16](client)
+		require.NoError(t, err)
 
 		adapter.Set(ctx, key, uint16(65535))
 
@@ -211,7 +449,8 @@ func TestRedis_Operations(t *testing.T) {
 
 	t.Run("Uint32", func(t *testing.T) {
 		key := uuid.New().String()
-		adapter := From[string, uint32](client)
+		adapter, err := From[string, uint32](client)
+		require.NoError(t, err)
 
 		adapter.Set(ctx, key, uint32(4294967295))
 
@@ -226,7 +465,8 @@ func TestRedis_Operations(t *testing.T) {
 
 	t.Run("Uint64", func(t *testing.T) {
 		key := uuid.New().String()
-		adapter := From[string, uint64](client)
+		adapter, err := From[string, uint64](client)
+		require.NoError(t, err)
 
 		adapter.Set(ctx, key, uint64(18446744073709551615))
 
@@ -242,7 +482,8 @@ func TestRedis_Operations(t *testing.T) {
 	t.Run("Bool", func(t *testing.T) {
 		key1 := uuid.New().String()
 		key2 := uuid.New().String()
-		adapter := From[string, bool](client)
+		adapter, err := From[string, bool](client)
+		require.NoError(t, err)
 
 		adapter.Set(ctx, key1, true)
 
@@ -267,7 +508,8 @@ func TestRedis_Operations(t *testing.T) {
 
 	t.Run("Float32", func(t *testing.T) {
 		key := uuid.New().String()
-		adapter := From[string, float32](client)
+		adapter, err := From[string, float32](client)
+		require.NoError(t, err)
 
 		adapter.Set(ctx, key, float32(3.14))
 
@@ -282,7 +524,8 @@ func TestRedis_Operations(t *testing.T) {
 
 	t.Run("Float64", func(t *testing.T) {
 		key := uuid.New().String()
-		adapter := From[string, float64](client)
+		adapter, err := From[string, float64](client)
+		require.NoError(t, err)
 
 		adapter.Set(ctx, key, 3.14159)
 
@@ -302,7 +545,8 @@ func TestRedis_Operations(t *testing.T) {
 		}
 
 		key := uuid.New().String()
-		adapter := From[string, TestStruct](client)
+		adapter, err := From[string, TestStruct](client)
+		require.NoError(t, err)
 
 		testData := TestStruct{Name: "test", Value: 123}
 		adapter.Set(ctx, key, testData)
@@ -318,7 +562,8 @@ func TestRedis_Operations(t *testing.T) {
 
 	t.Run("Array", func(t *testing.T) {
 		key := uuid.New().String()
-		adapter := From[string, []int](client)
+		adapter, err := From[string, []int](client)
+		require.NoError(t, err)
 
 		testData := []int{1, 2, 3, 4, 5}
 		adapter.Set(ctx, key, testData)
@@ -332,9 +577,688 @@ func TestRedis_Operations(t *testing.T) {
 		require.Equal(t, `[1,2,3,4,5]`, redisValue)
 	})
 
+	t.Run("GobCodec", func(t *testing.T) {
+		type TestStruct struct {
+			Name  string
+			Value int
+		}
+
+		key := uuid.New().String()
+		adapter, err := From[string, TestStruct](client, WithCodec(codec.Gob[TestStruct]()))
+		require.NoError(t, err)
+
+		testData := TestStruct{Name: "test", Value: 123}
+		require.NoError(t, adapter.Set(ctx, key, testData))
+
+		value, err := adapter.Get(ctx, key)
+		require.NoError(t, err)
+		require.Equal(t, testData, value)
+	})
+
+	t.Run("BytesCodec", func(t *testing.T) {
+		key := uuid.New().String()
+		adapter, err := From[string, []byte](client, WithCodec(codec.Bytes()))
+		require.NoError(t, err)
+
+		testData := []byte{0x00, 0xff, 0x10}
+		require.NoError(t, adapter.Set(ctx, key, testData))
+
+		value, err := adapter.Get(ctx, key)
+		require.NoError(t, err)
+		require.Equal(t, testData, value)
+
+		redisValue, err := client.Get(ctx, key).Bytes()
+		require.NoError(t, err)
+		require.Equal(t, testData, redisValue)
+	})
+
+	t.Run("GetMany", func(t *testing.T) {
+		adapter, err := From[string, int](client)
+		require.NoError(t, err)
+
+		keys := make([]string, 0, 10)
+		for i := range 10 {
+			key := uuid.New().String()
+			keys = append(keys, key)
+			if i%2 == 0 {
+				require.NoError(t, adapter.Set(ctx, key, i))
+			}
+		}
+
+		invalidKey := uuid.New().String()
+		require.NoError(t, client.Set(ctx, invalidKey, "not-a-number", 0).Err())
+		keys = append(keys, invalidKey)
+
+		results, err := adapter.GetMany(ctx, keys)
+		require.NoError(t, err)
+		require.Len(t, results, len(keys))
+
+		for i, key := range keys[:10] {
+			if i%2 == 0 {
+				require.NoError(t, results[key].Err)
+				require.Equal(t, i, results[key].Value)
+			} else {
+				require.ErrorIs(t, results[key].Err, cachehit.ErrNotFound)
+			}
+		}
+		require.ErrorContains(t, results[invalidKey].Err, "parse value")
+
+		results, err = adapter.GetMany(ctx, nil)
+		require.NoError(t, err)
+		require.Empty(t, results)
+	})
+
+	t.Run("SetMany", func(t *testing.T) {
+		adapter, err := From[string, string](client, WithExpiration(time.Minute))
+		require.NoError(t, err)
+
+		values := make(map[string]string)
+		for i := range 10 {
+			values[uuid.New().String()] = fmt.Sprintf("value%d", i)
+		}
+
+		require.NoError(t, adapter.SetMany(ctx, values))
+
+		for key, expected := range values {
+			value, err := adapter.Get(ctx, key)
+			require.NoError(t, err)
+			require.Equal(t, expected, value)
+
+			ttl, err := client.TTL(ctx, key).Result()
+			require.NoError(t, err)
+			require.Positive(t, ttl)
+		}
+
+		require.NoError(t, adapter.SetMany(ctx, nil))
+	})
+
+	t.Run("SetManyMarshallingError", func(t *testing.T) {
+		adapter, err := From[string, chan int](client)
+		require.NoError(t, err)
+
+		err = adapter.SetMany(ctx, map[string]chan int{uuid.New().String(): make(chan int)})
+		require.Error(t, err)
+	})
+
+	t.Run("Compression", func(t *testing.T) {
+		key := uuid.New().String()
+		smallKey := uuid.New().String()
+		adapter, err := From[string, string](client, WithCompression(codec.CompressionGzip, 64))
+		require.NoError(t, err)
+
+		testData := strings.Repeat("compressible ", 100)
+		require.NoError(t, adapter.Set(ctx, key, testData))
+		require.NoError(t, adapter.Set(ctx, smallKey, "small"))
+
+		redisValue, err := client.Get(ctx, key).Result()
+		require.NoError(t, err)
+		require.Less(t, len(redisValue), len(testData))
+
+		redisValue, err = client.Get(ctx, smallKey).Result()
+		require.NoError(t, err)
+		require.Equal(t, "small", redisValue)
+
+		plainAdapter, err := From[string, string](client)
+		require.NoError(t, err)
+		for _, a := range []*Redis[string, string]{adapter, plainAdapter} {
+			value, err := a.Get(ctx, key)
+			require.NoError(t, err)
+			require.Equal(t, testData, value)
+
+			value, err = a.Get(ctx, smallKey)
+			require.NoError(t, err)
+			require.Equal(t, "small", value)
+		}
+	})
+
+	t.Run("SchemaVersion", func(t *testing.T) {
+		key := uuid.New().String()
+
+		unversioned, err := From[string, string](client)
+		require.NoError(t, err)
+		v1, err := From[string, string](client, WithSchemaVersion(1, false))
+		require.NoError(t, err)
+		v2, err := From[string, string](client, WithSchemaVersion(2, false))
+		require.NoError(t, err)
+
+		require.NoError(t, unversioned.Set(ctx, key, "value0"))
+
+		_, err = v1.Get(ctx, key)
+		require.ErrorIs(t, err, cachehit.ErrNotFound)
+
+		require.NoError(t, v1.Set(ctx, key, "value1"))
+
+		value, err := v1.Get(ctx, key)
+		require.NoError(t, err)
+		require.Equal(t, "value1", value)
+
+		_, err = v2.Get(ctx, key)
+		require.ErrorIs(t, err, cachehit.ErrNotFound)
+
+		results, err := v2.GetMany(ctx, []string{key})
+		require.NoError(t, err)
+		require.ErrorIs(t, results[key].Err, cachehit.ErrNotFound)
+
+		// Mismatched values are kept unless configured otherwise
+		exists, err := client.Exists(ctx, key).Result()
+		require.NoError(t, err)
+		require.EqualValues(t, 1, exists)
+	})
+
+	t.Run("SchemaVersionDeleteMismatched", func(t *testing.T) {
+		keys := []string{uuid.New().String(), uuid.New().String()}
+
+		v1, err := From[string, string](client, WithSchemaVersion(1, false))
+		require.NoError(t, err)
+		v2, err := From[string, string](client, WithSchemaVersion(2, true))
+		require.NoError(t, err)
+
+		for _, key := range keys {
+			require.NoError(t, v1.Set(ctx, key, "value1"))
+		}
+
+		_, err = v2.Get(ctx, keys[0])
+		require.ErrorIs(t, err, cachehit.ErrNotFound)
+
+		results, err := v2.GetMany(ctx, keys[1:])
+		require.NoError(t, err)
+		require.ErrorIs(t, results[keys[1]].Err, cachehit.ErrNotFound)
+
+		for _, key := range keys {
+			exists, err := client.Exists(ctx, key).Result()
+			require.NoError(t, err)
+			require.Zero(t, exists)
+		}
+	})
+
+	t.Run("CorruptionPolicy", func(t *testing.T) {
+		policies := []CorruptionPolicy{CorruptionFail, CorruptionDelete, CorruptionQuarantine}
+		for _, policy := range policies {
+			key := uuid.New().String()
+
+			var reported error
+			adapter, err := From[string, int](client,
+				WithCorruptionPolicy(policy),
+				WithErrorCallback(func(err error) {
+					reported = err
+				}))
+			require.NoError(t, err)
+
+			require.NoError(t, client.Set(ctx, key, "not a number", 0).Err())
+
+			_, err = adapter.Get(ctx, key)
+			require.ErrorContains(t, reported, key)
+
+			exists, existsErr := client.Exists(ctx, key).Result()
+			require.NoError(t, existsErr)
+
+			switch policy {
+			case CorruptionFail:
+				require.ErrorContains(t, err, "parse value")
+				require.EqualValues(t, 1, exists)
+
+			case CorruptionDelete:
+				require.ErrorIs(t, err, cachehit.ErrNotFound)
+				require.Zero(t, exists)
+
+			case CorruptionQuarantine:
+				require.ErrorIs(t, err, cachehit.ErrNotFound)
+				require.Zero(t, exists)
+
+				quarantined, err := client.Get(ctx, QuarantinePrefix+key).Result()
+				require.NoError(t, err)
+				require.Equal(t, "not a number", quarantined)
+
+				ttl, err := client.PTTL(ctx, QuarantinePrefix+key).Result()
+				require.NoError(t, err)
+				require.Positive(t, ttl)
+			}
+		}
+	})
+
+	t.Run("CorruptionPolicyGetMany", func(t *testing.T) {
+		key := uuid.New().String()
+		adapter, err := From[string, int](client, WithCorruptionPolicy(CorruptionDelete))
+		require.NoError(t, err)
+
+		require.NoError(t, client.Set(ctx, key, "not a number", 0).Err())
+
+		results, err := adapter.GetMany(ctx, []string{key})
+		require.NoError(t, err)
+		require.ErrorIs(t, results[key].Err, cachehit.ErrNotFound)
+
+		exists, err := client.Exists(ctx, key).Result()
+		require.NoError(t, err)
+		require.Zero(t, exists)
+	})
+
+	t.Run("ChecksumMissing", func(t *testing.T) {
+		key := uuid.New().String()
+
+		// Stored before checksums were enabled
+		require.NoError(t, client.Set(ctx, key, "value1", 0).Err())
+
+		strict, err := From[string, string](client, WithChecksum(false))
+		require.NoError(t, err)
+
+		_, err = strict.Get(ctx, key)
+		require.ErrorIs(t, err, codec.ErrCorrupt)
+
+		lenient, err := From[string, string](client, WithChecksum(true))
+		require.NoError(t, err)
+
+		value, err := lenient.Get(ctx, key)
+		require.NoError(t, err)
+		require.Equal(t, "value1", value)
+	})
+
+	t.Run("Checksum", func(t *testing.T) {
+		key := uuid.New().String()
+
+		var reported error
+		adapter, err := From[string, string](client,
+			WithChecksum(false),
+			WithCorruptionPolicy(CorruptionDelete),
+			WithErrorCallback(func(err error) {
+				reported = err
+			}))
+		require.NoError(t, err)
+
+		require.NoError(t, adapter.Set(ctx, key, "value1"))
+
+		value, err := adapter.Get(ctx, key)
+		require.NoError(t, err)
+		require.Equal(t, "value1", value)
+
+		// Truncate the stored value
+		rawValue, err := client.Get(ctx, key).Bytes()
+		require.NoError(t, err)
+		require.NoError(t, client.Set(ctx, key, rawValue[:len(rawValue)-1], 0).Err())
+
+		_, err = adapter.Get(ctx, key)
+		require.ErrorIs(t, err, cachehit.ErrNotFound)
+		require.ErrorIs(t, reported, codec.ErrCorrupt)
+	})
+
+	t.Run("Delete", func(t *testing.T) {
+		key := uuid.New().String()
+		adapter, err := From[string, string](client)
+		require.NoError(t, err)
+
+		require.NoError(t, adapter.Set(ctx, key, "value1"))
+		require.NoError(t, adapter.Delete(ctx, key))
+		require.NoError(t, adapter.Delete(ctx, key))
+
+		_, err = adapter.Get(ctx, key)
+		require.ErrorIs(t, err, cachehit.ErrNotFound)
+	})
+
+	t.Run("SWRStore", func(t *testing.T) {
+		key := uuid.New().String()
+		store, err := NewSWRStore[string, string](client)
+		require.NoError(t, err)
+
+		now := time.Now()
+		entry := &cachehit.SWREntry[string]{
+			Value:   "value1",
+			StaleAt: now.Add(time.Minute),
+			DeadAt:  now.Add(time.Hour),
+		}
+
+		require.NoError(t, store.Set(ctx, key, entry))
+
+		actual, err := store.Get(ctx, key)
+		require.NoError(t, err)
+		require.Equal(t, entry.Value, actual.Value)
+		require.True(t, entry.StaleAt.Equal(actual.StaleAt))
+		require.True(t, entry.DeadAt.Equal(actual.DeadAt))
+
+		ttl, err := client.PTTL(ctx, key).Result()
+		require.NoError(t, err)
+		require.InDelta(t, time.Hour, ttl, float64(time.Minute))
+
+		require.NoError(t, store.Delete(ctx, key))
+
+		_, err = store.Get(ctx, key)
+		require.ErrorIs(t, err, cachehit.ErrNotFound)
+	})
+
+	t.Run("SWRStoreOptions", func(t *testing.T) {
+		key := uuid.New().String()
+		store, err := NewSWRStore[string, string](client,
+			WithChecksum(false),
+			WithSlidingExpiration(0, time.Minute))
+		require.NoError(t, err)
+
+		now := time.Now()
+		entry := &cachehit.SWREntry[string]{
+			Value:   "value1",
+			StaleAt: now.Add(time.Minute),
+			DeadAt:  now.Add(time.Hour),
+		}
+
+		require.NoError(t, store.Set(ctx, key, entry))
+
+		// Capped by the maximum age, and verified when read
+		ttl, err := client.PTTL(ctx, key).Result()
+		require.NoError(t, err)
+		require.InDelta(t, time.Minute, ttl, float64(time.Second))
+
+		rawValue, err := client.Get(ctx, key).Bytes()
+		require.NoError(t, err)
+		rawValue[len(rawValue)-1] ^= 0xFF
+		require.NoError(t, client.Set(ctx, key, rawValue, time.Minute).Err())
+
+		_, err = store.Get(ctx, key)
+		require.ErrorContains(t, err, "parse value")
+
+		// Dead entries are dropped
+		entry.DeadAt = now.Add(-time.Second)
+		require.NoError(t, store.Set(ctx, key, entry))
+
+		_, err = store.Get(ctx, key)
+		require.ErrorIs(t, err, cachehit.ErrNotFound)
+	})
+
+	t.Run("SharedSWR", func(t *testing.T) {
+		key := uuid.New().String()
+
+		lease, err := NewLease(client, "lease:", time.Minute)
+		require.NoError(t, err)
+
+		// Two processes sharing the same store, only one refreshes
+		var fetches atomic.Int32
+		repo := repositoryFunc[string, string](func(ctx context.Context, key string) (string, error) {
+			return fmt.Sprintf("value%d", fetches.Add(1)), nil
+		})
+
+		swrs := make([]*cachehit.SWR[string, string], 0, 2)
+		for range 2 {
+			store, err := NewSWRStore[string, string](client)
+			require.NoError(t, err)
+
+			swr, err := cachehit.NewSWRFromCache(store, repo,
+				time.Hour, 2*time.Hour, cachehit.SWRWithRefreshLease(lease))
+			require.NoError(t, err)
+			swrs = append(swrs, swr)
+		}
+
+		value, err := swrs[0].Get(ctx, key)
+		require.NoError(t, err)
+		require.Equal(t, "value1", value)
+
+		value, err = swrs[1].Get(ctx, key)
+		require.NoError(t, err)
+		require.Equal(t, "value1", value)
+		require.EqualValues(t, 1, fetches.Load())
+	})
+
+	t.Run("Bus", func(t *testing.T) {
+		channel := uuid.New().String()
+
+		buses := make([]*Bus, 0, 2)
+		received := make([]chan string, 0, 2)
+		for range 2 {
+			bus, err := NewBus(ctx, client, channel)
+			require.NoError(t, err)
+
+			ch := make(chan string, 1)
+			bus.Subscribe(func(message string) {
+				ch <- message
+			})
+
+			buses = append(buses, bus)
+			received = append(received, ch)
+		}
+
+		require.NoError(t, buses[0].Publish(ctx, "message"))
+
+		for _, ch := range received {
+			select {
+			case message := <-ch:
+				require.Equal(t, "message", message)
+			case <-time.After(5 * time.Second):
+				require.Fail(t, "invalidation not received")
+			}
+		}
+
+		for _, bus := range buses {
+			require.NoError(t, bus.Close())
+		}
+	})
+
+	t.Run("BusInvalidation", func(t *testing.T) {
+		bus, err := NewBus(ctx, client, uuid.New().String())
+		require.NoError(t, err)
+
+		t.Cleanup(func() {
+			require.NoError(t, bus.Close())
+		})
+
+		// Two processes, each with its own cache, sharing the bus
+		var fetches atomic.Int32
+		repo := repositoryFunc[int, string](func(ctx context.Context, key int) (string, error) {
+			return fmt.Sprintf("value%d", fetches.Add(1)), nil
+		})
+
+		invalidated := make(chan struct{}, 1)
+		bus.Subscribe(func(string) {
+			invalidated <- struct{}{}
+		})
+
+		swrs := make([]*cachehit.SWR[int, string], 0, 2)
+		for range 2 {
+			swr, err := cachehit.NewSWR(10, repo, time.Hour, 2*time.Hour,
+				cachehit.SWRWithInvalidationBus(bus, cachehit.InvalidationDrop))
+			require.NoError(t, err)
+			swrs = append(swrs, swr)
+		}
+
+		_, err = swrs[1].Get(ctx, 42)
+		require.NoError(t, err)
+
+		require.NoError(t, swrs[0].Invalidate(ctx, 42))
+
+		select {
+		case <-invalidated:
+		case <-time.After(5 * time.Second):
+			require.Fail(t, "invalidation not received")
+		}
+
+		require.Eventually(t, func() bool {
+			value, err := swrs[1].Get(ctx, 42)
+			return err == nil && value != "value1"
+		}, 5*time.Second, 10*time.Millisecond)
+	})
+
+	t.Run("Lease", func(t *testing.T) {
+		key := uuid.New().String()
+
+		lease, err := NewLease(client, "lease:", time.Minute)
+		require.NoError(t, err)
+
+		release, acquired, err := lease.Acquire(ctx, key)
+		require.NoError(t, err)
+		require.True(t, acquired)
+
+		_, acquired, err = lease.Acquire(ctx, key)
+		require.NoError(t, err)
+		require.False(t, acquired)
+
+		ttl, err := client.PTTL(ctx, "lease:"+key).Result()
+		require.NoError(t, err)
+		require.Positive(t, ttl)
+
+		release(nil)
+
+		release, acquired, err = lease.Acquire(ctx, key)
+		require.NoError(t, err)
+		require.True(t, acquired)
+
+		// Expired and taken by another holder, must not be released
+		require.NoError(t, client.Set(ctx, "lease:"+key, "other", 0).Err())
+		release(nil)
+
+		owner, err := client.Get(ctx, "lease:"+key).Result()
+		require.NoError(t, err)
+		require.Equal(t, "other", owner)
+	})
+
+	t.Run("LeaseOutcome", func(t *testing.T) {
+		lease, err := NewLease(client, "lease:", time.Minute, LeaseWithOutcomeTTL(time.Minute))
+		require.NoError(t, err)
+
+		tests := []struct {
+			name     string
+			fetchErr error
+			expected error
+		}{
+			{"NotFound", fmt.Errorf("repo get: %w", cachehit.ErrNotFound), cachehit.ErrNotFound},
+			{"Failed", errors.New("failed"), cachehit.ErrLeaseFailed},
+			{"Canceled", context.Canceled, nil},
+		}
+
+		for _, test := range tests {
+			key := uuid.New().String()
+
+			release, acquired, err := lease.Acquire(ctx, key)
+			require.NoError(t, err, test.name)
+			require.True(t, acquired, test.name)
+
+			release(test.fetchErr)
+
+			_, acquired, err = lease.Acquire(ctx, key)
+			if test.expected != nil {
+				require.ErrorIs(t, err, test.expected, test.name)
+				require.False(t, acquired, test.name)
+			} else {
+				require.NoError(t, err, test.name)
+				require.True(t, acquired, test.name)
+			}
+		}
+	})
+
+	t.Run("SlidingExpiration", func(t *testing.T) {
+		keys := []string{uuid.New().String(), uuid.New().String()}
+		adapter, err := From[string, string](client,
+			WithExpiration(time.Minute),
+			WithSlidingExpiration(time.Hour, 0))
+		require.NoError(t, err)
+
+		for _, key := range keys {
+			require.NoError(t, adapter.Set(ctx, key, "value1"))
+
+			ttl, err := client.PTTL(ctx, key).Result()
+			require.NoError(t, err)
+			require.LessOrEqual(t, ttl, time.Minute)
+		}
+
+		value, err := adapter.Get(ctx, keys[0])
+		require.NoError(t, err)
+		require.Equal(t, "value1", value)
+
+		results, err := adapter.GetMany(ctx, keys[1:])
+		require.NoError(t, err)
+		require.NoError(t, results[keys[1]].Err)
+		require.Equal(t, "value1", results[keys[1]].Value)
+
+		for _, key := range keys {
+			ttl, err := client.PTTL(ctx, key).Result()
+			require.NoError(t, err)
+			require.Greater(t, ttl, time.Minute)
+		}
+	})
+
+	t.Run("SlidingExpirationMaxAge", func(t *testing.T) {
+		keys := []string{uuid.New().String(), uuid.New().String()}
+		adapter, err := From[string, string](client, WithSlidingExpiration(time.Hour, time.Minute))
+		require.NoError(t, err)
+
+		for _, key := range keys {
+			require.NoError(t, adapter.Set(ctx, key, "value1"))
+		}
+
+		value, err := adapter.Get(ctx, keys[0])
+		require.NoError(t, err)
+		require.Equal(t, "value1", value)
+
+		results, err := adapter.GetMany(ctx, keys[1:])
+		require.NoError(t, err)
+		require.NoError(t, results[keys[1]].Err)
+
+		// Reads can't extend keys past their maximum age
+		for _, key := range keys {
+			ttl, err := client.PTTL(ctx, key).Result()
+			require.NoError(t, err)
+			require.Positive(t, ttl)
+			require.LessOrEqual(t, ttl, time.Minute)
+		}
+
+		// Keys past their maximum age are missing, and deleted
+		expired := appendDeadline(time.Now().Add(-time.Second), []byte("value1"))
+		for _, key := range keys {
+			require.NoError(t, client.Set(ctx, key, expired, 0).Err())
+		}
+
+		_, err = adapter.Get(ctx, keys[0])
+		require.ErrorIs(t, err, cachehit.ErrNotFound)
+
+		results, err = adapter.GetMany(ctx, keys[1:])
+		require.NoError(t, err)
+		require.ErrorIs(t, results[keys[1]].Err, cachehit.ErrNotFound)
+
+		for _, key := range keys {
+			exists, err := client.Exists(ctx, key).Result()
+			require.NoError(t, err)
+			require.Zero(t, exists)
+		}
+	})
+
+	t.Run("DeadlineMagicPayload", func(t *testing.T) {
+		payload := appendDeadline(time.Now().Add(-time.Second), []byte("value1"))
+
+		plain, err := From[string, []byte](client, WithCodec(codec.Bytes()))
+		require.NoError(t, err)
+
+		sliding, err := From[string, []byte](client,
+			WithCodec(codec.Bytes()),
+			WithSlidingExpiration(time.Hour, time.Minute))
+		require.NoError(t, err)
+
+		// Payloads that look like a deadline header are stored as is
+		for _, adapter := range []*Redis[string, []byte]{plain, sliding} {
+			key := uuid.New().String()
+			require.NoError(t, adapter.Set(ctx, key, payload))
+
+			value, err := adapter.Get(ctx, key)
+			require.NoError(t, err)
+			require.Equal(t, payload, value)
+		}
+	})
+
+	t.Run("DeadlineChecksum", func(t *testing.T) {
+		key := uuid.New().String()
+		adapter, err := From[string, string](client,
+			WithChecksum(false),
+			WithSlidingExpiration(time.Hour, time.Minute))
+		require.NoError(t, err)
+
+		require.NoError(t, adapter.Set(ctx, key, "value1"))
+
+		// Modify the deadline, which is covered by the checksum
+		rawValue, err := client.Get(ctx, key).Bytes()
+		require.NoError(t, err)
+		rawValue[len(rawValue)-len("value1")-1] ^= 0xFF
+		require.NoError(t, client.Set(ctx, key, rawValue, time.Minute).Err())
+
+		_, err = adapter.Get(ctx, key)
+		require.ErrorIs(t, err, codec.ErrCorrupt)
+	})
+
 	t.Run("Expiration", func(t *testing.T) {
 		key := uuid.New().String()
-		adapter := From[string, string](client, WithExpiration(1*time.Second))
+		adapter, err := From[string, string](client, WithExpiration(1*time.Second))
+		require.NoError(t, err)
 
 		adapter.Set(ctx, key, "value1")
 
@@ -348,8 +1272,22 @@ func TestRedis_Operations(t *testing.T) {
 		require.Error(t, err)
 	})
 
+	t.Run("SetWithTTL", func(t *testing.T) {
+		key := uuid.New().String()
+		adapter, err := From[string, string](client, WithExpiration(time.Hour))
+		require.NoError(t, err)
+
+		require.NoError(t, adapter.SetWithTTL(ctx, key, "value1", time.Minute))
+
+		ttl, err := client.TTL(ctx, key).Result()
+		require.NoError(t, err)
+		require.LessOrEqual(t, ttl, time.Minute)
+		require.Greater(t, ttl, time.Duration(0))
+	})
+
 	t.Run("IntKey", func(t *testing.T) {
-		adapter := From[int, string](client)
+		adapter, err := From[int, string](client)
+		require.NoError(t, err)
 
 		intKey := 123456789
 		adapter.Set(ctx, intKey, "value1")
@@ -362,144 +1300,157 @@ func TestRedis_Operations(t *testing.T) {
 	t.Run("ParsingErrors", func(t *testing.T) {
 		t.Run("Bool", func(t *testing.T) {
 			key := uuid.New().String()
-			adapter := From[string, bool](client)
+			adapter, err := From[string, bool](client)
+			require.NoError(t, err)
 
 			cmd := client.Set(ctx, key, "invalid", 0)
 			require.NoError(t, cmd.Err())
 
-			_, err := adapter.Get(ctx, key)
+			_, err = adapter.Get(ctx, key)
 			require.Error(t, err)
 		})
 
 		t.Run("Int", func(t *testing.T) {
 			key := uuid.New().String()
-			adapter := From[string, int](client)
+			adapter, err := From[string, int](client)
+			require.NoError(t, err)
 
 			cmd := client.Set(ctx, key, "not-a-number", 0)
 			require.NoError(t, cmd.Err())
 
-			_, err := adapter.Get(ctx, key)
+			_, err = adapter.Get(ctx, key)
 			require.Error(t, err)
 		})
 
 		t.Run("Int8", func(t *testing.T) {
 			key := uuid.New().String()
-			adapter := From[string, int8](client)
+			adapter, err := From[string, int8](client)
+			require.NoError(t, err)
 
 			cmd := client.Set(ctx, key, "999", 0)
 			require.NoError(t, cmd.Err())
 
-			_, err := adapter.Get(ctx, key)
+			_, err = adapter.Get(ctx, key)
 			require.Error(t, err)
 		})
 
 		t.Run("Int16", func(t *testing.T) {
 			key := uuid.New().String()
-			adapter := From[string, int16](client)
+			adapter, err := From[string, int16](client)
+			require.NoError(t, err)
 
 			cmd := client.Set(ctx, key, "99999", 0)
 			require.NoError(t, cmd.Err())
 
-			_, err := adapter.Get(ctx, key)
+			_, err = adapter.Get(ctx, key)
 			require.Error(t, err)
 		})
 
 		t.Run("Int32", func(t *testing.T) {
 			key := uuid.New().String()
-			adapter := From[string, int32](client)
+			adapter, err := From[string, int32](client)
+			require.NoError(t, err)
 
 			cmd := client.Set(ctx, key, "9999999999", 0)
 			require.NoError(t, cmd.Err())
 
-			_, err := adapter.Get(ctx, key)
+			_, err = adapter.Get(ctx, key)
 			require.Error(t, err)
 		})
 
 		t.Run("Int64", func(t *testing.T) {
 			key := uuid.New().String()
-			adapter := From[string, int64](client)
+			adapter, err := From[string, int64](client)
+			require.NoError(t, err)
 
 			cmd := client.Set(ctx, key, "not-a-number", 0)
 			require.NoError(t, cmd.Err())
 
-			_, err := adapter.Get(ctx, key)
+			_, err = adapter.Get(ctx, key)
 			require.Error(t, err)
 		})
 
 		t.Run("Uint", func(t *testing.T) {
 			key := uuid.New().String()
-			adapter := From[string, uint](client)
+			adapter, err := From[string, uint](client)
+			require.NoError(t, err)
 
 			cmd := client.Set(ctx, key, "-42", 0)
 			require.NoError(t, cmd.Err())
 
-			_, err := adapter.Get(ctx, key)
+			_, err = adapter.Get(ctx, key)
 			require.Error(t, err)
 		})
 
 		t.Run("Uint8", func(t *testing.T) {
 			key := uuid.New().String()
-			adapter := From[string, uint8](client)
+			adapter, err := From[string, uint8](client)
+			require.NoError(t, err)
 
 			cmd := client.Set(ctx, key, "999", 0)
 			require.NoError(t, cmd.Err())
 
-			_, err := adapter.Get(ctx, key)
+			_, err = adapter.Get(ctx, key)
 			require.Error(t, err)
 		})
 
 		t.Run("Uint16", func(t *testing.T) {
 			key := uuid.New().String()
-			adapter := From[string, uint16](client)
+			adapter, err := From[string, uint16](client)
+			require.NoError(t, err)
 
 			cmd := client.Set(ctx, key, "99999", 0)
 			require.NoError(t, cmd.Err())
 
-			_, err := adapter.Get(ctx, key)
+			_, err = adapter.Get(ctx, key)
 			require.Error(t, err)
 		})
 
 		t.Run("Uint32", func(t *testing.T) {
 			key := uuid.New().String()
-			adapter := From[string, uint32](client)
+			adapter, err := From[string, uint32](client)
+			require.NoError(t, err)
 
 			cmd := client.Set(ctx, key, "9999999999", 0)
 			require.NoError(t, cmd.Err())
 
-			_, err := adapter.Get(ctx, key)
+			_, err = adapter.Get(ctx, key)
 			require.Error(t, err)
 		})
 
 		t.Run("Uint64", func(t *testing.T) {
 			key := uuid.New().String()
-			adapter := From[string, uint64](client)
+			adapter, err := From[string, uint64](client)
+			require.NoError(t, err)
 
 			cmd := client.Set(ctx, key, "-1", 0)
 			require.NoError(t, cmd.Err())
 
-			_, err := adapter.Get(ctx, key)
+			_, err = adapter.Get(ctx, key)
 			require.Error(t, err)
 		})
 
 		t.Run("Float32", func(t *testing.T) {
 			key := uuid.New().String()
-			adapter := From[string, float32](client)
+			adapter, err := From[string, float32](client)
+			require.NoError(t, err)
 
 			cmd := client.Set(ctx, key, "not-a-float", 0)
 			require.NoError(t, cmd.Err())
 
-			_, err := adapter.Get(ctx, key)
+			_, err = adapter.Get(ctx, key)
 			require.Error(t, err)
 		})
 
 		t.Run("Float64", func(t *testing.T) {
 			key := uuid.New().String()
-			adapter := From[string, float64](client)
+			adapter, err := From[string, float64](client)
+			require.NoError(t, err)
 
 			cmd := client.Set(ctx, key, "not-a-float", 0)
 			require.NoError(t, cmd.Err())
 
-			_, err := adapter.Get(ctx, key)
+			_, err = adapter.Get(ctx, key)
 			require.Error(t, err)
 		})
 
@@ -510,12 +1461,13 @@ func TestRedis_Operations(t *testing.T) {
 			}
 
 			key := uuid.New().String()
-			adapter := From[string, TestStruct](client)
+			adapter, err := From[string, TestStruct](client)
+			require.NoError(t, err)
 
 			cmd := client.Set(ctx, key, "invalid-json{", 0)
 			require.NoError(t, cmd.Err())
 
-			_, err := adapter.Get(ctx, key)
+			_, err = adapter.Get(ctx, key)
 			require.Error(t, err)
 		})
 	})
@@ -523,9 +1475,10 @@ func TestRedis_Operations(t *testing.T) {
 	t.Run("MarshallingErrors", func(t *testing.T) {
 		t.Run("UnsupportedType", func(t *testing.T) {
 			key := uuid.New().String()
-			adapter := From[string, chan int](client)
+			adapter, err := From[string, chan int](client)
+			require.NoError(t, err)
 
-			err := adapter.Set(ctx, key, make(chan int))
+			err = adapter.Set(ctx, key, make(chan int))
 			require.Error(t, err)
 		})
 
@@ -535,9 +1488,10 @@ func TestRedis_Operations(t *testing.T) {
 			}
 
 			key := uuid.New().String()
-			adapter := From[string, InvalidStruct](client)
+			adapter, err := From[string, InvalidStruct](client)
+			require.NoError(t, err)
 
-			err := adapter.Set(ctx, key, InvalidStruct{Ch: make(chan int)})
+			err = adapter.Set(ctx, key, InvalidStruct{Ch: make(chan int)})
 			require.Error(t, err)
 		})
 	})
@@ -0,0 +1,82 @@
+package adapter
+
+import (
+	"context"
+	"fmt"
+	"sync"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// Bus is an invalidation bus over Redis pub/sub.
+// Messages are published to a single channel.
+type Bus struct {
+	underlying redis.UniversalClient
+	channel    string
+
+	pubsub *redis.PubSub
+	done   chan struct{}
+
+	mu       sync.RWMutex
+	handlers []func(message string)
+}
+
+// NewBus subscribes to the specified channel and starts dispatching received
+// messages to the bus subscribers. Call Close to unsubscribe.
+func NewBus(
+	ctx context.Context,
+	underlying redis.UniversalClient,
+	channel string,
+) (*Bus, error) {
+	pubsub := underlying.Subscribe(ctx, channel)
+
+	// Wait for the subscription to be confirmed, so no publications are missed
+	if _, err := pubsub.Receive(ctx); err != nil {
+		pubsub.Close()
+		return nil, fmt.Errorf("subscribe: %w", err)
+	}
+
+	b := &Bus{
+		underlying: underlying,
+		channel:    channel,
+		pubsub:     pubsub,
+		done:       make(chan struct{}),
+	}
+
+	go b.receive()
+
+	return b, nil
+}
+
+func (b *Bus) receive() {
+	defer close(b.done)
+
+	for msg := range b.pubsub.Channel() {
+		b.mu.RLock()
+		handlers := b.handlers
+		b.mu.RUnlock()
+
+		for _, handler := range handlers {
+			handler(msg.Payload)
+		}
+	}
+}
+
+func (b *Bus) Publish(ctx context.Context, message string) error {
+	cmd := b.underlying.Publish(ctx, b.channel, message)
+	return cmd.Err()
+}
+
+func (b *Bus) Subscribe(handler func(message string)) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.handlers = append(b.handlers, handler)
+}
+
+// Close unsubscribes from the channel and waits for pending dispatches to complete.
+func (b *Bus) Close() error {
+	err := b.pubsub.Close()
+	<-b.done
+	return err
+}
@@ -0,0 +1,143 @@
+package adapter
+
+import (
+	"context"
+	"crypto/rand"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/dtrugman/cachehit/internal"
+	"github.com/redis/go-redis/v9"
+)
+
+const (
+	DefaultLeaseOutcomeTTL = time.Second
+)
+
+const (
+	// Lease keys hold the token of their holder, or the outcome published by
+	// a holder that didn't store a value. Tokens never start with '!'.
+	leaseNotFound = "!notfound"
+	leaseFailed   = "!failed"
+)
+
+// acquireLeaseScript takes a lease, unless it's held or holds an outcome,
+// in which case the current value is returned instead.
+var acquireLeaseScript = redis.NewScript(`
+local current = redis.call("GET", KEYS[1])
+if current then
+	return current
+end
+redis.call("SET", KEYS[1], ARGV[1], "PX", ARGV[2])
+return false
+`)
+
+// releaseLeaseScript releases a lease only if it's still held by the specified token,
+// either deleting it, or replacing it with the specified outcome.
+var releaseLeaseScript = redis.NewScript(`
+if redis.call("GET", KEYS[1]) ~= ARGV[1] then
+	return 0
+end
+if ARGV[2] == "" then
+	return redis.call("DEL", KEYS[1])
+end
+redis.call("SET", KEYS[1], ARGV[2], "PX", ARGV[3])
+return 1
+`)
+
+type leaseOptions struct {
+	outcomeTTL time.Duration
+}
+
+type LeaseOption func(*leaseOptions)
+
+// LeaseWithOutcomeTTL configures the lease to keep the outcome published by
+// a holder that didn't store a value for the specified amount of time.
+// Defaults to DefaultLeaseOutcomeTTL.
+func LeaseWithOutcomeTTL(ttl time.Duration) LeaseOption {
+	return func(o *leaseOptions) {
+		o.outcomeTTL = ttl
+	}
+}
+
+// Lease is a distributed lease over Redis, stored under the lease key.
+// Leases expire after the specified ttl, so crashed holders don't block others.
+type Lease struct {
+	underlying redis.UniversalClient
+	prefix     string
+	ttl        time.Duration
+	outcomeTTL time.Duration
+}
+
+// NewLease creates a new lease, storing lease keys under the specified prefix.
+// The ttl should comfortably exceed the expected fetch duration.
+func NewLease(
+	underlying redis.UniversalClient,
+	prefix string,
+	ttl time.Duration,
+	opts ...LeaseOption,
+) (*Lease, error) {
+	if ttl <= time.Duration(0) {
+		return nil, fmt.Errorf("ttl must be positive")
+	}
+
+	o := &leaseOptions{
+		outcomeTTL: DefaultLeaseOutcomeTTL,
+	}
+	for _, opt := range opts {
+		opt(o)
+	}
+
+	if o.outcomeTTL <= time.Duration(0) {
+		return nil, fmt.Errorf("outcome ttl must be positive")
+	}
+
+	return &Lease{
+		underlying: underlying,
+		prefix:     prefix,
+		ttl:        ttl,
+		outcomeTTL: o.outcomeTTL,
+	}, nil
+}
+
+func (l *Lease) Acquire(ctx context.Context, key string) (func(error), bool, error) {
+	leaseKey := l.prefix + key
+	token := rand.Text()
+
+	current, err := acquireLeaseScript.Run(ctx, l.underlying, []string{leaseKey},
+		token, max(l.ttl.Milliseconds(), 1)).Text()
+	if errors.Is(err, redis.Nil) {
+		// Acquired
+	} else if err != nil {
+		return nil, false, fmt.Errorf("acquire: %w", err)
+	} else if current == leaseNotFound {
+		return nil, false, fmt.Errorf("lease holder: %w", internal.ErrNotFound)
+	} else if current == leaseFailed {
+		return nil, false, internal.ErrLeaseFailed
+	} else {
+		return nil, false, nil
+	}
+
+	release := func(fetchErr error) {
+		// Release even if the fetch context was canceled, or others wait for the ttl
+		ctx, cancel := context.WithTimeout(context.WithoutCancel(ctx), l.ttl)
+		defer cancel()
+
+		// A holder whose context was canceled says nothing about the key,
+		// so others are free to fetch it right away
+		outcome := ""
+		if errors.Is(fetchErr, internal.ErrNotFound) {
+			outcome = leaseNotFound
+		} else if fetchErr != nil && !errors.Is(fetchErr, context.Canceled) && !errors.Is(fetchErr, context.DeadlineExceeded) {
+			outcome = leaseFailed
+		}
+
+		// Release only if still held by this owner, a holder whose lease
+		// expired must not release a lease taken by someone else
+		_ = releaseLeaseScript.Run(ctx, l.underlying, []string{leaseKey},
+			token, outcome, max(l.outcomeTTL.Milliseconds(), 1)).Err()
+	}
+
+	return release, true, nil
+}
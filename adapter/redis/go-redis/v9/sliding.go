@@ -0,0 +1,111 @@
+package adapter
+
+import (
+	"bytes"
+	"encoding/binary"
+	"time"
+)
+
+// deadlineMagic prefixes values stored while sliding expiration or a maximum age is
+// configured (see WithSlidingExpiration), followed by the deadline in unix milliseconds
+// (8 bytes), or zero if there's none. The deadline is stored in the value, since sliding
+// expiration overrides the expiration set when the value was written. Every value is
+// prefixed, so values that happen to start with the magic can't be mistaken for a header.
+var deadlineMagic = []byte{0xC4, 0xC8, 0x64}
+
+const deadlineHeaderSize = 11
+
+func appendDeadline(deadline time.Time, data []byte) []byte {
+	var unixMilli int64
+	if !deadline.IsZero() {
+		unixMilli = deadline.UnixMilli()
+	}
+
+	encoded := make([]byte, 0, deadlineHeaderSize+len(data))
+	encoded = append(encoded, deadlineMagic...)
+	encoded = binary.BigEndian.AppendUint64(encoded, uint64(unixMilli))
+	return append(encoded, data...)
+}
+
+// splitDeadline returns the deadline of the specified value, if any, and the value itself.
+// Values stored before sliding expiration was configured aren't prefixed.
+func splitDeadline(data []byte) (time.Time, []byte) {
+	if len(data) < deadlineHeaderSize || !bytes.HasPrefix(data, deadlineMagic) {
+		return time.Time{}, data
+	}
+
+	unixMilli := binary.BigEndian.Uint64(data[len(deadlineMagic):deadlineHeaderSize])
+	if unixMilli == 0 {
+		return time.Time{}, data[deadlineHeaderSize:]
+	}
+	return time.UnixMilli(int64(unixMilli)), data[deadlineHeaderSize:]
+}
+
+// WithSlidingExpiration configures the adapter to reset the expiration of keys to
+// the specified ttl whenever they are read (using GETEX), so frequently read keys don't expire.
+// If maxAge is positive, keys expire once that long has passed since they were written,
+// regardless of reads. The expiration set when keys are written is configured separately,
+// using WithExpiration.
+func WithSlidingExpiration(ttl time.Duration, maxAge time.Duration) Option {
+	return func(o *options) {
+		o.slidingExpiration = ttl
+		o.maxAge = maxAge
+	}
+}
+
+// writeExpiration returns the expiration of written keys, capped by the maximum age.
+func (r *Redis[K, V]) writeExpiration() time.Duration {
+	return r.capExpiration(r.expiration)
+}
+
+// capExpiration caps the specified expiration by the maximum age.
+func (r *Redis[K, V]) capExpiration(expiration time.Duration) time.Duration {
+	if r.maxAge > 0 && (expiration <= 0 || expiration > r.maxAge) {
+		return r.maxAge
+	}
+	return expiration
+}
+
+// sliding reports whether values are stored along with their deadline.
+func (r *Redis[K, V]) sliding() bool {
+	return r.slidingExpiration > 0 || r.maxAge > 0
+}
+
+func (r *Redis[K, V]) encode(value V) ([]byte, error) {
+	data, err := r.codec.Encode(value)
+	if err != nil {
+		return nil, err
+	}
+
+	if r.sliding() {
+		var deadline time.Time
+		if r.maxAge > 0 {
+			deadline = time.Now().Add(r.maxAge)
+		}
+		data = appendDeadline(deadline, data)
+	}
+
+	return r.envelope.Encode(data)
+}
+
+// unwrap returns the deadline of the specified stored value, if any,
+// and the value encoded by the codec.
+func (r *Redis[K, V]) unwrap(rawValue []byte) (time.Time, []byte, error) {
+	data, err := r.envelope.Decode(rawValue)
+	if err != nil {
+		return time.Time{}, nil, err
+	}
+
+	if !r.sliding() {
+		return time.Time{}, data, nil
+	}
+
+	deadline, data := splitDeadline(data)
+	return deadline, data, nil
+}
+
+// extended reports whether reading a key with the specified deadline extended it past
+// the deadline, in which case its expiration must be reset to the deadline.
+func (r *Redis[K, V]) extended(deadline time.Time) bool {
+	return r.slidingExpiration > 0 && time.Now().Add(r.slidingExpiration).After(deadline)
+}
@@ -0,0 +1,36 @@
+package adapter
+
+import (
+	"strings"
+)
+
+const (
+	clusterSlots = 16384
+)
+
+// crc16 implements CRC16-CCITT (XMODEM), as used by Redis Cluster.
+func crc16(data string) uint16 {
+	var crc uint16
+	for i := 0; i < len(data); i++ {
+		crc ^= uint16(data[i]) << 8
+		for range 8 {
+			if crc&0x8000 != 0 {
+				crc = (crc << 1) ^ 0x1021
+			} else {
+				crc <<= 1
+			}
+		}
+	}
+	return crc
+}
+
+// slot returns the Redis Cluster hash slot of the specified key,
+// honoring hash tags (e.g. "{user}.name" and "{user}.email" share a slot).
+func slot(key string) int {
+	if start := strings.IndexByte(key, '{'); start >= 0 {
+		if end := strings.IndexByte(key[start+1:], '}'); end > 0 {
+			key = key[start+1 : start+1+end]
+		}
+	}
+	return int(crc16(key) % clusterSlots)
+}
@@ -0,0 +1,19 @@
+package adapter
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestSlot(t *testing.T) {
+	// Reference values taken from the Redis Cluster specification and `CLUSTER KEYSLOT`
+	require.Equal(t, uint16(0x31C3), crc16("123456789"))
+	require.Equal(t, 12182, slot("foo"))
+	require.Equal(t, 5061, slot("bar"))
+
+	require.Equal(t, slot("user"), slot("{user}.name"))
+	require.Equal(t, slot("{user}.name"), slot("{user}.email"))
+	require.Equal(t, slot("{}.name"), slot("{}.name"))
+	require.NotEqual(t, slot("{}.name"), slot("{}.email"))
+}
@@ -0,0 +1,58 @@
+package adapter
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/dtrugman/cachehit/internal"
+	"github.com/redis/go-redis/v9"
+)
+
+// SWRStore stores SWR entries in Redis, so the stale and dead times of each key
+// are shared by all processes, instead of being tracked by each of them separately.
+// Keys expire once their entries are dead.
+type SWRStore[K comparable, V any] struct {
+	redis *Redis[K, *internal.Entry[V]]
+}
+
+// NewSWRStore creates a new SWR store over the specified client, to be used with
+// cachehit.NewSWRFromCache. Entries are serialized as a whole, so a codec passed
+// using WithCodec must handle *cachehit.SWREntry[V]. WithExpiration is ignored, as keys
+// expire once their entries are dead (or once their maximum age passes, if sooner).
+// Sliding expiration isn't supported, as it would keep dead entries around.
+func NewSWRStore[K comparable, V any](
+	underlying redis.UniversalClient,
+	opts ...Option,
+) (*SWRStore[K, V], error) {
+	r, err := From[K, *internal.Entry[V]](underlying, opts...)
+	if err != nil {
+		return nil, err
+	}
+
+	if r.slidingExpiration > 0 {
+		return nil, fmt.Errorf("options: sliding expiration: not supported by SWR stores")
+	}
+
+	return &SWRStore[K, V]{
+		redis: r,
+	}, nil
+}
+
+func (s *SWRStore[K, V]) Get(ctx context.Context, key K) (*internal.Entry[V], error) {
+	return s.redis.Get(ctx, key)
+}
+
+func (s *SWRStore[K, V]) Set(ctx context.Context, key K, entry *internal.Entry[V]) error {
+	ttl := time.Until(entry.DeadAt)
+	if ttl <= time.Duration(0) {
+		// A dead entry is as good as a missing one
+		return s.redis.Delete(ctx, key)
+	}
+
+	return s.redis.SetWithTTL(ctx, key, entry, ttl)
+}
+
+func (s *SWRStore[K, V]) Delete(ctx context.Context, key K) error {
+	return s.redis.Delete(ctx, key)
+}
@@ -0,0 +1,395 @@
+package adapter
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/hashicorp/golang-lru/v2/expirable"
+	"github.com/redis/go-redis/v9"
+)
+
+const (
+	// TrackingChannel is the channel Redis publishes invalidation messages to,
+	// when tracking is redirected to a connection using RESP2.
+	TrackingChannel = "__redis__:invalidate"
+)
+
+// trackingHealthCheckInterval is the time without invalidations after which the
+// subscriber connection is pinged, so a dead connection is detected and replaced.
+const trackingHealthCheckInterval = time.Minute
+
+type trackingOptions struct {
+	maxAge time.Duration
+	opts   []Option
+}
+
+type TrackingOption func(*trackingOptions)
+
+// TrackingWithMaxAge configures the tracking adapter to drop local values after
+// the specified amount of time, regardless of invalidations. Defaults to no limit.
+func TrackingWithMaxAge(maxAge time.Duration) TrackingOption {
+	return func(o *trackingOptions) {
+		o.maxAge = maxAge
+	}
+}
+
+// TrackingWithAdapterOptions configures the tracking adapter to read and write
+// values using the specified adapter options (e.g. WithCodec).
+func TrackingWithAdapterOptions(opts ...Option) TrackingOption {
+	return func(o *trackingOptions) {
+		o.opts = append(o.opts, opts...)
+	}
+}
+
+// Tracking is a Redis adapter that keeps a bounded local copy of the values it reads,
+// using server-assisted client side caching (CLIENT TRACKING, Redis 6+).
+// Redis pushes an invalidation message whenever a key read through the adapter
+// changes, and the local copy is dropped instantly. Invalidations that can't be
+// attributed to keys (e.g. following FLUSHALL) drop all local values.
+//
+// Tracking requires a redis.Client. For other clients, or if the server doesn't
+// support tracking, the adapter falls back to plain reads (see Enabled).
+type Tracking[K comparable, V any] struct {
+	fallback *Redis[K, V]
+	base     *redis.Options
+
+	subscriber *redis.Client
+	pubsub     *redis.PubSub
+	done       chan struct{}
+
+	mu      sync.Mutex
+	tracker *trackedClient[K, V]
+	local   *expirable.LRU[string, V]
+	pending map[string]uint64
+	seq     uint64
+}
+
+// trackedClient is a tracked client, along with the operations using it, so it's
+// only closed once they complete.
+type trackedClient[K comparable, V any] struct {
+	client  *redis.Client
+	tracked *Redis[K, V]
+	users   sync.WaitGroup
+}
+
+// close waits for the operations using the client to complete, and closes it.
+func (c *trackedClient[K, V]) close() {
+	c.users.Wait()
+	c.client.Close()
+}
+
+// NewTracking creates a new tracking adapter, keeping up to size values locally.
+// The adapter opens its own connections, using the options of the specified client.
+// Use TrackingWithMaxAge to bound the time a value is kept locally, as a safety net.
+// Fails if tracking can't be enabled for any reason other than lack of support.
+func NewTracking[K comparable, V any](
+	ctx context.Context,
+	underlying redis.UniversalClient,
+	size int,
+	opts ...TrackingOption,
+) (*Tracking[K, V], error) {
+	if size <= 0 {
+		return nil, fmt.Errorf("size must be positive")
+	}
+
+	o := &trackingOptions{}
+	for _, opt := range opts {
+		opt(o)
+	}
+
+	fallback, err := From[K, V](underlying, o.opts...)
+	if err != nil {
+		return nil, err
+	}
+
+	t := &Tracking[K, V]{
+		fallback: fallback,
+		local:    expirable.NewLRU[string, V](size, nil, o.maxAge),
+		pending:  make(map[string]uint64),
+	}
+
+	client, ok := underlying.(*redis.Client)
+	if !ok {
+		return t, nil
+	}
+
+	t.base = client.Options()
+
+	if err := t.enable(ctx); err != nil {
+		t.Close()
+
+		if trackingUnsupported(err) {
+			// Fallback to plain reads
+			return t, nil
+		}
+		return nil, err
+	}
+
+	return t, nil
+}
+
+// trackingUnsupported reports whether the server rejected the commands enabling
+// tracking as unknown, e.g. when it predates CLIENT TRACKING.
+func trackingUnsupported(err error) bool {
+	var redisErr redis.Error
+	if !errors.As(err, &redisErr) {
+		return false
+	}
+
+	msg := strings.ToLower(redisErr.Error())
+	return strings.Contains(msg, "unknown command") || strings.Contains(msg, "unknown subcommand")
+}
+
+func (t *Tracking[K, V]) enable(ctx context.Context) error {
+	// Invalidations are redirected to a dedicated connection subscribed to the
+	// tracking channel, which requires RESP2 to receive them as messages.
+	subscriberOpts := *t.base
+	subscriberOpts.Protocol = 2
+	subscriberOpts.OnConnect = func(ctx context.Context, cn *redis.Conn) error {
+		id, err := cn.ClientID(ctx).Result()
+		if err != nil {
+			return fmt.Errorf("client id: %w", err)
+		}
+		t.redirect(id)
+		return nil
+	}
+
+	t.subscriber = redis.NewClient(&subscriberOpts)
+	t.pubsub = t.subscriber.Subscribe(ctx, TrackingChannel)
+	if _, err := t.pubsub.Receive(ctx); err != nil {
+		return fmt.Errorf("subscribe: %w", err)
+	}
+
+	t.done = make(chan struct{})
+	go t.receive()
+
+	t.mu.Lock()
+	tracker := t.tracker
+	t.mu.Unlock()
+
+	// Make sure tracking is supported by opening a tracked connection
+	if err := tracker.client.Ping(ctx).Err(); err != nil {
+		return fmt.Errorf("tracking: %w", err)
+	}
+
+	return nil
+}
+
+// redirect (re)creates the tracked client, redirecting invalidations to the
+// specified subscriber connection. Called whenever the subscriber connects.
+func (t *Tracking[K, V]) redirect(id int64) {
+	trackerOpts := *t.base
+	onConnect := trackerOpts.OnConnect
+	trackerOpts.OnConnect = func(ctx context.Context, cn *redis.Conn) error {
+		if onConnect != nil {
+			if err := onConnect(ctx, cn); err != nil {
+				return err
+			}
+		}
+		return cn.Do(ctx, "CLIENT", "TRACKING", "ON", "REDIRECT", id).Err()
+	}
+
+	client := redis.NewClient(&trackerOpts)
+
+	t.mu.Lock()
+	previous := t.tracker
+	t.tracker = &trackedClient[K, V]{
+		client:  client,
+		tracked: t.fallback.over(client),
+	}
+
+	if previous != nil {
+		// Invalidations might have been missed while the subscriber was disconnected
+		t.local.Purge()
+		clear(t.pending)
+	}
+	t.mu.Unlock()
+
+	if previous != nil {
+		// Operations still using the previous client are left to complete
+		go previous.close()
+	}
+}
+
+// acquire returns the current tracker, or nil if tracking is disabled.
+// Call release once done using it.
+func (t *Tracking[K, V]) acquire() *trackedClient[K, V] {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	if t.tracker != nil {
+		t.tracker.users.Add(1)
+	}
+	return t.tracker
+}
+
+func (t *Tracking[K, V]) release(tracker *trackedClient[K, V]) {
+	tracker.users.Done()
+}
+
+// receive reads invalidations from the subscriber connection. Messages are read
+// directly rather than through PubSub.Channel, which drops the invalidations
+// Redis sends with a null payload after FLUSHALL and FLUSHDB.
+func (t *Tracking[K, V]) receive() {
+	defer close(t.done)
+
+	ctx := context.Background()
+
+	var errCount int
+	for {
+		msg, err := t.pubsub.ReceiveTimeout(ctx, trackingHealthCheckInterval)
+		if errors.Is(err, redis.ErrClosed) {
+			return
+		}
+
+		var netErr net.Error
+		if errors.As(err, &netErr) && netErr.Timeout() {
+			// A failed ping replaces the connection, which purges the local values
+			_ = t.pubsub.Ping(ctx)
+			continue
+		} else if err != nil {
+			// Either a flush, or an invalidation that was lost along with its keys
+			t.purge()
+
+			if errCount > 0 {
+				time.Sleep(100 * time.Millisecond)
+			}
+			errCount++
+			continue
+		}
+
+		errCount = 0
+
+		if msg, ok := msg.(*redis.Message); ok {
+			if msg.PayloadSlice != nil {
+				for _, key := range msg.PayloadSlice {
+					t.invalidate(key)
+				}
+			} else {
+				t.invalidate(msg.Payload)
+			}
+		}
+	}
+}
+
+func (t *Tracking[K, V]) purge() {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	t.local.Purge()
+	clear(t.pending)
+}
+
+func (t *Tracking[K, V]) invalidate(keyStr string) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	t.local.Remove(keyStr)
+	delete(t.pending, keyStr)
+}
+
+// Enabled reports whether values are tracked and kept locally.
+func (t *Tracking[K, V]) Enabled() bool {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	return t.tracker != nil
+}
+
+func (t *Tracking[K, V]) Get(ctx context.Context, key K) (V, error) {
+	keyStr := fmt.Sprintf("%v", key)
+
+	t.mu.Lock()
+	tracker := t.tracker
+	if tracker == nil {
+		t.mu.Unlock()
+		return t.fallback.Get(ctx, key)
+	}
+
+	if value, ok := t.local.Get(keyStr); ok {
+		t.mu.Unlock()
+		return value, nil
+	}
+
+	// An invalidation received while fetching clears the token,
+	// so a value that is already outdated isn't kept locally.
+	t.seq++
+	token := t.seq
+	t.pending[keyStr] = token
+	tracker.users.Add(1)
+	t.mu.Unlock()
+
+	value, err := tracker.tracked.Get(ctx, key)
+	t.release(tracker)
+
+	t.mu.Lock()
+	if t.pending[keyStr] == token {
+		delete(t.pending, keyStr)
+		if err == nil {
+			t.local.Add(keyStr, value)
+		}
+	}
+	t.mu.Unlock()
+
+	return value, err
+}
+
+func (t *Tracking[K, V]) Set(ctx context.Context, key K, value V) error {
+	tracker := t.acquire()
+	if tracker == nil {
+		return t.fallback.Set(ctx, key, value)
+	}
+
+	err := tracker.tracked.Set(ctx, key, value)
+	t.release(tracker)
+
+	t.invalidate(fmt.Sprintf("%v", key))
+	return err
+}
+
+func (t *Tracking[K, V]) Delete(ctx context.Context, key K) error {
+	tracker := t.acquire()
+	if tracker == nil {
+		return t.fallback.Delete(ctx, key)
+	}
+
+	err := tracker.tracked.Delete(ctx, key)
+	t.release(tracker)
+
+	t.invalidate(fmt.Sprintf("%v", key))
+	return err
+}
+
+// Close releases the connections opened by the adapter.
+// The client passed to NewTracking is not closed.
+func (t *Tracking[K, V]) Close() error {
+	var err error
+
+	if t.pubsub != nil {
+		err = t.pubsub.Close()
+		if t.done != nil {
+			<-t.done
+		}
+	}
+
+	if t.subscriber != nil {
+		t.subscriber.Close()
+	}
+
+	t.mu.Lock()
+	tracker := t.tracker
+	t.tracker = nil
+	t.local.Purge()
+	t.mu.Unlock()
+
+	if tracker != nil {
+		tracker.close()
+	}
+
+	return err
+}
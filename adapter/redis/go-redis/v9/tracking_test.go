@@ -0,0 +1,213 @@
+package adapter
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/redis/go-redis/v9"
+	"github.com/stretchr/testify/require"
+
+	"github.com/dtrugman/cachehit"
+	"github.com/dtrugman/cachehit/example/resource"
+)
+
+func TestNewTracking_InvalidSize(t *testing.T) {
+	client := redis.NewClient(&redis.Options{})
+
+	_, err := NewTracking[string, string](context.Background(), client, 0)
+	require.Error(t, err)
+}
+
+func TestNewTracking_Fallback(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("Cluster", func(t *testing.T) {
+		client := redis.NewClusterClient(&redis.ClusterOptions{})
+
+		tracking, err := NewTracking[string, string](ctx, client, 16)
+		require.NoError(t, err)
+		require.False(t, tracking.Enabled())
+		require.NoError(t, tracking.Close())
+	})
+
+}
+
+func TestNewTracking_Disconnected(t *testing.T) {
+	client := redis.NewClient(&redis.Options{
+		Addr: "localhost:9999",
+	})
+
+	// Only unsupported servers fall back to plain reads
+	_, err := NewTracking[string, string](context.Background(), client, 16)
+	require.Error(t, err)
+}
+
+type serverError string
+
+func (e serverError) Error() string { return string(e) }
+
+func (serverError) RedisError() {}
+
+func TestTrackingUnsupported(t *testing.T) {
+	require.True(t, trackingUnsupported(serverError("ERR unknown subcommand 'TRACKING'")))
+	require.True(t, trackingUnsupported(fmt.Errorf("tracking: %w", serverError("ERR Unknown subcommand or wrong number of arguments for 'TRACKING'"))))
+	require.True(t, trackingUnsupported(serverError("ERR unknown command 'CLIENT'")))
+	require.False(t, trackingUnsupported(serverError("NOAUTH Authentication required.")))
+	require.False(t, trackingUnsupported(errors.New("ERR unknown command 'CLIENT'")))
+}
+
+func TestTrackedClient_Close(t *testing.T) {
+	tracker := &trackedClient[string, string]{
+		client: redis.NewClient(&redis.Options{Addr: "localhost:9999"}),
+	}
+	tracker.users.Add(1)
+
+	closed := make(chan struct{})
+	go func() {
+		tracker.close()
+		close(closed)
+	}()
+
+	// The client is in use, so it isn't closed yet
+	select {
+	case <-closed:
+		require.Fail(t, "closed while in use")
+	case <-time.After(50 * time.Millisecond):
+	}
+
+	tracker.users.Done()
+
+	select {
+	case <-closed:
+	case <-time.After(5 * time.Second):
+		require.Fail(t, "not closed once released")
+	}
+	require.ErrorIs(t, tracker.client.Ping(context.Background()).Err(), redis.ErrClosed)
+}
+
+func TestTracking_Cache(t *testing.T) {
+	var _ cachehit.Cache[string, int] = (*Tracking[string, int])(nil)
+	var _ cachehit.Deleter[string] = (*Tracking[string, int])(nil)
+}
+
+func TestTracking_Operations(t *testing.T) {
+	ctx := context.Background()
+
+	instance, err := resource.RedisRun(ctx)
+	require.NoError(t, err)
+
+	t.Cleanup(func() {
+		require.NoError(t, instance.Cleanup())
+	})
+
+	client, err := resource.RedisConn(ctx, instance.DSN)
+	require.NoError(t, err)
+
+	t.Cleanup(func() {
+		client.Close()
+	})
+
+	tracking, err := NewTracking[string, string](ctx, client, 16)
+	require.NoError(t, err)
+	require.True(t, tracking.Enabled())
+
+	t.Cleanup(func() {
+		require.NoError(t, tracking.Close())
+	})
+
+	t.Run("LocalCopy", func(t *testing.T) {
+		key := uuid.New().String()
+		require.NoError(t, client.Set(ctx, key, "value1", 0).Err())
+
+		value, err := tracking.Get(ctx, key)
+		require.NoError(t, err)
+		require.Equal(t, "value1", value)
+		require.True(t, tracking.local.Contains(key))
+
+		value, err = tracking.Get(ctx, key)
+		require.NoError(t, err)
+		require.Equal(t, "value1", value)
+	})
+
+	t.Run("InvalidatedByServer", func(t *testing.T) {
+		key := uuid.New().String()
+		require.NoError(t, client.Set(ctx, key, "value1", 0).Err())
+
+		value, err := tracking.Get(ctx, key)
+		require.NoError(t, err)
+		require.Equal(t, "value1", value)
+
+		// Modified by another client
+		require.NoError(t, client.Set(ctx, key, "value2", 0).Err())
+
+		require.Eventually(t, func() bool {
+			return !tracking.local.Contains(key)
+		}, 5*time.Second, 10*time.Millisecond)
+
+		value, err = tracking.Get(ctx, key)
+		require.NoError(t, err)
+		require.Equal(t, "value2", value)
+	})
+
+	t.Run("SetAndDelete", func(t *testing.T) {
+		key := uuid.New().String()
+
+		require.NoError(t, tracking.Set(ctx, key, "value1"))
+
+		value, err := tracking.Get(ctx, key)
+		require.NoError(t, err)
+		require.Equal(t, "value1", value)
+
+		require.NoError(t, tracking.Set(ctx, key, "value2"))
+		require.False(t, tracking.local.Contains(key))
+
+		value, err = tracking.Get(ctx, key)
+		require.NoError(t, err)
+		require.Equal(t, "value2", value)
+
+		require.NoError(t, tracking.Delete(ctx, key))
+
+		_, err = tracking.Get(ctx, key)
+		require.ErrorIs(t, err, cachehit.ErrNotFound)
+	})
+
+	t.Run("Flushed", func(t *testing.T) {
+		key := uuid.New().String()
+		require.NoError(t, client.Set(ctx, key, "value1", 0).Err())
+
+		_, err := tracking.Get(ctx, key)
+		require.NoError(t, err)
+		require.True(t, tracking.local.Contains(key))
+
+		// Invalidates all keys with a null payload
+		require.NoError(t, client.FlushDB(ctx).Err())
+
+		require.Eventually(t, func() bool {
+			return !tracking.local.Contains(key)
+		}, 5*time.Second, 10*time.Millisecond)
+
+		_, err = tracking.Get(ctx, key)
+		require.ErrorIs(t, err, cachehit.ErrNotFound)
+	})
+
+	t.Run("MaxAge", func(t *testing.T) {
+		tracking, err := NewTracking[string, string](ctx, client, 16, TrackingWithMaxAge(100*time.Millisecond))
+		require.NoError(t, err)
+		defer tracking.Close()
+
+		key := uuid.New().String()
+		require.NoError(t, client.Set(ctx, key, "value1", 0).Err())
+
+		_, err = tracking.Get(ctx, key)
+		require.NoError(t, err)
+		require.True(t, tracking.local.Contains(key))
+
+		require.Eventually(t, func() bool {
+			return !tracking.local.Contains(key)
+		}, 5*time.Second, 10*time.Millisecond)
+	})
+}
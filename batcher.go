@@ -0,0 +1,139 @@
+package cachehit
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+)
+
+type batcherCall[V any] struct {
+	done   chan struct{}
+	result Result[V]
+}
+
+// Batcher is a repository that collects concurrent single key fetches over a
+// short window, and fetches them using a single BatchRepository.GetMany call
+// (similar to DataLoader). Concurrent fetches of the same key share a result.
+type Batcher[K comparable, V any] struct {
+	repo BatchRepository[K, V]
+
+	maxBatchSize int
+	wait         time.Duration
+	timeout      time.Duration
+
+	mu      sync.Mutex
+	batch   uint64
+	keys    []K
+	pending map[K]*batcherCall[V]
+	timer   *time.Timer
+}
+
+func NewBatcher[K comparable, V any](repo BatchRepository[K, V], opts ...BatcherOption) (*Batcher[K, V], error) {
+	if repo == nil {
+		return nil, fmt.Errorf("nil repo")
+	}
+
+	o := batcherCompileOptions(opts...)
+	if err := o.Validate(); err != nil {
+		return nil, fmt.Errorf("options: %w", err)
+	}
+
+	return &Batcher[K, V]{
+		repo:         repo,
+		maxBatchSize: o.maxBatchSize,
+		wait:         o.wait,
+		timeout:      o.timeout,
+		pending:      make(map[K]*batcherCall[V]),
+	}, nil
+}
+
+// take removes the current batch, must be called with the lock held.
+func (b *Batcher[K, V]) take() ([]K, map[K]*batcherCall[V]) {
+	keys, pending := b.keys, b.pending
+
+	b.batch++
+	b.keys = nil
+	b.pending = make(map[K]*batcherCall[V])
+	if b.timer != nil {
+		b.timer.Stop()
+		b.timer = nil
+	}
+
+	return keys, pending
+}
+
+func (b *Batcher[K, V]) expire(batch uint64) {
+	b.mu.Lock()
+	if b.batch != batch {
+		// Already fetched, since it reached the max batch size
+		b.mu.Unlock()
+		return
+	}
+	keys, pending := b.take()
+	b.mu.Unlock()
+
+	b.fetch(keys, pending)
+}
+
+func (b *Batcher[K, V]) fetch(keys []K, pending map[K]*batcherCall[V]) {
+	ctx, cancel := context.WithTimeout(context.Background(), b.timeout)
+	defer cancel()
+
+	results, err := b.repo.GetMany(ctx, keys)
+
+	for _, key := range keys {
+		call := pending[key]
+
+		if err != nil {
+			call.result = Result[V]{Err: fmt.Errorf("repo get many: %w", err)}
+		} else if res, found := results[key]; found {
+			call.result = res
+		} else {
+			call.result = Result[V]{Err: ErrNotFound}
+		}
+
+		close(call.done)
+	}
+}
+
+func (b *Batcher[K, V]) Get(ctx context.Context, key K) (V, error) {
+	b.mu.Lock()
+	call, exists := b.pending[key]
+	if !exists {
+		call = &batcherCall[V]{done: make(chan struct{})}
+		b.pending[key] = call
+		b.keys = append(b.keys, key)
+
+		if len(b.keys) == 1 {
+			batch := b.batch
+			b.timer = time.AfterFunc(b.wait, func() {
+				b.expire(batch)
+			})
+		}
+	}
+
+	var keys []K
+	var pending map[K]*batcherCall[V]
+	if len(b.keys) >= b.maxBatchSize {
+		keys, pending = b.take()
+	}
+	b.mu.Unlock()
+
+	if keys != nil {
+		go b.fetch(keys, pending)
+	}
+
+	select {
+	case <-ctx.Done():
+		var v V
+		return v, ctx.Err()
+	case <-call.done:
+		return call.result.Value, call.result.Err
+	}
+}
+
+// GetMany fetches the specified keys directly, without batching them with other calls.
+func (b *Batcher[K, V]) GetMany(ctx context.Context, keys []K) (map[K]Result[V], error) {
+	return b.repo.GetMany(ctx, keys)
+}
@@ -0,0 +1,79 @@
+package cachehit
+
+import (
+	"fmt"
+	"time"
+)
+
+const (
+	BatcherDefaultMaxBatchSize = 100
+	BatcherDefaultWait         = 2 * time.Millisecond
+	BatcherDefaultTimeout      = 15 * time.Second
+)
+
+type batcherOptions struct {
+	maxBatchSize int
+	wait         time.Duration
+	timeout      time.Duration
+}
+
+func (o *batcherOptions) Validate() error {
+	if o.maxBatchSize <= 0 {
+		return fmt.Errorf("max batch size must be positive")
+	}
+
+	if o.wait <= time.Duration(0) {
+		return fmt.Errorf("wait must be positive")
+	}
+
+	if o.timeout <= time.Duration(0) {
+		return fmt.Errorf("timeout must be positive")
+	}
+
+	return nil
+}
+
+func batcherDefaultOptions() *batcherOptions {
+	return &batcherOptions{
+		maxBatchSize: BatcherDefaultMaxBatchSize,
+		wait:         BatcherDefaultWait,
+		timeout:      BatcherDefaultTimeout,
+	}
+}
+
+func batcherCompileOptions(opts ...BatcherOption) *batcherOptions {
+	o := batcherDefaultOptions()
+
+	for _, opt := range opts {
+		opt(o)
+	}
+
+	return o
+}
+
+type BatcherOption func(*batcherOptions)
+
+// BatcherWithMaxBatchSize configures the batcher to fetch a batch as soon as
+// it holds N distinct keys, without waiting for the rest of the window.
+func BatcherWithMaxBatchSize(size int) BatcherOption {
+	return func(o *batcherOptions) {
+		o.maxBatchSize = size
+	}
+}
+
+// BatcherWithWait configures the batcher to collect keys for the specified
+// amount of time, starting with the first key of a batch, before fetching them.
+func BatcherWithWait(wait time.Duration) BatcherOption {
+	return func(o *batcherOptions) {
+		o.wait = wait
+	}
+}
+
+// BatcherWithTimeout configures the batcher to timeout batch fetches after the
+// specified amount of time. Batches are shared by multiple callers, so they
+// aren't bound to the context of any of them.
+func BatcherWithTimeout(timeout time.Duration) BatcherOption {
+	return func(o *batcherOptions) {
+		o.timeout = timeout
+	}
+}
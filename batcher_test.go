@@ -0,0 +1,205 @@
+package cachehit
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+type batchRepoFunc[K comparable, V any] func(ctx context.Context, keys []K) (map[K]Result[V], error)
+
+func (f batchRepoFunc[K, V]) GetMany(ctx context.Context, keys []K) (map[K]Result[V], error) {
+	return f(ctx, keys)
+}
+
+// recordingRepo returns the value "value-<key>" for every key but "missing" and "broken"
+type recordingRepo struct {
+	mu      sync.Mutex
+	batches [][]string
+}
+
+func (r *recordingRepo) GetMany(_ context.Context, keys []string) (map[string]Result[string], error) {
+	r.mu.Lock()
+	r.batches = append(r.batches, append([]string(nil), keys...))
+	r.mu.Unlock()
+
+	results := make(map[string]Result[string], len(keys))
+	for _, key := range keys {
+		switch key {
+		case "missing":
+		case "broken":
+			results[key] = Result[string]{Err: errors.New("failure")}
+		default:
+			results[key] = Result[string]{Value: "value-" + key}
+		}
+	}
+	return results, nil
+}
+
+func (r *recordingRepo) recorded() [][]string {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	return append([][]string(nil), r.batches...)
+}
+
+func Test_Batcher_New_WithInvalidOptions(t *testing.T) {
+	repo := &recordingRepo{}
+
+	_, err := NewBatcher[string, string](nil)
+	require.ErrorContains(t, err, "repo")
+
+	_, err = NewBatcher(repo, BatcherWithMaxBatchSize(0))
+	require.ErrorContains(t, err, "max batch size")
+
+	_, err = NewBatcher(repo, BatcherWithWait(0))
+	require.ErrorContains(t, err, "wait")
+
+	_, err = NewBatcher(repo, BatcherWithTimeout(0))
+	require.ErrorContains(t, err, "timeout")
+}
+
+func Test_Batcher_Repository(t *testing.T) {
+	var _ Repository[string, string] = (*Batcher[string, string])(nil)
+	var _ BatchRepository[string, string] = (*Batcher[string, string])(nil)
+
+	cache := &mockCache[string, string]{}
+	batcher, err := NewBatcher(&recordingRepo{})
+	require.NoError(t, err)
+
+	_, err = NewLookThrough(cache, Repository[string, string](batcher))
+	require.NoError(t, err)
+}
+
+func Test_Batcher_ConcurrentGets(t *testing.T) {
+	ctx := t.Context()
+
+	n := 50
+
+	repo := &recordingRepo{}
+	batcher, err := NewBatcher(repo, BatcherWithWait(50*time.Millisecond))
+	require.NoError(t, err)
+
+	var wg sync.WaitGroup
+	for i := range n {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+
+			key := fmt.Sprintf("key%d", i)
+			value, err := batcher.Get(ctx, key)
+			require.NoError(t, err)
+			require.Equal(t, "value-"+key, value)
+		}()
+	}
+
+	for _, key := range []string{"missing", "broken"} {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+
+			_, err := batcher.Get(ctx, key)
+			if key == "missing" {
+				require.ErrorIs(t, err, ErrNotFound)
+			} else {
+				require.ErrorContains(t, err, "failure")
+			}
+		}()
+	}
+	wg.Wait()
+
+	batches := repo.recorded()
+	require.Len(t, batches, 1)
+	require.Len(t, batches[0], n+2)
+}
+
+func Test_Batcher_MaxBatchSize(t *testing.T) {
+	ctx := t.Context()
+
+	repo := &recordingRepo{}
+	batcher, err := NewBatcher(repo,
+		BatcherWithWait(50*time.Millisecond),
+		BatcherWithMaxBatchSize(4))
+	require.NoError(t, err)
+
+	var wg sync.WaitGroup
+	for i := range 10 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+
+			_, err := batcher.Get(ctx, fmt.Sprintf("key%d", i))
+			require.NoError(t, err)
+		}()
+	}
+	wg.Wait()
+
+	total := 0
+	for _, batch := range repo.recorded() {
+		require.LessOrEqual(t, len(batch), 4)
+		total += len(batch)
+	}
+	require.Equal(t, 10, total)
+}
+
+func Test_Batcher_DuplicateKeys(t *testing.T) {
+	ctx := t.Context()
+
+	repo := &recordingRepo{}
+	batcher, err := NewBatcher(repo, BatcherWithWait(50*time.Millisecond))
+	require.NoError(t, err)
+
+	var wg sync.WaitGroup
+	for range 5 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+
+			value, err := batcher.Get(ctx, "key")
+			require.NoError(t, err)
+			require.Equal(t, "value-key", value)
+		}()
+	}
+	wg.Wait()
+
+	require.Equal(t, [][]string{{"key"}}, repo.recorded())
+}
+
+func Test_Batcher_BatchError(t *testing.T) {
+	ctx := t.Context()
+
+	batchErr := errors.New("failure")
+	repo := batchRepoFunc[string, string](func(ctx context.Context, keys []string) (map[string]Result[string], error) {
+		return nil, batchErr
+	})
+
+	batcher, err := NewBatcher(repo)
+	require.NoError(t, err)
+
+	_, err = batcher.Get(ctx, "key")
+	require.ErrorIs(t, err, batchErr)
+}
+
+func Test_Batcher_ContextCanceled(t *testing.T) {
+	release := make(chan struct{})
+	defer close(release)
+
+	repo := batchRepoFunc[string, string](func(ctx context.Context, keys []string) (map[string]Result[string], error) {
+		<-release
+		return nil, nil
+	})
+
+	batcher, err := NewBatcher(repo)
+	require.NoError(t, err)
+
+	ctx, cancel := context.WithTimeout(t.Context(), 20*time.Millisecond)
+	defer cancel()
+
+	_, err = batcher.Get(ctx, "key")
+	require.ErrorIs(t, err, context.DeadlineExceeded)
+}
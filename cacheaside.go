@@ -0,0 +1,193 @@
+package cachehit
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"golang.org/x/sync/singleflight"
+)
+
+// CacheAside is a cache over a store, whose writes go to the store first,
+// and then update the cache. Within a process, reads that raced with a write
+// don't store the value they fetched before it in the cache. Across processes,
+// such values are dropped by a delayed second delete (see CacheAsideWithDoubleDelete).
+type CacheAside[K comparable, V any] struct {
+	cache Cache[K, V]
+	store Store[K, V]
+
+	dedup *singleflight.Group
+
+	mode              CacheAsideMode
+	doubleDeleteDelay time.Duration
+
+	errorCallback ErrorCallback
+
+	// guard keeps reads that started before a write from storing outdated values
+	guard *keyGuard[K]
+
+	// writes serializes writes of the same key, when they store their values
+	writes *keyLock[K]
+}
+
+func NewCacheAside[K comparable, V any](
+	cache Cache[K, V],
+	store Store[K, V],
+	opts ...CacheAsideOption,
+) (*CacheAside[K, V], error) {
+	if cache == nil {
+		return nil, fmt.Errorf("nil cache")
+	}
+
+	if store == nil {
+		return nil, fmt.Errorf("nil store")
+	}
+
+	o := cacheAsideCompileOptions(opts...)
+	if err := o.Validate(); err != nil {
+		return nil, fmt.Errorf("options: %w", err)
+	}
+
+	if _, ok := cache.(Deleter[K]); !ok {
+		return nil, fmt.Errorf("cache doesn't support delete")
+	}
+
+	return &CacheAside[K, V]{
+		cache: cache,
+		store: store,
+
+		dedup: new(singleflight.Group),
+
+		mode:              o.mode,
+		doubleDeleteDelay: o.doubleDeleteDelay,
+
+		errorCallback: o.errorCallback,
+
+		guard:  newKeyGuard[K](),
+		writes: newKeyLock[K](),
+	}, nil
+}
+
+func (c *CacheAside[K, V]) reportError(err error) {
+	if c.errorCallback != nil {
+		c.errorCallback(err)
+	}
+}
+
+func (c *CacheAside[K, V]) fetch(ctx context.Context, key K) (V, error) {
+	k := fmt.Sprintf("%v", key)
+	res, err, _ := c.dedup.Do(k, func() (interface{}, error) {
+		fetch := c.guard.begin(key)
+		defer c.guard.end(fetch)
+
+		value, err := c.store.Get(ctx, key)
+		if err != nil {
+			return nil, fmt.Errorf("store get: %w", err)
+		}
+
+		// Skip values that might have been overwritten while fetching
+		c.guard.store(fetch, func() {
+			if err := c.cache.Set(ctx, key, value); err != nil {
+				c.reportError(fmt.Errorf("cache set: %v: %w", key, err))
+			}
+		})
+		return value, nil
+	})
+
+	if err != nil {
+		var v V
+		return v, err
+	}
+
+	value, ok := res.(V)
+	if !ok {
+		var v V
+		return v, fmt.Errorf("value type: expected %T: found %T", v, res)
+	}
+
+	return value, nil
+}
+
+func (c *CacheAside[K, V]) Get(ctx context.Context, key K) (V, error) {
+	value, err := c.cache.Get(ctx, key)
+	if errors.Is(err, ErrNotFound) {
+		return c.fetch(ctx, key)
+	} else if err != nil {
+		c.reportError(fmt.Errorf("cache get: %v: %w", key, err))
+		return c.fetch(ctx, key)
+	}
+
+	return value, nil
+}
+
+// written updates the cache after the specified key was written to the store.
+// Waits for reads that are storing values fetched before the write.
+func (c *CacheAside[K, V]) written(ctx context.Context, key K, value V, deleted bool) {
+	c.guard.invalidate(key)
+
+	// Make sure later calls don't join a fetch that started before the write
+	c.dedup.Forget(fmt.Sprintf("%v", key))
+
+	if c.mode == CacheAsideSet && !deleted {
+		if err := c.cache.Set(ctx, key, value); err != nil {
+			c.reportError(fmt.Errorf("cache set: %v: %w", key, err))
+		}
+	} else {
+		c.delete(ctx, key)
+	}
+
+	if c.doubleDeleteDelay > 0 {
+		time.AfterFunc(c.doubleDeleteDelay, func() {
+			c.delete(context.WithoutCancel(ctx), key)
+		})
+	}
+}
+
+func (c *CacheAside[K, V]) delete(ctx context.Context, key K) {
+	if err := c.cache.(Deleter[K]).Delete(ctx, key); err != nil {
+		c.reportError(fmt.Errorf("cache delete: %v: %w", key, err))
+	}
+}
+
+// lockWrite serializes writes of the key that store their values, so the cache
+// ends up holding the value written to the store last. Returns a function that unlocks the key.
+func (c *CacheAside[K, V]) lockWrite(key K) func() {
+	if c.mode != CacheAsideSet {
+		return func() {}
+	}
+
+	return c.writes.lock(key)
+}
+
+// Update writes the value to the store, and then updates the cache.
+// Cache failures are reported to the error callback, as the write already succeeded.
+func (c *CacheAside[K, V]) Update(ctx context.Context, key K, value V) error {
+	defer c.lockWrite(key)()
+
+	if err := c.store.Write(ctx, key, value); err != nil {
+		return fmt.Errorf("store write: %w", err)
+	}
+
+	c.written(ctx, key, value, false)
+	return nil
+}
+
+// Delete deletes the key from the store, which must implement Deleter,
+// and then from the cache.
+func (c *CacheAside[K, V]) Delete(ctx context.Context, key K) error {
+	deleter, ok := c.store.(Deleter[K])
+	if !ok {
+		return fmt.Errorf("store delete: %v: not supported", key)
+	}
+
+	defer c.lockWrite(key)()
+
+	if err := deleter.Delete(ctx, key); err != nil {
+		return fmt.Errorf("store delete: %w", err)
+	}
+
+	var v V
+	c.written(ctx, key, v, true)
+	return nil
+}
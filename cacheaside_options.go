@@ -0,0 +1,86 @@
+package cachehit
+
+import (
+	"fmt"
+	"time"
+)
+
+// CacheAsideMode controls how the cache is updated after writes.
+type CacheAsideMode int
+
+const (
+	// CacheAsideDelete deletes written keys from the cache,
+	// so the next read fetches them from the store.
+	CacheAsideDelete CacheAsideMode = iota
+
+	// CacheAsideSet stores updated values in the cache. Deleted keys are
+	// deleted from the cache. Writes of the same key are serialized within
+	// the process, but concurrent writes from other processes sharing the cache
+	// may leave it holding a value other than the one written to the store last.
+	CacheAsideSet
+)
+
+type cacheAsideOptions struct {
+	mode CacheAsideMode
+
+	doubleDeleteDelay time.Duration
+
+	errorCallback ErrorCallback
+}
+
+func (o *cacheAsideOptions) Validate() error {
+	if o.mode != CacheAsideDelete && o.mode != CacheAsideSet {
+		return fmt.Errorf("unknown mode: %d", o.mode)
+	}
+
+	if o.doubleDeleteDelay < time.Duration(0) {
+		return fmt.Errorf("double delete delay must not be negative")
+	}
+
+	return nil
+}
+
+func cacheAsideDefaultOptions() *cacheAsideOptions {
+	return &cacheAsideOptions{
+		mode: CacheAsideDelete,
+	}
+}
+
+func cacheAsideCompileOptions(opts ...CacheAsideOption) *cacheAsideOptions {
+	o := cacheAsideDefaultOptions()
+
+	for _, opt := range opts {
+		opt(o)
+	}
+
+	return o
+}
+
+type CacheAsideOption func(*cacheAsideOptions)
+
+// CacheAsideWithErrorCallback configures the cache aside construct to call the
+// specified callback synchronously when an error happens during internal operations.
+// Errors of delayed deletes are reported from their own goroutine.
+func CacheAsideWithErrorCallback(errorCallback ErrorCallback) CacheAsideOption {
+	return func(o *cacheAsideOptions) {
+		o.errorCallback = errorCallback
+	}
+}
+
+// CacheAsideWithMode configures how the cache is updated after writes.
+// Defaults to CacheAsideDelete.
+func CacheAsideWithMode(mode CacheAsideMode) CacheAsideOption {
+	return func(o *cacheAsideOptions) {
+		o.mode = mode
+	}
+}
+
+// CacheAsideWithDoubleDelete configures the cache aside construct to delete
+// written keys from the cache once more after the specified delay, dropping
+// old values stored by reads that raced with the write in other processes.
+// The delay should exceed the duration of a read. Defaults to disabled.
+func CacheAsideWithDoubleDelete(delay time.Duration) CacheAsideOption {
+	return func(o *cacheAsideOptions) {
+		o.doubleDeleteDelay = delay
+	}
+}
@@ -0,0 +1,363 @@
+package cachehit
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	lru_adapter "github.com/dtrugman/cachehit/adapter/hashicorp/golang-lru/v2"
+	lru "github.com/hashicorp/golang-lru/v2"
+)
+
+// slowStore blocks reads until released, to simulate reads racing with writes
+type slowStore struct {
+	*fakeStore
+	fetched chan struct{}
+	release chan struct{}
+}
+
+func (s *slowStore) Get(ctx context.Context, key string) (int, error) {
+	value, err := s.fakeStore.Get(ctx, key)
+	close(s.fetched)
+	<-s.release
+	return value, err
+}
+
+// slowCache blocks sets of the slow value until released, to simulate writes racing with other writes
+type slowCache struct {
+	*lru_adapter.LRU[string, int]
+	slow    int
+	setting chan struct{}
+	release chan struct{}
+}
+
+func (c *slowCache) Set(ctx context.Context, key string, value int) error {
+	if value == c.slow {
+		close(c.setting)
+		<-c.release
+	}
+	return c.LRU.Set(ctx, key, value)
+}
+
+// slowWriteStore blocks writes of the slow value until released, after writing them
+type slowWriteStore struct {
+	*fakeStore
+	slow    int
+	written chan struct{}
+	release chan struct{}
+}
+
+func (s *slowWriteStore) Write(ctx context.Context, key string, value int) error {
+	err := s.fakeStore.Write(ctx, key, value)
+	if value == s.slow {
+		close(s.written)
+		<-s.release
+	}
+	return err
+}
+
+func newCacheAside(
+	t *testing.T,
+	store Store[string, int],
+	opts ...CacheAsideOption,
+) (*CacheAside[string, int], *lru_adapter.LRU[string, int]) {
+	t.Helper()
+
+	cache, err := lru.New[string, int](128)
+	require.NoError(t, err)
+	adapter := lru_adapter.From(cache)
+
+	ca, err := NewCacheAside(adapter, store, opts...)
+	require.NoError(t, err)
+
+	return ca, adapter
+}
+
+func Test_CacheAside_New_WithInvalidArgs(t *testing.T) {
+	cache := &mockCache[string, int]{}
+	store := newFakeStore()
+
+	_, err := NewCacheAside(nil, Store[string, int](store))
+	require.ErrorContains(t, err, "cache")
+
+	_, err = NewCacheAside[string, int](cache, nil)
+	require.ErrorContains(t, err, "store")
+
+	_, err = NewCacheAside(cache, Store[string, int](store), CacheAsideWithMode(CacheAsideMode(42)))
+	require.ErrorContains(t, err, "mode")
+
+	_, err = NewCacheAside(cache, Store[string, int](store), CacheAsideWithDoubleDelete(-time.Second))
+	require.ErrorContains(t, err, "double delete")
+
+	// Hide Delete
+	noDelete := struct{ Cache[string, int] }{cache}
+	_, err = NewCacheAside(noDelete, Store[string, int](store))
+	require.ErrorContains(t, err, "delete")
+}
+
+func Test_CacheAside_Get(t *testing.T) {
+	ctx := t.Context()
+
+	store := newFakeStore()
+	require.NoError(t, store.Write(ctx, "key", 1))
+
+	ca, cache := newCacheAside(t, store)
+
+	value, err := ca.Get(ctx, "key")
+	require.NoError(t, err)
+	require.Equal(t, 1, value)
+
+	cached, err := cache.Get(ctx, "key")
+	require.NoError(t, err)
+	require.Equal(t, 1, cached)
+
+	_, err = ca.Get(ctx, "missing")
+	require.ErrorIs(t, err, ErrNotFound)
+}
+
+func Test_CacheAside_Update_Delete(t *testing.T) {
+	ctx := t.Context()
+
+	store := newFakeStore()
+	ca, cache := newCacheAside(t, store)
+
+	require.NoError(t, ca.Update(ctx, "key", 1))
+	_, err := ca.Get(ctx, "key")
+	require.NoError(t, err)
+
+	require.NoError(t, ca.Update(ctx, "key", 2))
+
+	_, err = cache.Get(ctx, "key")
+	require.ErrorIs(t, err, ErrNotFound)
+
+	value, err := ca.Get(ctx, "key")
+	require.NoError(t, err)
+	require.Equal(t, 2, value)
+}
+
+func Test_CacheAside_Update_Set(t *testing.T) {
+	ctx := t.Context()
+
+	store := newFakeStore()
+	ca, cache := newCacheAside(t, store, CacheAsideWithMode(CacheAsideSet))
+
+	require.NoError(t, ca.Update(ctx, "key", 1))
+
+	cached, err := cache.Get(ctx, "key")
+	require.NoError(t, err)
+	require.Equal(t, 1, cached)
+
+	values, _ := store.snapshot()
+	require.Equal(t, map[string]int{"key": 1}, values)
+}
+
+func Test_CacheAside_Update_StoreError(t *testing.T) {
+	ctx := t.Context()
+
+	store := newFakeStore()
+	store.fail(1)
+
+	ca, cache := newCacheAside(t, store, CacheAsideWithMode(CacheAsideSet))
+
+	require.Error(t, ca.Update(ctx, "key", 1))
+
+	_, err := cache.Get(ctx, "key")
+	require.ErrorIs(t, err, ErrNotFound)
+}
+
+func Test_CacheAside_Delete(t *testing.T) {
+	ctx := t.Context()
+
+	store := newFakeStore()
+	ca, cache := newCacheAside(t, store, CacheAsideWithMode(CacheAsideSet))
+
+	require.NoError(t, ca.Update(ctx, "key", 1))
+	require.NoError(t, ca.Delete(ctx, "key"))
+
+	_, err := cache.Get(ctx, "key")
+	require.ErrorIs(t, err, ErrNotFound)
+
+	_, err = ca.Get(ctx, "key")
+	require.ErrorIs(t, err, ErrNotFound)
+}
+
+func Test_CacheAside_Delete_NotSupported(t *testing.T) {
+	ctx := t.Context()
+
+	// Hide Delete
+	store := struct{ Store[string, int] }{newFakeStore()}
+	ca, _ := newCacheAside(t, store)
+
+	err := ca.Delete(ctx, "key")
+	require.ErrorContains(t, err, "not supported")
+}
+
+func Test_CacheAside_ReadRacingWithUpdate(t *testing.T) {
+	ctx := t.Context()
+
+	store := &slowStore{
+		fakeStore: newFakeStore(),
+		fetched:   make(chan struct{}),
+		release:   make(chan struct{}),
+	}
+	require.NoError(t, store.Write(ctx, "key", 1))
+
+	ca, cache := newCacheAside(t, store)
+
+	type result struct {
+		value int
+		err   error
+	}
+	read := make(chan result)
+	go func() {
+		value, err := ca.Get(ctx, "key")
+		read <- result{value, err}
+	}()
+
+	// The read fetched the old value, and the update completes before it returns
+	<-store.fetched
+	require.NoError(t, ca.Update(ctx, "key", 2))
+	close(store.release)
+
+	res := <-read
+	require.NoError(t, res.err)
+	require.Equal(t, 1, res.value)
+
+	// The old value isn't cached
+	_, err := cache.Get(ctx, "key")
+	require.ErrorIs(t, err, ErrNotFound)
+}
+
+func Test_CacheAside_UpdateRacingWithReadStore(t *testing.T) {
+	ctx := t.Context()
+
+	store := newFakeStore()
+	require.NoError(t, store.Write(ctx, "key", 1))
+
+	lruCache, err := lru.New[string, int](128)
+	require.NoError(t, err)
+	cache := &slowCache{
+		LRU:     lru_adapter.From(lruCache),
+		slow:    1,
+		setting: make(chan struct{}),
+		release: make(chan struct{}),
+	}
+
+	ca, err := NewCacheAside[string, int](cache, store)
+	require.NoError(t, err)
+
+	read := make(chan error)
+	go func() {
+		_, err := ca.Get(ctx, "key")
+		read <- err
+	}()
+
+	// The read fetched the old value before the update, and is storing it
+	<-cache.setting
+
+	updated := make(chan error, 1)
+	go func() {
+		updated <- ca.Update(ctx, "key", 2)
+	}()
+
+	// The update waits for the store to complete, so it can't be undone by it
+	require.Never(t, func() bool {
+		return len(updated) > 0
+	}, 50*time.Millisecond, 5*time.Millisecond)
+
+	close(cache.release)
+	require.NoError(t, <-read)
+	require.NoError(t, <-updated)
+
+	// The old value isn't cached
+	_, err = cache.Get(ctx, "key")
+	require.ErrorIs(t, err, ErrNotFound)
+}
+
+func Test_CacheAside_Update_Set_Concurrent(t *testing.T) {
+	ctx := t.Context()
+
+	store := &slowWriteStore{
+		fakeStore: newFakeStore(),
+		slow:      1,
+		written:   make(chan struct{}),
+		release:   make(chan struct{}),
+	}
+
+	ca, cache := newCacheAside(t, store, CacheAsideWithMode(CacheAsideSet))
+
+	first := make(chan error)
+	go func() {
+		first <- ca.Update(ctx, "key", 1)
+	}()
+
+	// The first update wrote the store, but didn't update the cache yet
+	<-store.written
+
+	second := make(chan error, 1)
+	go func() {
+		second <- ca.Update(ctx, "key", 2)
+	}()
+
+	// The second update waits for the first one to complete
+	require.Never(t, func() bool {
+		return len(second) > 0
+	}, 50*time.Millisecond, 5*time.Millisecond)
+
+	close(store.release)
+	require.NoError(t, <-first)
+	require.NoError(t, <-second)
+
+	// The cache holds the value written to the store last
+	stored, err := store.Get(ctx, "key")
+	require.NoError(t, err)
+	require.Equal(t, 2, stored)
+
+	cached, err := cache.Get(ctx, "key")
+	require.NoError(t, err)
+	require.Equal(t, 2, cached)
+}
+
+func Test_CacheAside_DoubleDelete(t *testing.T) {
+	ctx := t.Context()
+
+	store := newFakeStore()
+	ca, cache := newCacheAside(t, store, CacheAsideWithDoubleDelete(20*time.Millisecond))
+
+	require.NoError(t, ca.Update(ctx, "key", 2))
+
+	// A read in another process stores the old value after the first delete
+	require.NoError(t, cache.Set(ctx, "key", 1))
+
+	require.Eventually(t, func() bool {
+		_, err := cache.Get(ctx, "key")
+		return errors.Is(err, ErrNotFound)
+	}, time.Second, 5*time.Millisecond)
+}
+
+func Test_CacheAside_ErrorCallback_CacheDelete(t *testing.T) {
+	ctx := t.Context()
+
+	cacheDeleteErr := errors.New("failure")
+	cache := &mockCache[string, int]{}
+	cache.On("Delete", ctx, "key").Return(cacheDeleteErr)
+
+	store := newFakeStore()
+
+	var capturedErr error
+	ca, err := NewCacheAside(cache, Store[string, int](store),
+		CacheAsideWithErrorCallback(func(err error) {
+			capturedErr = err
+		}))
+	require.NoError(t, err)
+
+	// The write succeeded, so the cache error is only reported
+	require.NoError(t, ca.Update(ctx, "key", 1))
+	require.ErrorIs(t, capturedErr, cacheDeleteErr)
+	require.ErrorContains(t, capturedErr, "key")
+
+	cache.AssertExpectations(t)
+}
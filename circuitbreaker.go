@@ -0,0 +1,276 @@
+package cachehit
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+)
+
+// CircuitState is the state of a circuit breaker.
+type CircuitState int
+
+const (
+	// CircuitClosed lets all requests through.
+	CircuitClosed CircuitState = iota
+
+	// CircuitOpen fails all requests with ErrCircuitOpen.
+	CircuitOpen
+
+	// CircuitHalfOpen lets a limited number of trial requests through.
+	CircuitHalfOpen
+)
+
+func (s CircuitState) String() string {
+	switch s {
+	case CircuitClosed:
+		return "closed"
+	case CircuitOpen:
+		return "open"
+	case CircuitHalfOpen:
+		return "half open"
+	default:
+		return fmt.Sprintf("unknown (%d)", int(s))
+	}
+}
+
+// circuitOutcome is the outcome of a request let through the circuit.
+type circuitOutcome int
+
+const (
+	circuitSuccess circuitOutcome = iota
+	circuitFailure
+	// circuitIgnored requests, e.g. canceled by their callers, say nothing about the repository
+	circuitIgnored
+)
+
+// CircuitBreaker is a repository that stops fetching from a failing repository,
+// failing fast with ErrCircuitOpen instead. Once open, it lets trial requests
+// through after a timeout, and closes again if they succeed.
+// SWR recognizes ErrCircuitOpen, and serves dead values rather than failing.
+type CircuitBreaker[K comparable, V any] struct {
+	repo Repository[K, V]
+
+	consecutiveFailures int
+	failureRate         float64
+
+	openTimeout      time.Duration
+	halfOpenRequests int
+
+	isFailure func(err error) bool
+
+	stateCallback func(from, to CircuitState)
+
+	mu sync.Mutex
+
+	state    CircuitState
+	openedAt time.Time
+
+	// generation changes on every state change, so results of requests made
+	// in a previous state are ignored
+	generation uint64
+
+	// Closed state
+	consecutive int
+	outcomes    []bool
+	observed    int
+	failures    int
+
+	// Half open state
+	trials    int
+	successes int
+}
+
+func NewCircuitBreaker[K comparable, V any](
+	repo Repository[K, V],
+	opts ...CircuitBreakerOption,
+) (*CircuitBreaker[K, V], error) {
+	if repo == nil {
+		return nil, fmt.Errorf("nil repo")
+	}
+
+	o := circuitBreakerCompileOptions(opts...)
+	if err := o.Validate(); err != nil {
+		return nil, fmt.Errorf("options: %w", err)
+	}
+
+	cb := &CircuitBreaker[K, V]{
+		repo:                repo,
+		consecutiveFailures: o.consecutiveFailures,
+		failureRate:         o.failureRate,
+		openTimeout:         o.openTimeout,
+		halfOpenRequests:    o.halfOpenRequests,
+		isFailure:           o.isFailure,
+		stateCallback:       o.stateCallback,
+	}
+
+	if o.failureRate > 0 {
+		cb.outcomes = make([]bool, o.window)
+	}
+
+	return cb, nil
+}
+
+// State returns the current state of the circuit.
+func (cb *CircuitBreaker[K, V]) State() CircuitState {
+	cb.mu.Lock()
+	defer cb.mu.Unlock()
+
+	if cb.state == CircuitOpen && cb.expired() {
+		return CircuitHalfOpen
+	}
+	return cb.state
+}
+
+// expired reports whether the open timeout passed, must be called with the lock held.
+func (cb *CircuitBreaker[K, V]) expired() bool {
+	return !time.Now().Before(cb.openedAt.Add(cb.openTimeout))
+}
+
+// transition changes the state of the circuit, must be called with the lock held.
+// Returns a function notifying the state callback, to be called without the lock.
+func (cb *CircuitBreaker[K, V]) transition(to CircuitState) func() {
+	from := cb.state
+
+	cb.state = to
+	cb.generation++
+
+	switch to {
+	case CircuitClosed:
+		cb.consecutive = 0
+		cb.observed = 0
+		cb.failures = 0
+	case CircuitOpen:
+		cb.openedAt = time.Now()
+	case CircuitHalfOpen:
+		cb.trials = 0
+		cb.successes = 0
+	}
+
+	return func() {
+		if cb.stateCallback != nil {
+			cb.stateCallback(from, to)
+		}
+	}
+}
+
+// acquire returns the generation in which a request is let through,
+// or ErrCircuitOpen if it isn't.
+func (cb *CircuitBreaker[K, V]) acquire() (uint64, error) {
+	notify := func() {}
+	defer func() { notify() }()
+
+	cb.mu.Lock()
+	defer cb.mu.Unlock()
+
+	if cb.state == CircuitOpen {
+		if !cb.expired() {
+			return 0, ErrCircuitOpen
+		}
+		notify = cb.transition(CircuitHalfOpen)
+	}
+
+	if cb.state == CircuitHalfOpen {
+		if cb.trials >= cb.halfOpenRequests {
+			return 0, ErrCircuitOpen
+		}
+		cb.trials++
+	}
+
+	return cb.generation, nil
+}
+
+// observe records the outcome of a request made while the circuit is closed,
+// and returns whether it trips the circuit, must be called with the lock held.
+func (cb *CircuitBreaker[K, V]) observe(failed bool) bool {
+	if failed {
+		cb.consecutive++
+	} else {
+		cb.consecutive = 0
+	}
+
+	if cb.consecutiveFailures > 0 && cb.consecutive >= cb.consecutiveFailures {
+		return true
+	}
+
+	if cb.outcomes == nil {
+		return false
+	}
+
+	window := len(cb.outcomes)
+	i := cb.observed % window
+	if cb.observed >= window && cb.outcomes[i] {
+		cb.failures--
+	}
+	cb.outcomes[i] = failed
+	if failed {
+		cb.failures++
+	}
+	cb.observed++
+
+	return cb.observed >= window && float64(cb.failures) >= cb.failureRate*float64(window)
+}
+
+// release records the outcome of a request let through in the specified generation.
+// Ignored trials free their slot, so another trial can be let through instead.
+func (cb *CircuitBreaker[K, V]) release(generation uint64, outcome circuitOutcome) {
+	notify := func() {}
+	defer func() { notify() }()
+
+	cb.mu.Lock()
+	defer cb.mu.Unlock()
+
+	if generation != cb.generation {
+		return
+	}
+
+	if outcome == circuitIgnored {
+		if cb.state == CircuitHalfOpen {
+			cb.trials--
+		}
+		return
+	}
+
+	failed := outcome == circuitFailure
+
+	switch cb.state {
+	case CircuitClosed:
+		if cb.observe(failed) {
+			notify = cb.transition(CircuitOpen)
+		}
+	case CircuitHalfOpen:
+		if failed {
+			notify = cb.transition(CircuitOpen)
+			return
+		}
+		cb.successes++
+		if cb.successes >= cb.halfOpenRequests {
+			notify = cb.transition(CircuitClosed)
+		}
+	}
+}
+
+// Get fetches the specified key from the repository, unless the circuit is open,
+// in which case ErrCircuitOpen is returned.
+func (cb *CircuitBreaker[K, V]) Get(ctx context.Context, key K) (V, error) {
+	generation, err := cb.acquire()
+	if err != nil {
+		var v V
+		return v, err
+	}
+
+	value, err := cb.repo.Get(ctx, key)
+	cb.release(generation, cb.outcome(err))
+
+	return value, err
+}
+
+func (cb *CircuitBreaker[K, V]) outcome(err error) circuitOutcome {
+	if errors.Is(err, context.Canceled) {
+		return circuitIgnored
+	} else if cb.isFailure(err) {
+		return circuitFailure
+	}
+	return circuitSuccess
+}
@@ -0,0 +1,139 @@
+package cachehit
+
+import (
+	"errors"
+	"fmt"
+	"time"
+)
+
+const (
+	CircuitBreakerDefaultConsecutiveFailures = 5
+	CircuitBreakerDefaultFailureRate         = 0.5
+	CircuitBreakerDefaultWindow              = 20
+	CircuitBreakerDefaultOpenTimeout         = 10 * time.Second
+	CircuitBreakerDefaultHalfOpenRequests    = 1
+)
+
+type circuitBreakerOptions struct {
+	consecutiveFailures int
+
+	failureRate float64
+	window      int
+
+	openTimeout      time.Duration
+	halfOpenRequests int
+
+	isFailure func(err error) bool
+
+	stateCallback func(from, to CircuitState)
+}
+
+func (o *circuitBreakerOptions) Validate() error {
+	if o.consecutiveFailures < 0 {
+		return fmt.Errorf("consecutive failures must be non-negative")
+	}
+
+	if o.failureRate < 0 || o.failureRate > 1 {
+		return fmt.Errorf("failure rate must be in [0, 1]")
+	}
+
+	if o.failureRate > 0 && o.window <= 0 {
+		return fmt.Errorf("window must be positive")
+	}
+
+	if o.openTimeout <= time.Duration(0) {
+		return fmt.Errorf("open timeout must be positive")
+	}
+
+	if o.halfOpenRequests <= 0 {
+		return fmt.Errorf("half open requests must be positive")
+	}
+
+	if o.isFailure == nil {
+		return fmt.Errorf("nil failure classifier")
+	}
+
+	return nil
+}
+
+// isCircuitFailure is the default failure classifier. Not found keys are valid responses.
+func isCircuitFailure(err error) bool {
+	return err != nil && !errors.Is(err, ErrNotFound)
+}
+
+func circuitBreakerDefaultOptions() *circuitBreakerOptions {
+	return &circuitBreakerOptions{
+		consecutiveFailures: CircuitBreakerDefaultConsecutiveFailures,
+		failureRate:         CircuitBreakerDefaultFailureRate,
+		window:              CircuitBreakerDefaultWindow,
+		openTimeout:         CircuitBreakerDefaultOpenTimeout,
+		halfOpenRequests:    CircuitBreakerDefaultHalfOpenRequests,
+		isFailure:           isCircuitFailure,
+	}
+}
+
+func circuitBreakerCompileOptions(opts ...CircuitBreakerOption) *circuitBreakerOptions {
+	o := circuitBreakerDefaultOptions()
+
+	for _, opt := range opts {
+		opt(o)
+	}
+
+	return o
+}
+
+type CircuitBreakerOption func(*circuitBreakerOptions)
+
+// CircuitBreakerWithConsecutiveFailures configures the circuit breaker to open
+// after N consecutive failures. Zero disables this trip condition.
+func CircuitBreakerWithConsecutiveFailures(n int) CircuitBreakerOption {
+	return func(o *circuitBreakerOptions) {
+		o.consecutiveFailures = n
+	}
+}
+
+// CircuitBreakerWithFailureRate configures the circuit breaker to open once
+// the specified fraction (e.g. 0.5) of the last N requests failed.
+// The rate is only checked once N requests were made since the circuit closed.
+// Zero disables this trip condition.
+func CircuitBreakerWithFailureRate(rate float64, window int) CircuitBreakerOption {
+	return func(o *circuitBreakerOptions) {
+		o.failureRate = rate
+		o.window = window
+	}
+}
+
+// CircuitBreakerWithOpenTimeout configures the circuit breaker to stay open
+// for the specified amount of time, before letting trial requests through (half open).
+func CircuitBreakerWithOpenTimeout(timeout time.Duration) CircuitBreakerOption {
+	return func(o *circuitBreakerOptions) {
+		o.openTimeout = timeout
+	}
+}
+
+// CircuitBreakerWithHalfOpenRequests configures the circuit breaker to let
+// N trial requests through when half open. The circuit closes once all of
+// them succeed, and opens again as soon as one of them fails.
+func CircuitBreakerWithHalfOpenRequests(n int) CircuitBreakerOption {
+	return func(o *circuitBreakerOptions) {
+		o.halfOpenRequests = n
+	}
+}
+
+// CircuitBreakerWithFailureClassifier configures the circuit breaker to count
+// only errors classified as failures by the specified classifier.
+// By default, all errors but ErrNotFound are failures. Requests canceled by their
+// callers (context.Canceled) are never classified, and count neither way.
+func CircuitBreakerWithFailureClassifier(isFailure func(err error) bool) CircuitBreakerOption {
+	return func(o *circuitBreakerOptions) {
+		o.isFailure = isFailure
+	}
+}
+
+// CircuitBreakerWithStateCallback configures the circuit breaker to call the
+// specified callback synchronously whenever its state changes.
+func CircuitBreakerWithStateCallback(stateCallback func(from, to CircuitState)) CircuitBreakerOption {
+	return func(o *circuitBreakerOptions) {
+		o.stateCallback = stateCallback
+	}
+}
@@ -0,0 +1,353 @@
+package cachehit
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+// flakyRepo fails all requests while failing is set
+type flakyRepo struct {
+	failing atomic.Bool
+	calls   atomic.Int32
+}
+
+var errFlaky = errors.New("failure")
+
+func (r *flakyRepo) Get(ctx context.Context, key string) (string, error) {
+	r.calls.Add(1)
+
+	if r.failing.Load() {
+		return "", errFlaky
+	}
+	if key == "missing" {
+		return "", ErrNotFound
+	}
+	return "value", nil
+}
+
+func Test_CircuitBreaker_New_WithInvalidOptions(t *testing.T) {
+	repo := &flakyRepo{}
+
+	_, err := NewCircuitBreaker[string, string](nil)
+	require.ErrorContains(t, err, "repo")
+
+	_, err = NewCircuitBreaker(repo, CircuitBreakerWithConsecutiveFailures(-1))
+	require.ErrorContains(t, err, "consecutive failures")
+
+	_, err = NewCircuitBreaker(repo, CircuitBreakerWithFailureRate(1.5, 10))
+	require.ErrorContains(t, err, "failure rate")
+
+	_, err = NewCircuitBreaker(repo, CircuitBreakerWithFailureRate(0.5, 0))
+	require.ErrorContains(t, err, "window")
+
+	_, err = NewCircuitBreaker(repo, CircuitBreakerWithOpenTimeout(0))
+	require.ErrorContains(t, err, "open timeout")
+
+	_, err = NewCircuitBreaker(repo, CircuitBreakerWithHalfOpenRequests(0))
+	require.ErrorContains(t, err, "half open requests")
+
+	_, err = NewCircuitBreaker(repo, CircuitBreakerWithFailureClassifier(nil))
+	require.ErrorContains(t, err, "classifier")
+}
+
+func Test_CircuitBreaker_ConsecutiveFailures(t *testing.T) {
+	ctx := t.Context()
+
+	repo := &flakyRepo{}
+	cb, err := NewCircuitBreaker(repo,
+		CircuitBreakerWithConsecutiveFailures(3),
+		CircuitBreakerWithFailureRate(0, 0))
+	require.NoError(t, err)
+
+	repo.failing.Store(true)
+	for range 2 {
+		_, err := cb.Get(ctx, "key")
+		require.ErrorIs(t, err, errFlaky)
+	}
+
+	// A success resets the consecutive failures
+	repo.failing.Store(false)
+	_, err = cb.Get(ctx, "key")
+	require.NoError(t, err)
+
+	repo.failing.Store(true)
+	for range 3 {
+		_, err := cb.Get(ctx, "key")
+		require.ErrorIs(t, err, errFlaky)
+	}
+	require.Equal(t, CircuitOpen, cb.State())
+
+	_, err = cb.Get(ctx, "key")
+	require.ErrorIs(t, err, ErrCircuitOpen)
+	require.Equal(t, int32(6), repo.calls.Load())
+}
+
+func Test_CircuitBreaker_FailureRate(t *testing.T) {
+	ctx := t.Context()
+
+	repo := &flakyRepo{}
+	cb, err := NewCircuitBreaker(repo,
+		CircuitBreakerWithConsecutiveFailures(0),
+		CircuitBreakerWithFailureRate(0.5, 4))
+	require.NoError(t, err)
+
+	// Alternating failures never reach the consecutive failures, but reach the rate
+	for i := range 3 {
+		repo.failing.Store(i%2 == 0)
+		_, _ = cb.Get(ctx, "key")
+		require.Equal(t, CircuitClosed, cb.State())
+	}
+
+	repo.failing.Store(false)
+	_, err = cb.Get(ctx, "key")
+	require.NoError(t, err)
+	require.Equal(t, CircuitOpen, cb.State())
+}
+
+func Test_CircuitBreaker_FailureRate_SlidingWindow(t *testing.T) {
+	ctx := t.Context()
+
+	repo := &flakyRepo{}
+	cb, err := NewCircuitBreaker(repo,
+		CircuitBreakerWithConsecutiveFailures(0),
+		CircuitBreakerWithFailureRate(0.5, 4))
+	require.NoError(t, err)
+
+	// A single failure followed by successes slides out of the window
+	repo.failing.Store(true)
+	_, _ = cb.Get(ctx, "key")
+
+	repo.failing.Store(false)
+	for range 10 {
+		_, err := cb.Get(ctx, "key")
+		require.NoError(t, err)
+	}
+	require.Equal(t, CircuitClosed, cb.State())
+
+	repo.failing.Store(true)
+	_, _ = cb.Get(ctx, "key")
+	require.Equal(t, CircuitClosed, cb.State())
+	_, _ = cb.Get(ctx, "key")
+	require.Equal(t, CircuitOpen, cb.State())
+}
+
+func Test_CircuitBreaker_NotFound(t *testing.T) {
+	ctx := t.Context()
+
+	repo := &flakyRepo{}
+	cb, err := NewCircuitBreaker(repo, CircuitBreakerWithConsecutiveFailures(1))
+	require.NoError(t, err)
+
+	for range 10 {
+		_, err := cb.Get(ctx, "missing")
+		require.ErrorIs(t, err, ErrNotFound)
+	}
+	require.Equal(t, CircuitClosed, cb.State())
+}
+
+func Test_CircuitBreaker_FailureClassifier(t *testing.T) {
+	ctx := t.Context()
+
+	repo := &flakyRepo{}
+	cb, err := NewCircuitBreaker(repo,
+		CircuitBreakerWithConsecutiveFailures(1),
+		CircuitBreakerWithFailureClassifier(func(err error) bool {
+			return err != nil && !errors.Is(err, errFlaky)
+		}))
+	require.NoError(t, err)
+
+	repo.failing.Store(true)
+	for range 10 {
+		_, err := cb.Get(ctx, "key")
+		require.ErrorIs(t, err, errFlaky)
+	}
+	require.Equal(t, CircuitClosed, cb.State())
+}
+
+func Test_CircuitBreaker_HalfOpen(t *testing.T) {
+	ctx := t.Context()
+
+	var transitions []CircuitState
+	stateCallback := func(from, to CircuitState) {
+		transitions = append(transitions, to)
+	}
+
+	repo := &flakyRepo{}
+	cb, err := NewCircuitBreaker(repo,
+		CircuitBreakerWithConsecutiveFailures(1),
+		CircuitBreakerWithOpenTimeout(20*time.Millisecond),
+		CircuitBreakerWithHalfOpenRequests(2),
+		CircuitBreakerWithStateCallback(stateCallback))
+	require.NoError(t, err)
+
+	repo.failing.Store(true)
+	_, err = cb.Get(ctx, "key")
+	require.ErrorIs(t, err, errFlaky)
+	require.Equal(t, CircuitOpen, cb.State())
+
+	// A failed trial opens the circuit again
+	time.Sleep(30 * time.Millisecond)
+	require.Equal(t, CircuitHalfOpen, cb.State())
+	_, err = cb.Get(ctx, "key")
+	require.ErrorIs(t, err, errFlaky)
+	require.Equal(t, CircuitOpen, cb.State())
+
+	// Successful trials close the circuit
+	time.Sleep(30 * time.Millisecond)
+	repo.failing.Store(false)
+	for range 2 {
+		_, err := cb.Get(ctx, "key")
+		require.NoError(t, err)
+	}
+	require.Equal(t, CircuitClosed, cb.State())
+
+	require.Equal(t, []CircuitState{
+		CircuitOpen, CircuitHalfOpen, CircuitOpen, CircuitHalfOpen, CircuitClosed,
+	}, transitions)
+}
+
+func Test_CircuitBreaker_HalfOpen_LimitsTrials(t *testing.T) {
+	ctx := t.Context()
+
+	release := make(chan struct{})
+	var calls atomic.Int32
+	repo := repoFunc[string, string](func(ctx context.Context, key string) (string, error) {
+		if calls.Add(1) == 1 {
+			return "", errFlaky
+		}
+		<-release
+		return "value", nil
+	})
+
+	cb, err := NewCircuitBreaker(repo,
+		CircuitBreakerWithConsecutiveFailures(1),
+		CircuitBreakerWithOpenTimeout(time.Millisecond))
+	require.NoError(t, err)
+
+	_, err = cb.Get(ctx, "key")
+	require.ErrorIs(t, err, errFlaky)
+	time.Sleep(5 * time.Millisecond)
+
+	var wg sync.WaitGroup
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+
+		value, err := cb.Get(ctx, "key")
+		require.NoError(t, err)
+		require.Equal(t, "value", value)
+	}()
+
+	require.Eventually(t, func() bool {
+		return calls.Load() == 2
+	}, time.Second, time.Millisecond)
+
+	// The single trial is in flight
+	_, err = cb.Get(ctx, "key")
+	require.ErrorIs(t, err, ErrCircuitOpen)
+
+	close(release)
+	wg.Wait()
+
+	require.Equal(t, CircuitClosed, cb.State())
+}
+
+func Test_CircuitBreaker_HalfOpen_CanceledTrial(t *testing.T) {
+	ctx := t.Context()
+
+	repo := repoFunc[string, string](func(ctx context.Context, key string) (string, error) {
+		if err := ctx.Err(); err != nil {
+			return "", err
+		}
+		if key == "failing" {
+			return "", errFlaky
+		}
+		return "value", nil
+	})
+
+	cb, err := NewCircuitBreaker(repo,
+		CircuitBreakerWithConsecutiveFailures(1),
+		CircuitBreakerWithOpenTimeout(time.Millisecond))
+	require.NoError(t, err)
+
+	_, err = cb.Get(ctx, "failing")
+	require.ErrorIs(t, err, errFlaky)
+	time.Sleep(5 * time.Millisecond)
+
+	// A canceled trial doesn't close the circuit, and frees its slot
+	canceledCtx, cancel := context.WithCancel(ctx)
+	cancel()
+	_, err = cb.Get(canceledCtx, "key")
+	require.ErrorIs(t, err, context.Canceled)
+	require.Equal(t, CircuitHalfOpen, cb.State())
+
+	value, err := cb.Get(ctx, "key")
+	require.NoError(t, err)
+	require.Equal(t, "value", value)
+	require.Equal(t, CircuitClosed, cb.State())
+}
+
+func Test_CircuitBreaker_Canceled(t *testing.T) {
+	ctx := t.Context()
+
+	repo := repoFunc[string, string](func(ctx context.Context, key string) (string, error) {
+		if err := ctx.Err(); err != nil {
+			return "", err
+		}
+		return "", errFlaky
+	})
+
+	cb, err := NewCircuitBreaker(repo,
+		CircuitBreakerWithConsecutiveFailures(2),
+		CircuitBreakerWithFailureRate(0, 0))
+	require.NoError(t, err)
+
+	_, err = cb.Get(ctx, "key")
+	require.ErrorIs(t, err, errFlaky)
+
+	// A canceled request doesn't reset the consecutive failures
+	canceledCtx, cancel := context.WithCancel(ctx)
+	cancel()
+	_, err = cb.Get(canceledCtx, "key")
+	require.ErrorIs(t, err, context.Canceled)
+	require.Equal(t, CircuitClosed, cb.State())
+
+	_, err = cb.Get(ctx, "key")
+	require.ErrorIs(t, err, errFlaky)
+	require.Equal(t, CircuitOpen, cb.State())
+}
+
+func Test_CircuitBreaker_SWR(t *testing.T) {
+	ctx := t.Context()
+
+	repo := &flakyRepo{}
+	cb, err := NewCircuitBreaker(repo, CircuitBreakerWithConsecutiveFailures(1))
+	require.NoError(t, err)
+
+	swr, err := NewSWR(128, Repository[string, string](cb), time.Millisecond, 2*time.Millisecond)
+	require.NoError(t, err)
+
+	value, err := swr.Get(ctx, "key")
+	require.NoError(t, err)
+	require.Equal(t, "value", value)
+
+	repo.failing.Store(true)
+	_, err = swr.Get(ctx, "other")
+	require.ErrorIs(t, err, errFlaky)
+	require.Equal(t, CircuitOpen, cb.State())
+
+	// The value is dead, and the circuit is open
+	time.Sleep(5 * time.Millisecond)
+	calls := repo.calls.Load()
+
+	value, err = swr.Get(ctx, "key")
+	require.NoError(t, err)
+	require.Equal(t, "value", value)
+	require.Equal(t, calls, repo.calls.Load())
+}
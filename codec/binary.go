@@ -0,0 +1,49 @@
+package codec
+
+import (
+	"encoding"
+	"fmt"
+	"reflect"
+)
+
+type binaryCodec[V any] struct{}
+
+// Binary returns a codec for types implementing encoding.BinaryMarshaler
+// and encoding.BinaryUnmarshaler (usually on the pointer receiver).
+// When V is a pointer type, Decode allocates the pointed-to value.
+func Binary[V any]() Codec[V] {
+	return binaryCodec[V]{}
+}
+
+func (binaryCodec[V]) Encode(value V) ([]byte, error) {
+	if m, ok := any(value).(encoding.BinaryMarshaler); ok {
+		return m.MarshalBinary()
+	}
+	if m, ok := any(&value).(encoding.BinaryMarshaler); ok {
+		return m.MarshalBinary()
+	}
+	return nil, fmt.Errorf("%T does not implement encoding.BinaryMarshaler", value)
+}
+
+func (binaryCodec[V]) Decode(data []byte) (V, error) {
+	var value V
+
+	target := any(&value)
+	if t := reflect.TypeOf(value); t != nil && t.Kind() == reflect.Pointer {
+		ptr := reflect.New(t.Elem())
+		reflect.ValueOf(&value).Elem().Set(ptr)
+		target = ptr.Interface()
+	}
+
+	u, ok := target.(encoding.BinaryUnmarshaler)
+	if !ok {
+		var zero V
+		return zero, fmt.Errorf("%T does not implement encoding.BinaryUnmarshaler", target)
+	}
+
+	if err := u.UnmarshalBinary(data); err != nil {
+		var zero V
+		return zero, err
+	}
+	return value, nil
+}
@@ -0,0 +1,71 @@
+package codec
+
+import (
+	"encoding/binary"
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+type point struct {
+	X, Y uint32
+}
+
+func (p point) MarshalBinary() ([]byte, error) {
+	data := make([]byte, 8)
+	binary.BigEndian.PutUint32(data[0:4], p.X)
+	binary.BigEndian.PutUint32(data[4:8], p.Y)
+	return data, nil
+}
+
+func (p *point) UnmarshalBinary(data []byte) error {
+	if len(data) != 8 {
+		return errors.New("invalid length")
+	}
+	p.X = binary.BigEndian.Uint32(data[0:4])
+	p.Y = binary.BigEndian.Uint32(data[4:8])
+	return nil
+}
+
+func TestBinary_RoundTrip(t *testing.T) {
+	t.Run("Value", func(t *testing.T) {
+		c := Binary[point]()
+
+		value := point{X: 1, Y: 2}
+		data, err := c.Encode(value)
+		require.NoError(t, err)
+		require.Equal(t, []byte{0, 0, 0, 1, 0, 0, 0, 2}, data)
+
+		decoded, err := c.Decode(data)
+		require.NoError(t, err)
+		require.Equal(t, value, decoded)
+	})
+
+	t.Run("Pointer", func(t *testing.T) {
+		c := Binary[*point]()
+
+		value := &point{X: 1, Y: 2}
+		data, err := c.Encode(value)
+		require.NoError(t, err)
+
+		decoded, err := c.Decode(data)
+		require.NoError(t, err)
+		require.Equal(t, value, decoded)
+	})
+}
+
+func TestBinary_Errors(t *testing.T) {
+	t.Run("NotMarshaler", func(t *testing.T) {
+		_, err := Binary[int]().Encode(42)
+		require.Error(t, err)
+
+		_, err = Binary[int]().Decode([]byte{0})
+		require.Error(t, err)
+	})
+
+	t.Run("Unmarshal", func(t *testing.T) {
+		_, err := Binary[point]().Decode([]byte{0})
+		require.Error(t, err)
+	})
+}
@@ -0,0 +1,16 @@
+package codec
+
+type bytesCodec struct{}
+
+// Bytes returns a codec that stores byte slices as is, without any encoding.
+func Bytes() Codec[[]byte] {
+	return bytesCodec{}
+}
+
+func (bytesCodec) Encode(value []byte) ([]byte, error) {
+	return value, nil
+}
+
+func (bytesCodec) Decode(data []byte) ([]byte, error) {
+	return data, nil
+}
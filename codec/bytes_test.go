@@ -0,0 +1,20 @@
+package codec
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestBytes_RoundTrip(t *testing.T) {
+	c := Bytes()
+
+	value := []byte{0x00, 0xff, 'a', 'b'}
+	data, err := c.Encode(value)
+	require.NoError(t, err)
+	require.Equal(t, value, data)
+
+	decoded, err := c.Decode(data)
+	require.NoError(t, err)
+	require.Equal(t, value, decoded)
+}
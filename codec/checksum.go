@@ -0,0 +1,77 @@
+package codec
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"hash/crc32"
+)
+
+var (
+	ErrCorrupt = errors.New("corrupt value")
+)
+
+// checksumMagic prefixes checksummed values, see compressionMagic.
+var checksumMagic = []byte{0xC4, 0xC8, 0x63}
+
+// checksumHeaderSize is the magic, followed by the CRC-32C of the payload (4 bytes).
+const checksumHeaderSize = 7
+
+var castagnoli = crc32.MakeTable(crc32.Castagnoli)
+
+type checksummedCodec[V any] struct {
+	inner        Codec[V]
+	allowMissing bool
+}
+
+// Checksummed returns a codec that prefixes values encoded by the inner codec
+// with a CRC-32C checksum, so truncated or otherwise modified values fail to
+// decode with ErrCorrupt, instead of being decoded into the wrong value.
+// Values that aren't checksummed are corrupt as well, unless allowMissing is set,
+// in which case they're passed to the inner codec as is, so checksums can be
+// enabled while old values are still stored.
+func Checksummed[V any](inner Codec[V], allowMissing bool) Codec[V] {
+	return checksummedCodec[V]{
+		inner:        inner,
+		allowMissing: allowMissing,
+	}
+}
+
+func (c checksummedCodec[V]) Encode(value V) ([]byte, error) {
+	data, err := c.inner.Encode(value)
+	if err != nil {
+		return nil, err
+	}
+
+	encoded := make([]byte, 0, checksumHeaderSize+len(data))
+	encoded = append(encoded, checksumMagic...)
+	encoded = binary.BigEndian.AppendUint32(encoded, crc32.Checksum(data, castagnoli))
+	return append(encoded, data...), nil
+}
+
+func (c checksummedCodec[V]) Decode(data []byte) (V, error) {
+	if !bytes.HasPrefix(data, checksumMagic) {
+		if c.allowMissing {
+			return c.inner.Decode(data)
+		}
+
+		var zero V
+		return zero, fmt.Errorf("%w: missing checksum", ErrCorrupt)
+	}
+
+	if len(data) < checksumHeaderSize {
+		var zero V
+		return zero, fmt.Errorf("%w: truncated header", ErrCorrupt)
+	}
+
+	expected := binary.BigEndian.Uint32(data[len(checksumMagic):checksumHeaderSize])
+	data = data[checksumHeaderSize:]
+
+	if actual := crc32.Checksum(data, castagnoli); actual != expected {
+		var zero V
+		return zero, fmt.Errorf("%w: checksum: expected %08x: found %08x", ErrCorrupt, expected, actual)
+	}
+
+	return c.inner.Decode(data)
+}
@@ -0,0 +1,53 @@
+package codec
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestChecksummed_RoundTrip(t *testing.T) {
+	c := Checksummed(Default[string](), false)
+
+	data, err := c.Encode("value")
+	require.NoError(t, err)
+	require.Equal(t, checksumMagic, data[:len(checksumMagic)])
+
+	decoded, err := c.Decode(data)
+	require.NoError(t, err)
+	require.Equal(t, "value", decoded)
+}
+
+func TestChecksummed_Unchecksummed(t *testing.T) {
+	_, err := Checksummed(Default[string](), false).Decode([]byte("value"))
+	require.ErrorIs(t, err, ErrCorrupt)
+
+	decoded, err := Checksummed(Default[string](), true).Decode([]byte("value"))
+	require.NoError(t, err)
+	require.Equal(t, "value", decoded)
+}
+
+func TestChecksummed_Corrupt(t *testing.T) {
+	c := Checksummed(Default[string](), true)
+
+	data, err := c.Encode("value")
+	require.NoError(t, err)
+
+	t.Run("Truncated", func(t *testing.T) {
+		_, err := c.Decode(data[:len(data)-1])
+		require.ErrorIs(t, err, ErrCorrupt)
+	})
+
+	t.Run("TruncatedHeader", func(t *testing.T) {
+		_, err := c.Decode(data[:checksumHeaderSize-1])
+		require.ErrorIs(t, err, ErrCorrupt)
+	})
+
+	t.Run("Modified", func(t *testing.T) {
+		modified := append([]byte{}, data...)
+		modified[len(modified)-1] ^= 0xff
+
+		_, err := c.Decode(modified)
+		require.ErrorIs(t, err, ErrCorrupt)
+	})
+}
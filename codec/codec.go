@@ -0,0 +1,8 @@
+package codec
+
+// Codec converts values to and from their serialized representation.
+// Codecs are used by adapters that store values outside of the process memory.
+type Codec[V any] interface {
+	Encode(value V) ([]byte, error)
+	Decode(data []byte) (V, error)
+}
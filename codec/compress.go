@@ -0,0 +1,162 @@
+package codec
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"sync"
+
+	"github.com/klauspost/compress/gzip"
+	"github.com/klauspost/compress/snappy"
+	"github.com/klauspost/compress/zstd"
+)
+
+type Compression byte
+
+const (
+	CompressionNone   Compression = 0
+	CompressionGzip   Compression = 1
+	CompressionZstd   Compression = 2
+	CompressionSnappy Compression = 3
+)
+
+// compressionMagic prefixes compressed values, followed by a single byte
+// identifying the compression algorithm. The first two bytes form an invalid
+// UTF-8 sequence, so textual values never start with it. Other uncompressed
+// values that do are escaped, using CompressionNone as the algorithm.
+var compressionMagic = []byte{0xC4, 0xC8, 0x7A}
+
+const compressionHeaderSize = 4
+
+var (
+	zstdEncoder = sync.OnceValues(func() (*zstd.Encoder, error) {
+		return zstd.NewWriter(nil)
+	})
+	zstdDecoder = sync.OnceValues(func() (*zstd.Decoder, error) {
+		return zstd.NewReader(nil)
+	})
+)
+
+func compress(compression Compression, data []byte) ([]byte, error) {
+	header := append(bytes.Clone(compressionMagic), byte(compression))
+
+	switch compression {
+	case CompressionGzip:
+		buf := bytes.NewBuffer(header)
+		w := gzip.NewWriter(buf)
+		if _, err := w.Write(data); err != nil {
+			return nil, err
+		}
+		if err := w.Close(); err != nil {
+			return nil, err
+		}
+		return buf.Bytes(), nil
+	case CompressionZstd:
+		encoder, err := zstdEncoder()
+		if err != nil {
+			return nil, err
+		}
+		return encoder.EncodeAll(data, header), nil
+	case CompressionSnappy:
+		return append(header, snappy.Encode(nil, data)...), nil
+	default:
+		return nil, fmt.Errorf("unknown compression: %d", compression)
+	}
+}
+
+// escape prefixes uncompressed values that start with the magic with a
+// CompressionNone header, so they aren't mistaken for compressed ones.
+func escape(data []byte) []byte {
+	if !bytes.HasPrefix(data, compressionMagic) {
+		return data
+	}
+
+	escaped := make([]byte, 0, compressionHeaderSize+len(data))
+	escaped = append(escaped, compressionMagic...)
+	escaped = append(escaped, byte(CompressionNone))
+	return append(escaped, data...)
+}
+
+func decompress(compression Compression, data []byte) ([]byte, error) {
+	switch compression {
+	case CompressionNone:
+		return data, nil
+	case CompressionGzip:
+		r, err := gzip.NewReader(bytes.NewReader(data))
+		if err != nil {
+			return nil, err
+		}
+		defer r.Close()
+		return io.ReadAll(r)
+	case CompressionZstd:
+		decoder, err := zstdDecoder()
+		if err != nil {
+			return nil, err
+		}
+		return decoder.DecodeAll(data, nil)
+	case CompressionSnappy:
+		return snappy.Decode(nil, data)
+	default:
+		return nil, fmt.Errorf("unknown compression: %d", compression)
+	}
+}
+
+type compressedCodec[V any] struct {
+	inner       Codec[V]
+	compression Compression
+	threshold   int
+}
+
+// Compressed returns a codec that compresses values encoded by the inner codec
+// if their size is at least threshold bytes. Compressed values are prefixed
+// with a header identifying the algorithm, while smaller values are stored as is,
+// unless they happen to start with the header magic.
+// Decoding handles both, regardless of the configured compression, so that
+// the compression settings can be changed while old values are still stored.
+// Use CompressionNone to disable compression while keeping the ability to
+// decode previously compressed values.
+func Compressed[V any](inner Codec[V], compression Compression, threshold int) Codec[V] {
+	return compressedCodec[V]{
+		inner:       inner,
+		compression: compression,
+		threshold:   threshold,
+	}
+}
+
+func (c compressedCodec[V]) Encode(value V) ([]byte, error) {
+	data, err := c.inner.Encode(value)
+	if err != nil {
+		return nil, err
+	}
+
+	if c.compression == CompressionNone || len(data) < c.threshold {
+		return escape(data), nil
+	}
+
+	compressed, err := compress(c.compression, data)
+	if err != nil {
+		return nil, fmt.Errorf("compress: %w", err)
+	}
+
+	// Compression doesn't pay off, store the original value
+	if len(compressed) >= len(data) {
+		return escape(data), nil
+	}
+
+	return compressed, nil
+}
+
+func (c compressedCodec[V]) Decode(data []byte) (V, error) {
+	if len(data) < compressionHeaderSize || !bytes.HasPrefix(data, compressionMagic) {
+		return c.inner.Decode(data)
+	}
+
+	compression := Compression(data[len(compressionMagic)])
+	decompressed, err := decompress(compression, data[compressionHeaderSize:])
+	if err != nil {
+		var zero V
+		return zero, fmt.Errorf("decompress: %w", err)
+	}
+
+	return c.inner.Decode(decompressed)
+}
@@ -0,0 +1,121 @@
+package codec
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestCompressed_RoundTrip(t *testing.T) {
+	value := strings.Repeat("compressible ", 100)
+
+	for _, compression := range []Compression{CompressionGzip, CompressionZstd, CompressionSnappy} {
+		c := Compressed(Default[string](), compression, 64)
+
+		data, err := c.Encode(value)
+		require.NoError(t, err)
+		require.Less(t, len(data), len(value))
+		require.Equal(t, compressionMagic, data[:len(compressionMagic)])
+		require.Equal(t, byte(compression), data[len(compressionMagic)])
+
+		decoded, err := c.Decode(data)
+		require.NoError(t, err)
+		require.Equal(t, value, decoded)
+	}
+}
+
+func TestCompressed_BelowThreshold(t *testing.T) {
+	c := Compressed(Default[string](), CompressionZstd, 64)
+
+	data, err := c.Encode("short")
+	require.NoError(t, err)
+	require.Equal(t, []byte("short"), data)
+
+	decoded, err := c.Decode(data)
+	require.NoError(t, err)
+	require.Equal(t, "short", decoded)
+}
+
+func TestCompressed_Incompressible(t *testing.T) {
+	c := Compressed(Bytes(), CompressionGzip, 1)
+
+	value := []byte{0x01, 0x02, 0x03}
+	data, err := c.Encode(value)
+	require.NoError(t, err)
+	require.Equal(t, value, data)
+}
+
+func TestCompressed_MagicPrefix(t *testing.T) {
+	value := append(append([]byte{}, compressionMagic...), 0x01, 0x02, 0x03)
+
+	codecs := []Codec[[]byte]{
+		Compressed(Bytes(), CompressionNone, 0),
+		Compressed(Bytes(), CompressionZstd, 64),
+		Compressed(Bytes(), CompressionGzip, 1),
+	}
+
+	for _, c := range codecs {
+		data, err := c.Encode(value)
+		require.NoError(t, err)
+
+		for _, other := range codecs {
+			decoded, err := other.Decode(data)
+			require.NoError(t, err)
+			require.Equal(t, value, decoded)
+		}
+	}
+}
+
+func TestCompressed_SettingsChanged(t *testing.T) {
+	value := strings.Repeat("compressible ", 100)
+
+	zstdCodec := Compressed(Default[string](), CompressionZstd, 64)
+	snappyCodec := Compressed(Default[string](), CompressionSnappy, 64)
+	noneCodec := Compressed(Default[string](), CompressionNone, 0)
+
+	compressed, err := zstdCodec.Encode(value)
+	require.NoError(t, err)
+
+	uncompressed, err := noneCodec.Encode(value)
+	require.NoError(t, err)
+	require.Equal(t, []byte(value), uncompressed)
+
+	for _, c := range []Codec[string]{zstdCodec, snappyCodec, noneCodec} {
+		decoded, err := c.Decode(compressed)
+		require.NoError(t, err)
+		require.Equal(t, value, decoded)
+
+		decoded, err = c.Decode(uncompressed)
+		require.NoError(t, err)
+		require.Equal(t, value, decoded)
+	}
+}
+
+func TestCompressed_Errors(t *testing.T) {
+	t.Run("UnknownCompression", func(t *testing.T) {
+		c := Compressed(Default[string](), Compression(42), 0)
+
+		_, err := c.Encode("value")
+		require.Error(t, err)
+
+		_, err = c.Decode(append(compressionMagic, 42, 0x00))
+		require.Error(t, err)
+	})
+
+	t.Run("CorruptData", func(t *testing.T) {
+		for _, compression := range []Compression{CompressionGzip, CompressionZstd, CompressionSnappy} {
+			c := Compressed(Default[string](), compression, 0)
+
+			_, err := c.Decode(append(append([]byte{}, compressionMagic...), byte(compression), 0xff, 0xff))
+			require.Error(t, err)
+		}
+	})
+
+	t.Run("Inner", func(t *testing.T) {
+		c := Compressed(Default[chan int](), CompressionGzip, 0)
+
+		_, err := c.Encode(make(chan int))
+		require.Error(t, err)
+	})
+}
@@ -0,0 +1,113 @@
+package codec
+
+import (
+	"encoding/json"
+	"strconv"
+)
+
+type defaultCodec[V any] struct{}
+
+// Default returns a codec that stores strings, booleans and numbers in their
+// textual form (as produced by the strconv package), and serializes
+// everything else using encoding/json.
+func Default[V any]() Codec[V] {
+	return defaultCodec[V]{}
+}
+
+func (defaultCodec[V]) Encode(value V) ([]byte, error) {
+	switch v := any(value).(type) {
+	case string:
+		return []byte(v), nil
+	case bool:
+		return strconv.AppendBool(nil, v), nil
+	case int:
+		return strconv.AppendInt(nil, int64(v), 10), nil
+	case int8:
+		return strconv.AppendInt(nil, int64(v), 10), nil
+	case int16:
+		return strconv.AppendInt(nil, int64(v), 10), nil
+	case int32:
+		return strconv.AppendInt(nil, int64(v), 10), nil
+	case int64:
+		return strconv.AppendInt(nil, v, 10), nil
+	case uint:
+		return strconv.AppendUint(nil, uint64(v), 10), nil
+	case uint8:
+		return strconv.AppendUint(nil, uint64(v), 10), nil
+	case uint16:
+		return strconv.AppendUint(nil, uint64(v), 10), nil
+	case uint32:
+		return strconv.AppendUint(nil, uint64(v), 10), nil
+	case uint64:
+		return strconv.AppendUint(nil, v, 10), nil
+	case float32:
+		return strconv.AppendFloat(nil, float64(v), 'f', -1, 32), nil
+	case float64:
+		return strconv.AppendFloat(nil, v, 'f', -1, 64), nil
+	default:
+		return json.Marshal(value)
+	}
+}
+
+func (defaultCodec[V]) Decode(data []byte) (V, error) {
+	var value V
+
+	var err error
+	raw := string(data)
+
+	switch ptr := any(&value).(type) {
+	case *string:
+		*ptr = raw
+	case *bool:
+		*ptr, err = strconv.ParseBool(raw)
+	case *int:
+		*ptr, err = strconv.Atoi(raw)
+	case *int8:
+		var parsed int64
+		parsed, err = strconv.ParseInt(raw, 10, 8)
+		*ptr = int8(parsed)
+	case *int16:
+		var parsed int64
+		parsed, err = strconv.ParseInt(raw, 10, 16)
+		*ptr = int16(parsed)
+	case *int32:
+		var parsed int64
+		parsed, err = strconv.ParseInt(raw, 10, 32)
+		*ptr = int32(parsed)
+	case *int64:
+		*ptr, err = strconv.ParseInt(raw, 10, 64)
+	case *uint:
+		var parsed uint64
+		parsed, err = strconv.ParseUint(raw, 10, 0)
+		*ptr = uint(parsed)
+	case *uint8:
+		var parsed uint64
+		parsed, err = strconv.ParseUint(raw, 10, 8)
+		*ptr = uint8(parsed)
+	case *uint16:
+		var parsed uint64
+		parsed, err = strconv.ParseUint(raw, 10, 16)
+		*ptr = uint16(parsed)
+	case *uint32:
+		var parsed uint64
+		parsed, err = strconv.ParseUint(raw, 10, 32)
+		*ptr = uint32(parsed)
+	case *uint64:
+		*ptr, err = strconv.ParseUint(raw, 10, 64)
+	case *float32:
+		var parsed float64
+		parsed, err = strconv.ParseFloat(raw, 32)
+		*ptr = float32(parsed)
+	case *float64:
+		*ptr, err = strconv.ParseFloat(raw, 64)
+	default:
+		err = json.Unmarshal(data, &value)
+	}
+
+	if err != nil {
+		var zero V
+		return zero, err
+	}
+
+	return value, nil
+}
@@ -0,0 +1,134 @@
+package codec
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func testRoundTrip[V any](t *testing.T, c Codec[V], value V, encoded string) {
+	t.Helper()
+
+	data, err := c.Encode(value)
+	require.NoError(t, err)
+	require.Equal(t, encoded, string(data))
+
+	decoded, err := c.Decode(data)
+	require.NoError(t, err)
+	require.Equal(t, value, decoded)
+}
+
+func TestDefault_RoundTrip(t *testing.T) {
+	type TestStruct struct {
+		Name  string
+		Value int
+	}
+
+	t.Run("String", func(t *testing.T) {
+		testRoundTrip(t, Default[string](), "value1", "value1")
+	})
+
+	t.Run("Bool", func(t *testing.T) {
+		testRoundTrip(t, Default[bool](), true, "true")
+	})
+
+	t.Run("Int", func(t *testing.T) {
+		testRoundTrip(t, Default[int](), 42, "42")
+	})
+
+	t.Run("Int8", func(t *testing.T) {
+		testRoundTrip(t, Default[int8](), int8(127), "127")
+	})
+
+	t.Run("Int16", func(t *testing.T) {
+		testRoundTrip(t, Default[int16](), int16(32767), "32767")
+	})
+
+	t.Run("Int32", func(t *testing.T) {
+		testRoundTrip(t, Default[int32](), int32(2147483647), "2147483647")
+	})
+
+	t.Run("Int64", func(t *testing.T) {
+		testRoundTrip(t, Default[int64](), int64(-9223372036854775808), "-9223372036854775808")
+	})
+
+	t.Run("Uint", func(t *testing.T) {
+		testRoundTrip(t, Default[uint](), uint(42), "42")
+	})
+
+	t.Run("Uint8", func(t *testing.T) {
+		testRoundTrip(t, Default[uint8](), uint8(255), "255")
+	})
+
+	t.Run("Uint16", func(t *testing.T) {
+		testRoundTrip(t, Default[uint16](), uint16(65535), "65535")
+	})
+
+	t.Run("Uint32", func(t *testing.T) {
+		testRoundTrip(t, Default[uint32](), uint32(4294967295), "4294967295")
+	})
+
+	t.Run("Uint64", func(t *testing.T) {
+		testRoundTrip(t, Default[uint64](), uint64(18446744073709551615), "18446744073709551615")
+	})
+
+	t.Run("Float32", func(t *testing.T) {
+		testRoundTrip(t, Default[float32](), float32(3.5), "3.5")
+	})
+
+	t.Run("Float64", func(t *testing.T) {
+		testRoundTrip(t, Default[float64](), 3.14159, "3.14159")
+	})
+
+	t.Run("Struct", func(t *testing.T) {
+		testRoundTrip(t, Default[TestStruct](), TestStruct{Name: "test", Value: 123}, `{"Name":"test","Value":123}`)
+	})
+
+	t.Run("Array", func(t *testing.T) {
+		testRoundTrip(t, Default[[]int](), []int{1, 2, 3}, `[1,2,3]`)
+	})
+}
+
+func TestDefault_DecodeErrors(t *testing.T) {
+	t.Run("Bool", func(t *testing.T) {
+		_, err := Default[bool]().Decode([]byte("invalid"))
+		require.Error(t, err)
+	})
+
+	t.Run("Int", func(t *testing.T) {
+		_, err := Default[int]().Decode([]byte("not-a-number"))
+		require.Error(t, err)
+	})
+
+	t.Run("Int8", func(t *testing.T) {
+		value, err := Default[int8]().Decode([]byte("999"))
+		require.Error(t, err)
+		require.Zero(t, value)
+	})
+
+	t.Run("Uint", func(t *testing.T) {
+		_, err := Default[uint]().Decode([]byte("-42"))
+		require.Error(t, err)
+	})
+
+	t.Run("Uint16", func(t *testing.T) {
+		value, err := Default[uint16]().Decode([]byte("99999"))
+		require.Error(t, err)
+		require.Zero(t, value)
+	})
+
+	t.Run("Float64", func(t *testing.T) {
+		_, err := Default[float64]().Decode([]byte("not-a-float"))
+		require.Error(t, err)
+	})
+
+	t.Run("JSON", func(t *testing.T) {
+		_, err := Default[map[string]int]().Decode([]byte("invalid-json{"))
+		require.Error(t, err)
+	})
+}
+
+func TestDefault_EncodeErrors(t *testing.T) {
+	_, err := Default[chan int]().Encode(make(chan int))
+	require.Error(t, err)
+}
@@ -0,0 +1,31 @@
+package codec
+
+import (
+	"bytes"
+	"encoding/gob"
+)
+
+type gobCodec[V any] struct{}
+
+// Gob returns a codec that serializes values using encoding/gob.
+// Interface values must be registered with gob.Register beforehand.
+func Gob[V any]() Codec[V] {
+	return gobCodec[V]{}
+}
+
+func (gobCodec[V]) Encode(value V) ([]byte, error) {
+	var buf bytes.Buffer
+	if err := gob.NewEncoder(&buf).Encode(value); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+func (gobCodec[V]) Decode(data []byte) (V, error) {
+	var value V
+	if err := gob.NewDecoder(bytes.NewReader(data)).Decode(&value); err != nil {
+		var zero V
+		return zero, err
+	}
+	return value, nil
+}
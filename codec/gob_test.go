@@ -0,0 +1,47 @@
+package codec
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestGob_RoundTrip(t *testing.T) {
+	type TestStruct struct {
+		Name  string
+		Value int
+		Tags  []string
+	}
+
+	t.Run("Struct", func(t *testing.T) {
+		c := Gob[TestStruct]()
+
+		value := TestStruct{Name: "test", Value: 123, Tags: []string{"a", "b"}}
+		data, err := c.Encode(value)
+		require.NoError(t, err)
+
+		decoded, err := c.Decode(data)
+		require.NoError(t, err)
+		require.Equal(t, value, decoded)
+	})
+
+	t.Run("Pointer", func(t *testing.T) {
+		c := Gob[*TestStruct]()
+
+		value := &TestStruct{Name: "test", Value: 123}
+		data, err := c.Encode(value)
+		require.NoError(t, err)
+
+		decoded, err := c.Decode(data)
+		require.NoError(t, err)
+		require.Equal(t, value, decoded)
+	})
+}
+
+func TestGob_Errors(t *testing.T) {
+	_, err := Gob[int]().Decode([]byte("garbage"))
+	require.Error(t, err)
+
+	_, err = Gob[chan int]().Encode(make(chan int))
+	require.Error(t, err)
+}
@@ -0,0 +1,25 @@
+package codec
+
+import (
+	"encoding/json"
+)
+
+type jsonCodec[V any] struct{}
+
+// JSON returns a codec that serializes values using encoding/json.
+func JSON[V any]() Codec[V] {
+	return jsonCodec[V]{}
+}
+
+func (jsonCodec[V]) Encode(value V) ([]byte, error) {
+	return json.Marshal(value)
+}
+
+func (jsonCodec[V]) Decode(data []byte) (V, error) {
+	var value V
+	if err := json.Unmarshal(data, &value); err != nil {
+		var zero V
+		return zero, err
+	}
+	return value, nil
+}
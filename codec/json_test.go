@@ -0,0 +1,29 @@
+package codec
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestJSON_RoundTrip(t *testing.T) {
+	t.Run("String", func(t *testing.T) {
+		testRoundTrip(t, JSON[string](), "value1", `"value1"`)
+	})
+
+	t.Run("Int", func(t *testing.T) {
+		testRoundTrip(t, JSON[int](), 42, "42")
+	})
+
+	t.Run("Map", func(t *testing.T) {
+		testRoundTrip(t, JSON[map[string]int](), map[string]int{"a": 1}, `{"a":1}`)
+	})
+}
+
+func TestJSON_Errors(t *testing.T) {
+	_, err := JSON[int]().Decode([]byte(`"not-a-number"`))
+	require.Error(t, err)
+
+	_, err = JSON[chan int]().Encode(make(chan int))
+	require.Error(t, err)
+}
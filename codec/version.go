@@ -0,0 +1,84 @@
+package codec
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"time"
+)
+
+var (
+	ErrVersionMismatch = errors.New("schema version mismatch")
+)
+
+// versionMagic prefixes versioned values, see compressionMagic.
+var versionMagic = []byte{0xC4, 0xC8, 0x76}
+
+// versionHeaderSize is the magic, followed by the version (4 bytes)
+// and the write timestamp in unix milliseconds (8 bytes).
+const versionHeaderSize = 15
+
+// Envelope holds the metadata stored alongside versioned values.
+type Envelope struct {
+	Version   uint32
+	WrittenAt time.Time
+}
+
+// ReadEnvelope returns the envelope of the specified versioned value.
+// Returns false if the value isn't versioned (e.g. written before versioning was enabled).
+func ReadEnvelope(data []byte) (Envelope, bool) {
+	if len(data) < versionHeaderSize || !bytes.HasPrefix(data, versionMagic) {
+		return Envelope{}, false
+	}
+
+	header := data[len(versionMagic):versionHeaderSize]
+	return Envelope{
+		Version:   binary.BigEndian.Uint32(header[:4]),
+		WrittenAt: time.UnixMilli(int64(binary.BigEndian.Uint64(header[4:]))),
+	}, true
+}
+
+type versionedCodec[V any] struct {
+	inner   Codec[V]
+	version uint32
+}
+
+// Versioned returns a codec that prefixes values encoded by the inner codec
+// with an envelope holding the specified schema version and the write time.
+// Decoding values of any other version fails with ErrVersionMismatch, instead of
+// (possibly silently) decoding them using the current schema.
+// Values that aren't versioned are considered to be of version 0.
+func Versioned[V any](inner Codec[V], version uint32) Codec[V] {
+	return versionedCodec[V]{
+		inner:   inner,
+		version: version,
+	}
+}
+
+func (c versionedCodec[V]) Encode(value V) ([]byte, error) {
+	data, err := c.inner.Encode(value)
+	if err != nil {
+		return nil, err
+	}
+
+	encoded := make([]byte, 0, versionHeaderSize+len(data))
+	encoded = append(encoded, versionMagic...)
+	encoded = binary.BigEndian.AppendUint32(encoded, c.version)
+	encoded = binary.BigEndian.AppendUint64(encoded, uint64(time.Now().UnixMilli()))
+	return append(encoded, data...), nil
+}
+
+func (c versionedCodec[V]) Decode(data []byte) (V, error) {
+	envelope, ok := ReadEnvelope(data)
+	if ok {
+		data = data[versionHeaderSize:]
+	}
+
+	if envelope.Version != c.version {
+		var zero V
+		return zero, fmt.Errorf("%w: expected %d: found %d", ErrVersionMismatch, c.version, envelope.Version)
+	}
+
+	return c.inner.Decode(data)
+}
@@ -0,0 +1,68 @@
+package codec
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestVersioned_RoundTrip(t *testing.T) {
+	type user struct {
+		Name string
+	}
+
+	c := Versioned(JSON[user](), 3)
+
+	before := time.Now().Truncate(time.Millisecond)
+
+	data, err := c.Encode(user{Name: "name"})
+	require.NoError(t, err)
+
+	envelope, ok := ReadEnvelope(data)
+	require.True(t, ok)
+	require.EqualValues(t, 3, envelope.Version)
+	require.False(t, envelope.WrittenAt.Before(before))
+	require.False(t, envelope.WrittenAt.After(time.Now()))
+
+	decoded, err := c.Decode(data)
+	require.NoError(t, err)
+	require.Equal(t, user{Name: "name"}, decoded)
+}
+
+func TestVersioned_Mismatch(t *testing.T) {
+	data, err := Versioned(Default[string](), 1).Encode("value")
+	require.NoError(t, err)
+
+	_, err = Versioned(Default[string](), 2).Decode(data)
+	require.ErrorIs(t, err, ErrVersionMismatch)
+}
+
+func TestVersioned_Unversioned(t *testing.T) {
+	data, err := Default[string]().Encode("value")
+	require.NoError(t, err)
+
+	_, ok := ReadEnvelope(data)
+	require.False(t, ok)
+
+	decoded, err := Versioned(Default[string](), 0).Decode(data)
+	require.NoError(t, err)
+	require.Equal(t, "value", decoded)
+
+	_, err = Versioned(Default[string](), 1).Decode(data)
+	require.ErrorIs(t, err, ErrVersionMismatch)
+}
+
+func TestVersioned_Compressed(t *testing.T) {
+	value := "compressible compressible compressible compressible compressible"
+
+	c := Versioned(Compressed(Default[string](), CompressionSnappy, 0), 1)
+
+	data, err := c.Encode(value)
+	require.NoError(t, err)
+	require.Less(t, len(data), len(value))
+
+	decoded, err := c.Decode(data)
+	require.NoError(t, err)
+	require.Equal(t, value, decoded)
+}
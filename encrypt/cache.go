@@ -0,0 +1,206 @@
+package encrypt
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+
+	"github.com/dtrugman/cachehit"
+	"github.com/dtrugman/cachehit/codec"
+)
+
+var (
+	ErrDecryption = errors.New("decryption failed")
+)
+
+// encryptionMagic prefixes encrypted values, followed by the key ID length,
+// the key ID, the nonce and the sealed value.
+var encryptionMagic = []byte{0xC4, 0xC8, 0x65}
+
+type options struct {
+	codec   any
+	hmacKey []byte
+}
+
+func defaultOptions() *options {
+	return &options{}
+}
+
+func compileOptions(opts ...Option) *options {
+	o := defaultOptions()
+
+	for _, opt := range opts {
+		opt(o)
+	}
+
+	return o
+}
+
+type Option func(*options)
+
+// WithCodec configures the cache to serialize values using the specified codec
+// before encrypting them. The codec value type must match the cache value type.
+// Defaults to codec.Default.
+func WithCodec[V any](c codec.Codec[V]) Option {
+	return func(o *options) {
+		o.codec = c
+	}
+}
+
+// WithHashedKeys configures the cache to store values under the hex encoded
+// HMAC-SHA256 of their keys, so the keys aren't visible to readers of the
+// underlying cache either. The secret must be kept stable, or all values are lost.
+func WithHashedKeys(secret []byte) Option {
+	return func(o *options) {
+		o.hmacKey = secret
+	}
+}
+
+// Cache encrypts values using AES-GCM before storing them in the underlying cache.
+// Values are bound to their keys, so a value copied to a different key fails to decrypt.
+type Cache[K comparable, V any] struct {
+	underlying cachehit.Cache[string, []byte]
+	keyring    *Keyring
+	codec      codec.Codec[V]
+	hmacKey    []byte
+}
+
+// New creates a new encrypting cache over the specified cache, which must store
+// values as is, e.g. a Redis adapter configured using WithCodec(codec.Bytes()).
+func New[K comparable, V any](
+	underlying cachehit.Cache[string, []byte],
+	keyring *Keyring,
+	opts ...Option,
+) (*Cache[K, V], error) {
+	if underlying == nil {
+		return nil, fmt.Errorf("nil cache")
+	}
+
+	if keyring == nil {
+		return nil, fmt.Errorf("nil keyring")
+	}
+
+	o := compileOptions(opts...)
+
+	var valueCodec codec.Codec[V]
+	if o.codec == nil {
+		valueCodec = codec.Default[V]()
+	} else if c, ok := o.codec.(codec.Codec[V]); ok {
+		valueCodec = c
+	} else {
+		var v V
+		return nil, fmt.Errorf("options: codec: expected codec for %T: found %T", v, o.codec)
+	}
+
+	return &Cache[K, V]{
+		underlying: underlying,
+		keyring:    keyring,
+		codec:      valueCodec,
+		hmacKey:    o.hmacKey,
+	}, nil
+}
+
+func (c *Cache[K, V]) storageKey(key K) string {
+	keyStr := fmt.Sprintf("%v", key)
+	if c.hmacKey == nil {
+		return keyStr
+	}
+
+	mac := hmac.New(sha256.New, c.hmacKey)
+	mac.Write([]byte(keyStr))
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+func (c *Cache[K, V]) encrypt(storageKey string, plaintext []byte) []byte {
+	id := c.keyring.primary
+	aead := c.keyring.aeads[id]
+
+	header := make([]byte, 0, len(encryptionMagic)+1+len(id)+aead.NonceSize())
+	header = append(header, encryptionMagic...)
+	header = append(header, byte(len(id)))
+	header = append(header, id...)
+
+	nonce := make([]byte, aead.NonceSize())
+	_, _ = rand.Read(nonce) // Never fails
+	header = append(header, nonce...)
+
+	return aead.Seal(header, nonce, plaintext, []byte(storageKey))
+}
+
+func (c *Cache[K, V]) decrypt(storageKey string, data []byte) ([]byte, error) {
+	if !bytes.HasPrefix(data, encryptionMagic) || len(data) < len(encryptionMagic)+1 {
+		return nil, fmt.Errorf("%w: not encrypted", ErrDecryption)
+	}
+	data = data[len(encryptionMagic):]
+
+	idLen := int(data[0])
+	if len(data) < 1+idLen {
+		return nil, fmt.Errorf("%w: truncated key id", ErrDecryption)
+	}
+	id := string(data[1 : 1+idLen])
+	data = data[1+idLen:]
+
+	aead, ok := c.keyring.aeads[id]
+	if !ok {
+		return nil, fmt.Errorf("%w: unknown key id: %q", ErrDecryption, id)
+	}
+
+	if len(data) < aead.NonceSize() {
+		return nil, fmt.Errorf("%w: key id: %q: truncated nonce", ErrDecryption, id)
+	}
+	nonce, sealed := data[:aead.NonceSize()], data[aead.NonceSize():]
+
+	plaintext, err := aead.Open(nil, nonce, sealed, []byte(storageKey))
+	if err != nil {
+		return nil, fmt.Errorf("%w: key id: %q: %v", ErrDecryption, id, err)
+	}
+
+	return plaintext, nil
+}
+
+func (c *Cache[K, V]) Get(ctx context.Context, key K) (V, error) {
+	var zero V
+
+	storageKey := c.storageKey(key)
+
+	data, err := c.underlying.Get(ctx, storageKey)
+	if err != nil {
+		return zero, err
+	}
+
+	plaintext, err := c.decrypt(storageKey, data)
+	if err != nil {
+		return zero, err
+	}
+
+	value, err := c.codec.Decode(plaintext)
+	if err != nil {
+		return zero, fmt.Errorf("parse value: %w", err)
+	}
+
+	return value, nil
+}
+
+func (c *Cache[K, V]) Set(ctx context.Context, key K, value V) error {
+	plaintext, err := c.codec.Encode(value)
+	if err != nil {
+		return fmt.Errorf("marshal value: %w", err)
+	}
+
+	storageKey := c.storageKey(key)
+	return c.underlying.Set(ctx, storageKey, c.encrypt(storageKey, plaintext))
+}
+
+func (c *Cache[K, V]) Delete(ctx context.Context, key K) error {
+	deleter, ok := c.underlying.(cachehit.Deleter[string])
+	if !ok {
+		return fmt.Errorf("delete: not supported")
+	}
+
+	return deleter.Delete(ctx, c.storageKey(key))
+}
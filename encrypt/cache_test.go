@@ -0,0 +1,179 @@
+package encrypt
+
+import (
+	"bytes"
+	"testing"
+
+	lru "github.com/hashicorp/golang-lru/v2"
+	"github.com/stretchr/testify/require"
+
+	"github.com/dtrugman/cachehit"
+	lru_adapter "github.com/dtrugman/cachehit/adapter/hashicorp/golang-lru/v2"
+	"github.com/dtrugman/cachehit/codec"
+)
+
+type user struct {
+	Name  string
+	Email string
+}
+
+func newUnderlying(t *testing.T) *lru_adapter.LRU[string, []byte] {
+	cache, err := lru.New[string, []byte](16)
+	require.NoError(t, err)
+	return lru_adapter.From(cache)
+}
+
+func newKeyring(t *testing.T, primary Key, previous ...Key) *Keyring {
+	keyring, err := NewKeyring(primary, previous...)
+	require.NoError(t, err)
+	return keyring
+}
+
+func TestNew_Invalid(t *testing.T) {
+	keyring := newKeyring(t, testKey("k1"))
+
+	_, err := New[string, user](nil, keyring)
+	require.Error(t, err)
+
+	_, err = New[string, user](newUnderlying(t), nil)
+	require.Error(t, err)
+
+	_, err = New[string, user](newUnderlying(t), keyring, WithCodec(codec.Gob[string]()))
+	require.ErrorContains(t, err, "codec")
+}
+
+func TestCache_RoundTrip(t *testing.T) {
+	ctx := t.Context()
+
+	underlying := newUnderlying(t)
+
+	cache, err := New[string, user](underlying, newKeyring(t, testKey("k1")))
+	require.NoError(t, err)
+
+	value := user{Name: "name", Email: "name@example.com"}
+	require.NoError(t, cache.Set(ctx, "key", value))
+
+	actual, err := cache.Get(ctx, "key")
+	require.NoError(t, err)
+	require.Equal(t, value, actual)
+
+	stored, err := underlying.Get(ctx, "key")
+	require.NoError(t, err)
+	require.False(t, bytes.Contains(stored, []byte(value.Email)))
+
+	_, err = cache.Get(ctx, "missing")
+	require.ErrorIs(t, err, cachehit.ErrNotFound)
+
+	require.NoError(t, cache.Delete(ctx, "key"))
+
+	_, err = cache.Get(ctx, "key")
+	require.ErrorIs(t, err, cachehit.ErrNotFound)
+}
+
+func TestCache_KeyRotation(t *testing.T) {
+	ctx := t.Context()
+
+	underlying := newUnderlying(t)
+
+	before, err := New[string, string](underlying, newKeyring(t, testKey("k1")))
+	require.NoError(t, err)
+	require.NoError(t, before.Set(ctx, "old", "value1"))
+
+	after, err := New[string, string](underlying, newKeyring(t, testKey("k2"), testKey("k1")))
+	require.NoError(t, err)
+	require.NoError(t, after.Set(ctx, "new", "value2"))
+
+	value, err := after.Get(ctx, "old")
+	require.NoError(t, err)
+	require.Equal(t, "value1", value)
+
+	value, err = after.Get(ctx, "new")
+	require.NoError(t, err)
+	require.Equal(t, "value2", value)
+
+	// Values written using the new primary key can't be read without it
+	_, err = before.Get(ctx, "new")
+	require.ErrorIs(t, err, ErrDecryption)
+	require.ErrorContains(t, err, "unknown key id")
+}
+
+func TestCache_DecryptionErrors(t *testing.T) {
+	ctx := t.Context()
+
+	underlying := newUnderlying(t)
+
+	cache, err := New[string, string](underlying, newKeyring(t, testKey("k1")))
+	require.NoError(t, err)
+	require.NoError(t, cache.Set(ctx, "key", "value"))
+
+	stored, err := underlying.Get(ctx, "key")
+	require.NoError(t, err)
+
+	t.Run("NotEncrypted", func(t *testing.T) {
+		require.NoError(t, underlying.Set(ctx, "plain", []byte("value")))
+
+		_, err := cache.Get(ctx, "plain")
+		require.ErrorIs(t, err, ErrDecryption)
+	})
+
+	t.Run("Truncated", func(t *testing.T) {
+		for _, size := range []int{len(encryptionMagic) + 1, len(encryptionMagic) + 4, len(stored) - 1} {
+			require.NoError(t, underlying.Set(ctx, "truncated", stored[:size]))
+
+			_, err := cache.Get(ctx, "truncated")
+			require.ErrorIs(t, err, ErrDecryption)
+		}
+	})
+
+	t.Run("Tampered", func(t *testing.T) {
+		tampered := bytes.Clone(stored)
+		tampered[len(tampered)-1] ^= 0xff
+		require.NoError(t, underlying.Set(ctx, "key", tampered))
+
+		_, err := cache.Get(ctx, "key")
+		require.ErrorIs(t, err, ErrDecryption)
+	})
+
+	t.Run("WrongKey", func(t *testing.T) {
+		// Values are bound to their keys, and can't be copied around
+		require.NoError(t, underlying.Set(ctx, "other", stored))
+
+		_, err := cache.Get(ctx, "other")
+		require.ErrorIs(t, err, ErrDecryption)
+	})
+
+	t.Run("WrongSecret", func(t *testing.T) {
+		other, err := New[string, string](underlying, newKeyring(t, Key{ID: "k1", Secret: make([]byte, 32)}))
+		require.NoError(t, err)
+		require.NoError(t, underlying.Set(ctx, "key", stored))
+
+		_, err = other.Get(ctx, "key")
+		require.ErrorIs(t, err, ErrDecryption)
+	})
+}
+
+func TestCache_HashedKeys(t *testing.T) {
+	ctx := t.Context()
+
+	lruCache, err := lru.New[string, []byte](16)
+	require.NoError(t, err)
+	underlying := lru_adapter.From(lruCache)
+
+	cache, err := New[string, string](underlying, newKeyring(t, testKey("k1")),
+		WithHashedKeys([]byte("secret")))
+	require.NoError(t, err)
+
+	require.NoError(t, cache.Set(ctx, "username", "value"))
+
+	value, err := cache.Get(ctx, "username")
+	require.NoError(t, err)
+	require.Equal(t, "value", value)
+
+	_, err = underlying.Get(ctx, "username")
+	require.ErrorIs(t, err, cachehit.ErrNotFound)
+
+	keys := lruCache.Keys()
+	require.Len(t, keys, 1)
+	require.Len(t, keys[0], 64)
+	require.NotContains(t, keys[0], "username")
+}
@@ -0,0 +1,57 @@
+package encrypt
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"fmt"
+)
+
+// Key is an AES key, identified by an ID that is stored alongside every value
+// encrypted using it, so values can be decrypted after the primary key is rotated.
+type Key struct {
+	// ID identifies the key, up to 255 bytes. IDs must never be reused for different secrets.
+	ID string
+	// Secret is the AES key, 16, 24 or 32 bytes long (AES-128, AES-192 or AES-256).
+	Secret []byte
+}
+
+// Keyring holds the primary key, used to encrypt values, and any previous keys
+// that values may still be encrypted with.
+type Keyring struct {
+	primary string
+	aeads   map[string]cipher.AEAD
+}
+
+// NewKeyring creates a new keyring. To rotate keys, add a new primary key,
+// and keep the previous one until all values encrypted using it have expired.
+func NewKeyring(primary Key, previous ...Key) (*Keyring, error) {
+	keys := append([]Key{primary}, previous...)
+
+	aeads := make(map[string]cipher.AEAD, len(keys))
+	for _, key := range keys {
+		if len(key.ID) == 0 || len(key.ID) > 255 {
+			return nil, fmt.Errorf("key id: %q: length must be between 1 and 255", key.ID)
+		}
+
+		if _, exists := aeads[key.ID]; exists {
+			return nil, fmt.Errorf("key id: %q: duplicate", key.ID)
+		}
+
+		block, err := aes.NewCipher(key.Secret)
+		if err != nil {
+			return nil, fmt.Errorf("key id: %q: %w", key.ID, err)
+		}
+
+		aead, err := cipher.NewGCM(block)
+		if err != nil {
+			return nil, fmt.Errorf("key id: %q: %w", key.ID, err)
+		}
+
+		aeads[key.ID] = aead
+	}
+
+	return &Keyring{
+		primary: primary.ID,
+		aeads:   aeads,
+	}, nil
+}
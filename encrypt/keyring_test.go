@@ -0,0 +1,41 @@
+package encrypt
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func testKey(id string) Key {
+	return Key{ID: id, Secret: bytes.Repeat([]byte(id[:1]), 32)}
+}
+
+func TestNewKeyring(t *testing.T) {
+	keyring, err := NewKeyring(testKey("k2"), testKey("k1"))
+	require.NoError(t, err)
+	require.Equal(t, "k2", keyring.primary)
+	require.Len(t, keyring.aeads, 2)
+}
+
+func TestNewKeyring_Invalid(t *testing.T) {
+	t.Run("EmptyID", func(t *testing.T) {
+		_, err := NewKeyring(Key{Secret: make([]byte, 32)})
+		require.Error(t, err)
+	})
+
+	t.Run("LongID", func(t *testing.T) {
+		_, err := NewKeyring(Key{ID: string(make([]byte, 256)), Secret: make([]byte, 32)})
+		require.Error(t, err)
+	})
+
+	t.Run("DuplicateID", func(t *testing.T) {
+		_, err := NewKeyring(testKey("k1"), testKey("k1"))
+		require.ErrorContains(t, err, "duplicate")
+	})
+
+	t.Run("SecretSize", func(t *testing.T) {
+		_, err := NewKeyring(Key{ID: "k1", Secret: make([]byte, 10)})
+		require.Error(t, err)
+	})
+}
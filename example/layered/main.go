@@ -65,8 +65,11 @@ func run() error {
 	}
 
 	redisExpiration := 1 * time.Minute
-	redisCache := redis_adapter.From[string, resource.GithubUser](
+	redisCache, err := redis_adapter.From[string, resource.GithubUser](
 		redisDB, redis_adapter.WithExpiration(redisExpiration))
+	if err != nil {
+		return fmt.Errorf("redis adapter: %w", err)
+	}
 
 	httpRepo := resource.NewGithubUserRepository()
 
@@ -82,12 +85,20 @@ func run() error {
 		return fmt.Errorf("look through: %w", err)
 	}
 
+	// Broadcast invalidations to the in-memory layer of all instances
+	invalidationBus, err := redis_adapter.NewBus(ctx, redisDB, "invalidations")
+	if err != nil {
+		return fmt.Errorf("invalidation bus: %w", err)
+	}
+	defer invalidationBus.Close()
+
 	cacheSize := 128
 	timeToStale := 10 * time.Second
 	timeToDead := 30 * time.Second
 	swr, err := cachehit.NewSWR(
 		cacheSize, lookthrough, timeToStale, timeToDead,
 		cachehit.SWRWithErrorCallback(errorCallback),
+		cachehit.SWRWithInvalidationBus(invalidationBus, cachehit.InvalidationDrop),
 	)
 	if err != nil {
 		return fmt.Errorf("new swr: %w", err)
@@ -155,6 +166,11 @@ func run() error {
 			del := redisDB.Del(ctx, username)
 			if err = del.Err(); err != nil {
 				fmt.Printf("Error: Failed to remove user from Redis: %v\n", err)
+				continue
+			}
+
+			if err = swr.Invalidate(ctx, username); err != nil {
+				fmt.Printf("Error: Failed to invalidate user in memory: %v\n", err)
 			} else {
 				fmt.Println("Done")
 			}
@@ -0,0 +1,61 @@
+package resource
+
+import (
+	"context"
+	"fmt"
+	"net"
+
+	"github.com/bradfitz/gomemcache/memcache"
+	"github.com/testcontainers/testcontainers-go"
+	"github.com/testcontainers/testcontainers-go/wait"
+)
+
+type MemcachedInstance struct {
+	Addr    string
+	Cleanup func() error
+}
+
+func MemcachedRun(ctx context.Context) (*MemcachedInstance, error) {
+	memcachedVersion := "memcached:1.6"
+	memcachedContainer, err := testcontainers.Run(ctx,
+		memcachedVersion,
+		testcontainers.WithExposedPorts("11211/tcp"),
+		testcontainers.WithWaitStrategy(wait.ForListeningPort("11211/tcp")),
+	)
+	if err != nil {
+		return nil, fmt.Errorf("run container: %w", err)
+	}
+
+	cleanup := func() error {
+		return testcontainers.TerminateContainer(memcachedContainer)
+	}
+
+	host, err := memcachedContainer.Host(ctx)
+	if err != nil {
+		cleanup()
+		return nil, fmt.Errorf("get host: %w", err)
+	}
+
+	port, err := memcachedContainer.MappedPort(ctx, "11211/tcp")
+	if err != nil {
+		cleanup()
+		return nil, fmt.Errorf("get port: %w", err)
+	}
+
+	harness := &MemcachedInstance{
+		Addr:    net.JoinHostPort(host, port.Port()),
+		Cleanup: cleanup,
+	}
+	return harness, nil
+}
+
+func MemcachedConn(addr string) (*memcache.Client, error) {
+	client := memcache.New(addr)
+
+	if err := client.Ping(); err != nil {
+		client.Close()
+		return nil, fmt.Errorf("ping: %w", err)
+	}
+
+	return client, nil
+}
@@ -3,10 +3,16 @@ package resource
 import (
 	"context"
 	"fmt"
+	"net"
+	"strconv"
+	"strings"
 
+	"github.com/docker/docker/api/types/container"
+	"github.com/docker/go-connections/nat"
 	"github.com/redis/go-redis/v9"
 	"github.com/testcontainers/testcontainers-go"
 	redis_container "github.com/testcontainers/testcontainers-go/modules/redis"
+	"github.com/testcontainers/testcontainers-go/wait"
 )
 
 type RedisInstance struct {
@@ -54,3 +60,99 @@ func RedisConn(ctx context.Context, dsn string) (*redis.Client, error) {
 
 	return client, nil
 }
+
+type RedisClusterInstance struct {
+	Addrs   []string
+	Cleanup func() error
+}
+
+// redisClusterPort returns a free host port that also leaves room for the
+// matching cluster bus port (port + 10000).
+func redisClusterPort() (int, error) {
+	for {
+		listener, err := net.Listen("tcp", "127.0.0.1:0")
+		if err != nil {
+			return 0, err
+		}
+		port := listener.Addr().(*net.TCPAddr).Port
+		listener.Close()
+
+		if port+10000 <= 65535 {
+			return port, nil
+		}
+	}
+}
+
+// RedisClusterRun launches a Redis Cluster with the specified number of master nodes.
+// All nodes run inside a single container, listening on the same ports as
+// the host, so that the addresses announced by the cluster are reachable.
+func RedisClusterRun(ctx context.Context, nodes int) (*RedisClusterInstance, error) {
+	redisVersion := "redis:7"
+
+	addrs := make([]string, 0, nodes)
+	bindings := nat.PortMap{}
+	exposed := make([]string, 0, nodes)
+
+	var script strings.Builder
+	for range nodes {
+		port, err := redisClusterPort()
+		if err != nil {
+			return nil, fmt.Errorf("free port: %w", err)
+		}
+
+		addr := fmt.Sprintf("127.0.0.1:%d", port)
+		addrs = append(addrs, addr)
+
+		containerPort := nat.Port(fmt.Sprintf("%d/tcp", port))
+		bindings[containerPort] = []nat.PortBinding{{HostIP: "127.0.0.1", HostPort: strconv.Itoa(port)}}
+		exposed = append(exposed, string(containerPort))
+
+		fmt.Fprintf(&script,
+			"redis-server --port %d --cluster-enabled yes --cluster-config-file nodes-%d.conf "+
+				"--cluster-announce-ip 127.0.0.1 --save '' --appendonly no --daemonize yes\n",
+			port, port)
+	}
+	fmt.Fprintf(&script,
+		"redis-cli --cluster create %s --cluster-replicas 0 --cluster-yes\n",
+		strings.Join(addrs, " "))
+	script.WriteString("tail -f /dev/null\n")
+
+	redisContainer, err := testcontainers.Run(ctx,
+		redisVersion,
+		testcontainers.WithEntrypoint("sh", "-c", script.String()),
+		testcontainers.WithExposedPorts(exposed...),
+		testcontainers.WithHostConfigModifier(func(hostConfig *container.HostConfig) {
+			hostConfig.PortBindings = bindings
+		}),
+		testcontainers.WithWaitStrategy(wait.ForLog("All 16384 slots covered")),
+	)
+	if err != nil {
+		return nil, fmt.Errorf("run container: %w", err)
+	}
+
+	cleanup := func() error {
+		return testcontainers.TerminateContainer(redisContainer)
+	}
+
+	harness := &RedisClusterInstance{
+		Addrs:   addrs,
+		Cleanup: cleanup,
+	}
+	return harness, nil
+}
+
+func RedisClusterConn(ctx context.Context, addrs []string) (*redis.ClusterClient, error) {
+	client := redis.NewClusterClient(&redis.ClusterOptions{
+		Addrs: addrs,
+	})
+
+	err := client.ForEachShard(ctx, func(ctx context.Context, shard *redis.Client) error {
+		return shard.Ping(ctx).Err()
+	})
+	if err != nil {
+		client.Close()
+		return nil, fmt.Errorf("ping: %w", err)
+	}
+
+	return client, nil
+}
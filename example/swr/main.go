@@ -69,7 +69,10 @@ func run() error {
 		return fmt.Errorf("connect to redis: %w", err)
 	}
 
-	redisAdapter := redis_adapter.From[string, string](redisDB)
+	redisAdapter, err := redis_adapter.From[string, string](redisDB)
+	if err != nil {
+		return fmt.Errorf("redis adapter: %w", err)
+	}
 
 	cacheSize := 128
 	timeToStale := 10 * time.Second
@@ -0,0 +1,160 @@
+package cachehit
+
+import (
+	"sync"
+	"sync/atomic"
+)
+
+// keyGuard keeps values fetched before a key was invalidated from being stored
+// in the cache after the invalidation. Every key being fetched is tracked with
+// a generation, which is bumped by every invalidation of the key.
+type keyGuard[K comparable] struct {
+	mu   sync.Mutex
+	keys map[K]*guardedKey
+
+	// batchMu serializes stores of multiple keys, so they can't deadlock each other
+	batchMu sync.Mutex
+}
+
+type guardedKey struct {
+	// mu is held while storing a value, so invalidations wait for the store to complete
+	mu         sync.Mutex
+	generation atomic.Uint64
+	fetches    int
+}
+
+// guardedFetch is a fetch of a key in progress, see keyGuard.begin.
+type guardedFetch[K comparable] struct {
+	key        K
+	guarded    *guardedKey
+	generation uint64
+}
+
+func newKeyGuard[K comparable]() *keyGuard[K] {
+	return &keyGuard[K]{
+		keys: make(map[K]*guardedKey),
+	}
+}
+
+// begin tracks a fetch of the specified key. end must be called once the fetch completes.
+func (g *keyGuard[K]) begin(key K) *guardedFetch[K] {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	guarded, exists := g.keys[key]
+	if !exists {
+		guarded = &guardedKey{}
+		g.keys[key] = guarded
+	}
+	guarded.fetches++
+
+	return &guardedFetch[K]{
+		key:        key,
+		guarded:    guarded,
+		generation: guarded.generation.Load(),
+	}
+}
+
+func (g *keyGuard[K]) end(fetch *guardedFetch[K]) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	fetch.guarded.fetches--
+	if fetch.guarded.fetches == 0 {
+		delete(g.keys, fetch.key)
+	}
+}
+
+// store calls set, unless the key was invalidated since the fetch began.
+// Returns whether set was called.
+func (g *keyGuard[K]) store(fetch *guardedFetch[K], set func()) bool {
+	fetch.guarded.mu.Lock()
+	defer fetch.guarded.mu.Unlock()
+
+	if fetch.guarded.generation.Load() != fetch.generation {
+		return false
+	}
+
+	set()
+	return true
+}
+
+// storeMany calls set with the keys that weren't invalidated since their fetches began.
+func (g *keyGuard[K]) storeMany(fetches []*guardedFetch[K], set func(keys []K)) {
+	g.batchMu.Lock()
+	defer g.batchMu.Unlock()
+
+	keys := make([]K, 0, len(fetches))
+	for _, fetch := range fetches {
+		fetch.guarded.mu.Lock()
+		defer fetch.guarded.mu.Unlock()
+
+		if fetch.guarded.generation.Load() == fetch.generation {
+			keys = append(keys, fetch.key)
+		}
+	}
+
+	if len(keys) > 0 {
+		set(keys)
+	}
+}
+
+// invalidate keeps fetches of the key in progress from storing their values,
+// waiting for a store in progress to complete.
+func (g *keyGuard[K]) invalidate(key K) {
+	g.mu.Lock()
+	guarded, exists := g.keys[key]
+	g.mu.Unlock()
+
+	if !exists {
+		return
+	}
+
+	guarded.mu.Lock()
+	defer guarded.mu.Unlock()
+
+	guarded.generation.Add(1)
+}
+
+// keyLock serializes operations on the same key, while operations on different keys run concurrently.
+type keyLock[K comparable] struct {
+	mu   sync.Mutex
+	keys map[K]*lockedKey
+}
+
+type lockedKey struct {
+	mu    sync.Mutex
+	users int
+}
+
+func newKeyLock[K comparable]() *keyLock[K] {
+	return &keyLock[K]{
+		keys: make(map[K]*lockedKey),
+	}
+}
+
+// lock locks the specified key, and returns a function that unlocks it.
+func (l *keyLock[K]) lock(key K) func() {
+	l.mu.Lock()
+	locked, exists := l.keys[key]
+	if !exists {
+		locked = &lockedKey{}
+		l.keys[key] = locked
+	}
+	locked.users++
+	l.mu.Unlock()
+
+	locked.mu.Lock()
+
+	return func() {
+		locked.mu.Unlock()
+
+		l.mu.Lock()
+		defer l.mu.Unlock()
+
+		locked.users--
+		if locked.users == 0 {
+			delete(l.keys, key)
+		}
+	}
+}
@@ -0,0 +1,83 @@
+package cachehit
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func Test_KeyGuard_Store(t *testing.T) {
+	guard := newKeyGuard[string]()
+
+	fetch := guard.begin("key")
+	require.True(t, guard.store(fetch, func() {}))
+	guard.end(fetch)
+
+	fetch = guard.begin("key")
+	guard.invalidate("key")
+	require.False(t, guard.store(fetch, func() {
+		t.Fatal("stored after invalidation")
+	}))
+	guard.end(fetch)
+
+	require.Empty(t, guard.keys)
+}
+
+func Test_KeyGuard_StoreMany(t *testing.T) {
+	guard := newKeyGuard[string]()
+
+	fetches := []*guardedFetch[string]{
+		guard.begin("key1"),
+		guard.begin("key2"),
+		guard.begin("key3"),
+	}
+
+	guard.invalidate("key2")
+
+	var stored []string
+	guard.storeMany(fetches, func(keys []string) {
+		stored = keys
+	})
+	require.Equal(t, []string{"key1", "key3"}, stored)
+
+	for _, fetch := range fetches {
+		guard.end(fetch)
+	}
+	require.Empty(t, guard.keys)
+}
+
+func Test_KeyGuard_InvalidateWithoutFetch(t *testing.T) {
+	guard := newKeyGuard[string]()
+
+	guard.invalidate("key")
+
+	fetch := guard.begin("key")
+	require.True(t, guard.store(fetch, func() {}))
+	guard.end(fetch)
+}
+
+func Test_KeyLock_Lock(t *testing.T) {
+	lock := newKeyLock[string]()
+
+	unlock := lock.lock("key1")
+
+	// Other keys aren't blocked
+	lock.lock("key2")()
+
+	locked := make(chan struct{})
+	go func() {
+		defer close(locked)
+		lock.lock("key1")()
+	}()
+
+	select {
+	case <-locked:
+		t.Fatal("locked twice")
+	case <-time.After(20 * time.Millisecond):
+	}
+
+	unlock()
+	<-locked
+	require.Empty(t, lock.keys)
+}
@@ -0,0 +1,165 @@
+package cachehit
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"sync"
+	"time"
+)
+
+// hedgedBurst is the number of second requests that can be issued in a row,
+// after a period in which none were issued.
+const hedgedBurst = 10
+
+type hedgedResult[V any] struct {
+	value V
+	err   error
+}
+
+// Hedged is a repository that issues a second (hedged) request for keys that
+// weren't fetched within a configurable or learned delay, and returns the result
+// of the request that finishes first, canceling the other one. Useful for
+// repositories with a long latency tail.
+type Hedged[K comparable, V any] struct {
+	repo Repository[K, V]
+
+	percentile float64
+	maxRate    float64
+
+	mu        sync.Mutex
+	delay     time.Duration
+	budget    float64
+	latencies []time.Duration
+	sampled   int
+	next      int
+}
+
+func NewHedged[K comparable, V any](repo Repository[K, V], opts ...HedgedOption) (*Hedged[K, V], error) {
+	if repo == nil {
+		return nil, fmt.Errorf("nil repo")
+	}
+
+	o := hedgedCompileOptions(opts...)
+	if err := o.Validate(); err != nil {
+		return nil, fmt.Errorf("options: %w", err)
+	}
+
+	h := &Hedged[K, V]{
+		repo:       repo,
+		percentile: o.percentile,
+		maxRate:    o.maxRate,
+		delay:      o.delay,
+	}
+
+	if o.percentile > 0 {
+		h.latencies = make([]time.Duration, o.window)
+	}
+
+	return h, nil
+}
+
+// Delay returns the current delay of second requests.
+func (h *Hedged[K, V]) Delay() time.Duration {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	return h.delay
+}
+
+// start returns the delay of second requests, and accounts for a new request
+// in the hedging budget.
+func (h *Hedged[K, V]) start() time.Duration {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	h.budget = min(h.budget+h.maxRate, hedgedBurst)
+	return h.delay
+}
+
+// allow reports whether the hedging budget allows issuing a second request.
+func (h *Hedged[K, V]) allow() bool {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	if h.budget < 1 {
+		return false
+	}
+
+	h.budget--
+	return true
+}
+
+// observe records the latency of the first request of a fetch. If the second request
+// finished first, the first one was canceled, and its latency is at least the observed one.
+func (h *Hedged[K, V]) observe(latency time.Duration) {
+	if h.latencies == nil {
+		return
+	}
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	window := len(h.latencies)
+
+	h.latencies[h.next] = latency
+	h.next = (h.next + 1) % window
+	h.sampled++
+
+	if h.sampled%max(window/10, 1) != 0 {
+		return
+	}
+
+	sorted := slices.Clone(h.latencies[:min(h.sampled, window)])
+	slices.Sort(sorted)
+	h.delay = sorted[int(h.percentile*float64(len(sorted)))]
+}
+
+func (h *Hedged[K, V]) fetch(ctx context.Context, key K, results chan<- hedgedResult[V]) {
+	value, err := h.repo.Get(ctx, key)
+	results <- hedgedResult[V]{value: value, err: err}
+}
+
+// Get fetches the specified key from the repository, issuing a second request if
+// the first one didn't finish within the delay, and the hedging budget allows it.
+// The first successful (or not found) result is returned. If a request fails,
+// the result of the other request, if any, is returned instead.
+func (h *Hedged[K, V]) Get(ctx context.Context, key K) (V, error) {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel() // Cancels the request that didn't finish first
+
+	start := time.Now()
+
+	results := make(chan hedgedResult[V], 2)
+	go h.fetch(ctx, key, results)
+	inflight := 1
+
+	timer := time.NewTimer(h.start())
+	defer timer.Stop()
+
+	hedge := timer.C
+	for {
+		select {
+		case result := <-results:
+			inflight--
+			if result.err == nil || errors.Is(result.err, ErrNotFound) || inflight == 0 {
+				// Observed from the start of the first request, as measuring a second
+				// request from its own start would bias the delay downwards
+				h.observe(time.Since(start))
+				return result.value, result.err
+			}
+
+		case <-hedge:
+			hedge = nil
+			if h.allow() {
+				go h.fetch(ctx, key, results)
+				inflight++
+			}
+
+		case <-ctx.Done():
+			var zero V
+			return zero, ctx.Err()
+		}
+	}
+}
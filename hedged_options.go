@@ -0,0 +1,89 @@
+package cachehit
+
+import (
+	"fmt"
+	"time"
+)
+
+const (
+	HedgedDefaultDelay   = 100 * time.Millisecond
+	HedgedDefaultMaxRate = 0.1
+)
+
+type hedgedOptions struct {
+	delay time.Duration
+
+	percentile float64
+	window     int
+
+	maxRate float64
+}
+
+func (o *hedgedOptions) Validate() error {
+	if o.delay <= time.Duration(0) {
+		return fmt.Errorf("delay must be positive")
+	}
+
+	if o.percentile < 0 || o.percentile >= 1 {
+		return fmt.Errorf("percentile must be in [0, 1)")
+	}
+
+	if o.percentile > 0 && o.window <= 0 {
+		return fmt.Errorf("window must be positive")
+	}
+
+	if o.maxRate < 0 || o.maxRate > 1 {
+		return fmt.Errorf("max rate must be in [0, 1]")
+	}
+
+	return nil
+}
+
+func hedgedDefaultOptions() *hedgedOptions {
+	return &hedgedOptions{
+		delay:   HedgedDefaultDelay,
+		maxRate: HedgedDefaultMaxRate,
+	}
+}
+
+func hedgedCompileOptions(opts ...HedgedOption) *hedgedOptions {
+	o := hedgedDefaultOptions()
+
+	for _, opt := range opts {
+		opt(o)
+	}
+
+	return o
+}
+
+type HedgedOption func(*hedgedOptions)
+
+// HedgedWithDelay configures the hedged repository to issue a second request
+// for keys that weren't fetched within the specified amount of time.
+// When used with HedgedWithPercentile, this is the delay used until enough
+// latencies were collected.
+func HedgedWithDelay(delay time.Duration) HedgedOption {
+	return func(o *hedgedOptions) {
+		o.delay = delay
+	}
+}
+
+// HedgedWithPercentile configures the hedged repository to learn the delay of
+// second requests, as the specified percentile (e.g. 0.95) of the latencies of
+// the last N fetches, measured from the start of their first request.
+// The delay is recomputed every N/10 fetches.
+func HedgedWithPercentile(percentile float64, window int) HedgedOption {
+	return func(o *hedgedOptions) {
+		o.percentile = percentile
+		o.window = window
+	}
+}
+
+// HedgedWithMaxRate configures the hedged repository to issue second requests
+// for at most the specified fraction of requests (e.g. 0.1 for 10%), so it never
+// doubles the load on a repository that slowed down. Zero disables hedging.
+func HedgedWithMaxRate(rate float64) HedgedOption {
+	return func(o *hedgedOptions) {
+		o.maxRate = rate
+	}
+}
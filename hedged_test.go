@@ -0,0 +1,277 @@
+package cachehit
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+type repoFunc[K comparable, V any] func(ctx context.Context, key K) (V, error)
+
+func (f repoFunc[K, V]) Get(ctx context.Context, key K) (V, error) {
+	return f(ctx, key)
+}
+
+// slowRepo returns a repository whose n-th call takes latency(n) to return value-n
+func slowRepo(calls *atomic.Int32, latency func(n int32) time.Duration) Repository[string, string] {
+	return repoFunc[string, string](func(ctx context.Context, key string) (string, error) {
+		n := calls.Add(1)
+
+		select {
+		case <-time.After(latency(n)):
+			return fmt.Sprintf("value%d", n), nil
+		case <-ctx.Done():
+			return "", ctx.Err()
+		}
+	})
+}
+
+func Test_Hedged_New_WithInvalidOptions(t *testing.T) {
+	repo := &mockRepo[string, string]{}
+
+	_, err := NewHedged[string, string](nil)
+	require.ErrorContains(t, err, "repo")
+
+	_, err = NewHedged(repo, HedgedWithDelay(0))
+	require.ErrorContains(t, err, "delay")
+
+	_, err = NewHedged(repo, HedgedWithPercentile(1, 100))
+	require.ErrorContains(t, err, "percentile")
+
+	_, err = NewHedged(repo, HedgedWithPercentile(0.95, 0))
+	require.ErrorContains(t, err, "window")
+
+	_, err = NewHedged(repo, HedgedWithMaxRate(1.5))
+	require.ErrorContains(t, err, "max rate")
+}
+
+func Test_Hedged_Get_Fast(t *testing.T) {
+	ctx := t.Context()
+
+	var calls atomic.Int32
+	repo := slowRepo(&calls, func(int32) time.Duration { return 0 })
+
+	hedged, err := NewHedged(repo, HedgedWithDelay(time.Second), HedgedWithMaxRate(1))
+	require.NoError(t, err)
+
+	value, err := hedged.Get(ctx, "key")
+	require.NoError(t, err)
+	require.Equal(t, "value1", value)
+	require.Equal(t, int32(1), calls.Load())
+}
+
+func Test_Hedged_Get_Slow(t *testing.T) {
+	ctx := t.Context()
+
+	canceled := make(chan struct{})
+
+	var calls atomic.Int32
+	repo := repoFunc[string, string](func(ctx context.Context, key string) (string, error) {
+		if calls.Add(1) == 1 {
+			<-ctx.Done()
+			close(canceled)
+			return "", ctx.Err()
+		}
+		return "hedged", nil
+	})
+
+	hedged, err := NewHedged(repo, HedgedWithDelay(10*time.Millisecond), HedgedWithMaxRate(1))
+	require.NoError(t, err)
+
+	value, err := hedged.Get(ctx, "key")
+	require.NoError(t, err)
+	require.Equal(t, "hedged", value)
+
+	select {
+	case <-canceled:
+	case <-time.After(time.Second):
+		require.Fail(t, "first request not canceled")
+	}
+}
+
+func Test_Hedged_Get_NoBudget(t *testing.T) {
+	ctx := t.Context()
+
+	var calls atomic.Int32
+	repo := slowRepo(&calls, func(int32) time.Duration { return 50 * time.Millisecond })
+
+	hedged, err := NewHedged(repo, HedgedWithDelay(time.Millisecond), HedgedWithMaxRate(0))
+	require.NoError(t, err)
+
+	value, err := hedged.Get(ctx, "key")
+	require.NoError(t, err)
+	require.Equal(t, "value1", value)
+	require.Equal(t, int32(1), calls.Load())
+}
+
+func Test_Hedged_Get_MaxRate(t *testing.T) {
+	ctx := t.Context()
+
+	var calls atomic.Int32
+	repo := slowRepo(&calls, func(int32) time.Duration { return 20 * time.Millisecond })
+
+	hedged, err := NewHedged(repo, HedgedWithDelay(time.Millisecond), HedgedWithMaxRate(0.5))
+	require.NoError(t, err)
+
+	for range 10 {
+		_, err := hedged.Get(ctx, "key")
+		require.NoError(t, err)
+	}
+
+	// Every other request is hedged
+	require.Eventually(t, func() bool {
+		return calls.Load() == 15
+	}, time.Second, time.Millisecond)
+}
+
+func Test_Hedged_Get_Error(t *testing.T) {
+	ctx := t.Context()
+
+	repoErr := errors.New("failure")
+
+	var calls atomic.Int32
+	repo := repoFunc[string, string](func(ctx context.Context, key string) (string, error) {
+		calls.Add(1)
+		return "", repoErr
+	})
+
+	hedged, err := NewHedged(repo, HedgedWithDelay(time.Second), HedgedWithMaxRate(1))
+	require.NoError(t, err)
+
+	_, err = hedged.Get(ctx, "key")
+	require.ErrorIs(t, err, repoErr)
+	require.Equal(t, int32(1), calls.Load())
+}
+
+func Test_Hedged_Get_ErrorAfterHedge(t *testing.T) {
+	ctx := t.Context()
+
+	var calls atomic.Int32
+	repo := repoFunc[string, string](func(ctx context.Context, key string) (string, error) {
+		if calls.Add(1) == 1 {
+			time.Sleep(20 * time.Millisecond)
+			return "", errors.New("failure")
+		}
+		time.Sleep(40 * time.Millisecond)
+		return "hedged", nil
+	})
+
+	hedged, err := NewHedged(repo, HedgedWithDelay(time.Millisecond), HedgedWithMaxRate(1))
+	require.NoError(t, err)
+
+	value, err := hedged.Get(ctx, "key")
+	require.NoError(t, err)
+	require.Equal(t, "hedged", value)
+}
+
+func Test_Hedged_Get_NotFound(t *testing.T) {
+	ctx := t.Context()
+
+	var calls atomic.Int32
+	repo := repoFunc[string, string](func(ctx context.Context, key string) (string, error) {
+		if calls.Add(1) == 1 {
+			time.Sleep(20 * time.Millisecond)
+			return "", ErrNotFound
+		}
+		<-ctx.Done()
+		return "", ctx.Err()
+	})
+
+	hedged, err := NewHedged(repo, HedgedWithDelay(time.Millisecond), HedgedWithMaxRate(1))
+	require.NoError(t, err)
+
+	_, err = hedged.Get(ctx, "key")
+	require.ErrorIs(t, err, ErrNotFound)
+}
+
+func Test_Hedged_Get_ContextCanceled(t *testing.T) {
+	var calls atomic.Int32
+	repo := slowRepo(&calls, func(int32) time.Duration { return time.Second })
+
+	hedged, err := NewHedged(repo)
+	require.NoError(t, err)
+
+	ctx, cancel := context.WithTimeout(t.Context(), 20*time.Millisecond)
+	defer cancel()
+
+	_, err = hedged.Get(ctx, "key")
+	require.ErrorIs(t, err, context.DeadlineExceeded)
+}
+
+func Test_Hedged_Percentile(t *testing.T) {
+	ctx := t.Context()
+
+	var calls atomic.Int32
+	repo := slowRepo(&calls, func(int32) time.Duration { return 5 * time.Millisecond })
+
+	hedged, err := NewHedged(repo,
+		HedgedWithDelay(time.Second),
+		HedgedWithPercentile(0.5, 10),
+		HedgedWithMaxRate(1))
+	require.NoError(t, err)
+	require.Equal(t, time.Second, hedged.Delay())
+
+	for range 10 {
+		_, err := hedged.Get(ctx, "key")
+		require.NoError(t, err)
+	}
+
+	require.GreaterOrEqual(t, hedged.Delay(), 5*time.Millisecond)
+	require.Less(t, hedged.Delay(), time.Second)
+}
+
+func Test_Hedged_Percentile_Bimodal(t *testing.T) {
+	ctx := t.Context()
+
+	// The first request of slow keys is slow, while any other request is fast,
+	// so second requests of slow keys always finish first
+	var mu sync.Mutex
+	fetched := make(map[string]bool)
+	repo := repoFunc[string, string](func(ctx context.Context, key string) (string, error) {
+		mu.Lock()
+		first := !fetched[key]
+		fetched[key] = true
+		mu.Unlock()
+
+		latency := time.Millisecond
+		if first && strings.HasPrefix(key, "slow") {
+			latency = 50 * time.Millisecond
+		}
+
+		select {
+		case <-time.After(latency):
+			return key, nil
+		case <-ctx.Done():
+			return "", ctx.Err()
+		}
+	})
+
+	delay := 10 * time.Millisecond
+	hedged, err := NewHedged(Repository[string, string](repo),
+		HedgedWithDelay(delay),
+		HedgedWithPercentile(0.9, 10),
+		HedgedWithMaxRate(1))
+	require.NoError(t, err)
+
+	for i := range 20 {
+		key := fmt.Sprintf("fast%d", i)
+		if i%2 == 1 {
+			key = fmt.Sprintf("slow%d", i)
+		}
+
+		value, err := hedged.Get(ctx, key)
+		require.NoError(t, err)
+		require.Equal(t, key, value)
+	}
+
+	// Half of the first requests take at least the delay, so the learned delay
+	// can't drop below it, even though they're canceled by faster second requests
+	require.GreaterOrEqual(t, hedged.Delay(), delay)
+}
@@ -2,8 +2,26 @@ package internal
 
 import (
 	"errors"
+	"time"
 )
 
 var (
 	ErrNotFound = errors.New("not found")
+
+	ErrLeaseFailed = errors.New("lease holder failed")
+
+	ErrClosed = errors.New("closed")
 )
+
+type NotFoundClassifier func(err error) bool
+
+type Result[V any] struct {
+	Value V
+	Err   error
+}
+
+type Entry[V any] struct {
+	Value   V
+	StaleAt time.Time
+	DeadAt  time.Time
+}
@@ -0,0 +1,104 @@
+package cachehit
+
+import (
+	"context"
+	"crypto/rand"
+	"fmt"
+	"strings"
+	"sync"
+
+	"github.com/dtrugman/cachehit/codec"
+)
+
+// InvalidationBus broadcasts invalidation messages between cache instances,
+// usually across processes. Every published message is delivered to all
+// subscribers, including the ones in the publishing instance.
+// Messages are opaque to the bus, caches encode the invalidated keys in them.
+type InvalidationBus interface {
+	Publish(ctx context.Context, message string) error
+	Subscribe(handler func(message string))
+}
+
+type InvalidationMode int
+
+const (
+	// InvalidationDrop removes invalidated keys from the cache.
+	InvalidationDrop InvalidationMode = iota
+
+	// InvalidationMarkStale keeps invalidated keys in the cache, but marks them
+	// as stale, so they are served while being refreshed in the background (SWR only).
+	InvalidationMarkStale
+)
+
+// invalidations encodes invalidated keys into bus messages, tagged with the
+// publishing instance, so that instances can skip their own messages.
+type invalidations[K comparable] struct {
+	origin string
+	codec  codec.Codec[K]
+}
+
+func newInvalidations[K comparable]() *invalidations[K] {
+	return &invalidations[K]{
+		origin: rand.Text(),
+		codec:  codec.Default[K](),
+	}
+}
+
+func (i *invalidations[K]) encode(key K) (string, error) {
+	data, err := i.codec.Encode(key)
+	if err != nil {
+		return "", err
+	}
+	return i.origin + ":" + string(data), nil
+}
+
+// decode returns the key encoded in the message, and whether it was
+// published by another instance.
+func (i *invalidations[K]) decode(message string) (K, bool, error) {
+	var key K
+
+	origin, data, found := strings.Cut(message, ":")
+	if !found {
+		return key, false, fmt.Errorf("malformed message")
+	}
+
+	if origin == i.origin {
+		return key, false, nil
+	}
+
+	key, err := i.codec.Decode([]byte(data))
+	if err != nil {
+		return key, false, err
+	}
+	return key, true, nil
+}
+
+// LocalInvalidationBus is an in-process InvalidationBus.
+// Handlers are called synchronously by Publish.
+type LocalInvalidationBus struct {
+	mu       sync.RWMutex
+	handlers []func(message string)
+}
+
+func NewLocalInvalidationBus() *LocalInvalidationBus {
+	return &LocalInvalidationBus{}
+}
+
+func (b *LocalInvalidationBus) Publish(_ context.Context, message string) error {
+	b.mu.RLock()
+	handlers := b.handlers
+	b.mu.RUnlock()
+
+	for _, handler := range handlers {
+		handler(message)
+	}
+
+	return nil
+}
+
+func (b *LocalInvalidationBus) Subscribe(handler func(message string)) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.handlers = append(b.handlers, handler)
+}
@@ -0,0 +1,55 @@
+package cachehit
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func Test_LocalInvalidationBus(t *testing.T) {
+	ctx := t.Context()
+
+	bus := NewLocalInvalidationBus()
+
+	require.NoError(t, bus.Publish(ctx, "nobody"))
+
+	var first, second []string
+	bus.Subscribe(func(key string) {
+		first = append(first, key)
+	})
+	bus.Subscribe(func(key string) {
+		second = append(second, key)
+	})
+
+	require.NoError(t, bus.Publish(ctx, "key1"))
+	require.NoError(t, bus.Publish(ctx, "key2"))
+
+	require.Equal(t, []string{"key1", "key2"}, first)
+	require.Equal(t, []string{"key1", "key2"}, second)
+}
+
+func Test_Invalidations(t *testing.T) {
+	local := newInvalidations[int]()
+	remote := newInvalidations[int]()
+
+	message, err := remote.encode(42)
+	require.NoError(t, err)
+
+	key, fromRemote, err := local.decode(message)
+	require.NoError(t, err)
+	require.True(t, fromRemote)
+	require.Equal(t, 42, key)
+
+	message, err = local.encode(42)
+	require.NoError(t, err)
+
+	_, fromRemote, err = local.decode(message)
+	require.NoError(t, err)
+	require.False(t, fromRemote)
+
+	_, _, err = local.decode("malformed")
+	require.Error(t, err)
+
+	_, _, err = local.decode(remote.origin + ":not a number")
+	require.Error(t, err)
+}
@@ -0,0 +1,21 @@
+package cachehit
+
+import (
+	"context"
+)
+
+// Lease coordinates fetching the same key across processes, so that only the
+// lease holder fetches it from the repository, while others wait for the value
+// to appear in the cache. Leases must expire, so a crashed holder doesn't block others.
+// Keys are formatted using fmt's %v verb, like the keys of the Redis adapter.
+type Lease interface {
+	// Acquire tries to take the lease for the specified key, without blocking.
+	// If acquired, release must be called once the fetch completes, with the fetch
+	// error, or nil if the value was stored in the cache.
+	//
+	// Holders that release the lease with an error (other than a context error)
+	// publish their outcome for a short while, so others don't repeat the fetch.
+	// Meanwhile, Acquire returns an error wrapping ErrNotFound if the key wasn't
+	// found, or ErrLeaseFailed if the fetch failed.
+	Acquire(ctx context.Context, key string) (release func(err error), acquired bool, err error)
+}
@@ -4,6 +4,8 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"sync"
+	"time"
 
 	"golang.org/x/sync/singleflight"
 )
@@ -14,6 +16,17 @@ type LookThrough[K comparable, V any] struct {
 
 	dedup *singleflight.Group
 
+	invalidationBus InvalidationBus
+	invalidations   *invalidations[K]
+
+	guard *keyGuard[K]
+
+	lease             Lease
+	leasePollInterval time.Duration
+	leaseMaxWait      time.Duration
+
+	isNotFound NotFoundClassifier
+
 	errorCallback ErrorCallback
 }
 
@@ -29,12 +42,31 @@ func NewLookThrough[K comparable, V any](
 		return nil, fmt.Errorf("options: %w", err)
 	}
 
-	return &LookThrough[K, V]{
-		cache:         cache,
-		repo:          repo,
-		dedup:         dedup,
-		errorCallback: o.errorCallback,
-	}, nil
+	if o.invalidationBus != nil {
+		if _, ok := cache.(Deleter[K]); !ok {
+			return nil, fmt.Errorf("options: invalidation bus: cache doesn't support delete")
+		}
+	}
+
+	lt := &LookThrough[K, V]{
+		cache:             cache,
+		repo:              repo,
+		dedup:             dedup,
+		invalidationBus:   o.invalidationBus,
+		invalidations:     newInvalidations[K](),
+		guard:             newKeyGuard[K](),
+		lease:             o.lease,
+		leasePollInterval: o.leasePollInterval,
+		leaseMaxWait:      o.leaseMaxWait,
+		isNotFound:        o.isNotFound,
+		errorCallback:     o.errorCallback,
+	}
+
+	if lt.invalidationBus != nil {
+		lt.invalidationBus.Subscribe(lt.onInvalidation)
+	}
+
+	return lt, nil
 }
 
 func (c *LookThrough[K, V]) reportError(err error) {
@@ -43,17 +75,143 @@ func (c *LookThrough[K, V]) reportError(err error) {
 	}
 }
 
-func (c *LookThrough[K, V]) get(ctx context.Context, key K) (V, error) {
-	k := fmt.Sprintf("%v", key)
-	res, err, _ := c.dedup.Do(k, func() (interface{}, error) {
-		value, err := c.repo.Get(ctx, key)
+func (c *LookThrough[K, V]) notFound(err error) bool {
+	return isNotFound(err, c.isNotFound)
+}
+
+func (c *LookThrough[K, V]) invalidate(ctx context.Context, key K) {
+	// Make sure fetches that started before the invalidation don't store their values,
+	// and that later calls don't join them
+	c.guard.invalidate(key)
+	c.dedup.Forget(fmt.Sprintf("%v", key))
+
+	deleter, ok := c.cache.(Deleter[K])
+	if !ok {
+		c.reportError(fmt.Errorf("cache delete: %v: not supported", key))
+		return
+	}
+
+	if err := deleter.Delete(ctx, key); err != nil {
+		c.reportError(fmt.Errorf("cache delete: %v: %w", key, err))
+	}
+}
+
+func (c *LookThrough[K, V]) onInvalidation(message string) {
+	key, remote, err := c.invalidations.decode(message)
+	if err != nil {
+		c.reportError(fmt.Errorf("invalidation: %q: %w", message, err))
+		return
+	}
+
+	// Keys invalidated by this instance were already invalidated by Invalidate
+	if remote {
+		c.invalidate(context.Background(), key)
+	}
+}
+
+// Invalidate drops the specified key from the cache, and if an invalidation bus
+// is configured, broadcasts the invalidation to all other instances sharing it.
+func (c *LookThrough[K, V]) Invalidate(ctx context.Context, key K) error {
+	c.invalidate(ctx, key)
+
+	if c.invalidationBus != nil {
+		message, err := c.invalidations.encode(key)
 		if err != nil {
-			return nil, fmt.Errorf("repo get: %w", err)
+			return fmt.Errorf("encode: %v: %w", key, err)
+		}
+
+		if err := c.invalidationBus.Publish(ctx, message); err != nil {
+			return fmt.Errorf("publish: %w", err)
 		}
+	}
 
+	return nil
+}
+
+func (c *LookThrough[K, V]) fetch(ctx context.Context, key K) (V, error) {
+	fetch := c.guard.begin(key)
+	defer c.guard.end(fetch)
+
+	value, err := c.repo.Get(ctx, key)
+	if err != nil {
+		var v V
+		return v, fmt.Errorf("repo get: %w", asNotFound(err, c.isNotFound))
+	}
+
+	// A value fetched before the key was invalidated is returned, but not cached
+	c.guard.store(fetch, func() {
 		if err := c.cache.Set(ctx, key, value); err != nil {
 			c.reportError(fmt.Errorf("cache set: %v: %w", key, err))
 		}
+	})
+	return value, nil
+}
+
+func (c *LookThrough[K, V]) fetchWithLease(ctx context.Context, key K) (V, error) {
+	deadline := time.Now().Add(c.leaseMaxWait)
+
+	for attempt := 0; ; attempt++ {
+		release, acquired, err := c.lease.Acquire(ctx, fmt.Sprintf("%v", key))
+		if c.notFound(err) || errors.Is(err, ErrLeaseFailed) {
+			// A recent holder didn't store a value, share its outcome instead of fetching again
+			var v V
+			return v, fmt.Errorf("lease: %w", asNotFound(err, c.isNotFound))
+		} else if err != nil {
+			c.reportError(fmt.Errorf("lease acquire: %v: %w", key, err))
+			return c.fetch(ctx, key)
+		}
+
+		if acquired {
+			// The previous holder might have stored the value just before releasing
+			if attempt > 0 {
+				if value, err := c.cache.Get(ctx, key); err == nil {
+					release(nil)
+					return value, nil
+				}
+			}
+
+			value, err := c.fetch(ctx, key)
+			release(err)
+			return value, err
+		}
+
+		if !time.Now().Before(deadline) {
+			// Waited long enough for the holder, fetch it ourselves
+			return c.fetch(ctx, key)
+		}
+
+		timer := time.NewTimer(c.leasePollInterval)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			var v V
+			return v, fmt.Errorf("lease wait: %w", ctx.Err())
+		case <-timer.C:
+		}
+
+		value, err := c.cache.Get(ctx, key)
+		if err == nil {
+			return value, nil
+		} else if !c.notFound(err) {
+			c.reportError(fmt.Errorf("cache get: %v: %w", key, err))
+		}
+	}
+}
+
+func (c *LookThrough[K, V]) get(ctx context.Context, key K) (V, error) {
+	k := fmt.Sprintf("%v", key)
+	res, err, _ := c.dedup.Do(k, func() (interface{}, error) {
+		var value V
+		var err error
+		if c.lease != nil {
+			value, err = c.fetchWithLease(ctx, key)
+		} else {
+			value, err = c.fetch(ctx, key)
+		}
+
+		if err != nil {
+			return nil, err
+		}
 		return value, nil
 	})
 
@@ -73,7 +231,7 @@ func (c *LookThrough[K, V]) get(ctx context.Context, key K) (V, error) {
 
 func (c *LookThrough[K, V]) Get(ctx context.Context, key K) (V, error) {
 	value, err := c.cache.Get(ctx, key)
-	if errors.Is(err, ErrNotFound) {
+	if c.notFound(err) {
 		return c.get(ctx, key)
 	} else if err != nil {
 		c.reportError(fmt.Errorf("cache get: %v: %w", key, err))
@@ -82,3 +240,150 @@ func (c *LookThrough[K, V]) Get(ctx context.Context, key K) (V, error) {
 
 	return value, nil
 }
+
+func (c *LookThrough[K, V]) getManyFromCache(ctx context.Context, keys []K) (map[K]Result[V], []K) {
+	results := make(map[K]Result[V], len(keys))
+	misses := make([]K, 0, len(keys))
+
+	batch, ok := c.cache.(BatchCache[K, V])
+	if !ok {
+		for _, key := range keys {
+			value, err := c.cache.Get(ctx, key)
+			if err == nil {
+				results[key] = Result[V]{Value: value}
+				continue
+			} else if !c.notFound(err) {
+				c.reportError(fmt.Errorf("cache get: %v: %w", key, err))
+			}
+			misses = append(misses, key)
+		}
+		return results, misses
+	}
+
+	cached, err := batch.GetMany(ctx, keys)
+	if err != nil {
+		c.reportError(fmt.Errorf("cache get many: %w", err))
+		return results, keys
+	}
+
+	for _, key := range keys {
+		res, found := cached[key]
+		if found && res.Err == nil {
+			results[key] = res
+			continue
+		} else if found && !c.notFound(res.Err) {
+			c.reportError(fmt.Errorf("cache get: %v: %w", key, res.Err))
+		}
+		misses = append(misses, key)
+	}
+	return results, misses
+}
+
+func (c *LookThrough[K, V]) getManyFromRepo(ctx context.Context, keys []K) (map[K]Result[V], error) {
+	batch, ok := c.repo.(BatchRepository[K, V])
+	if !ok {
+		var mu sync.Mutex
+		var wg sync.WaitGroup
+
+		results := make(map[K]Result[V], len(keys))
+		for _, key := range keys {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+
+				value, err := c.get(ctx, key)
+
+				mu.Lock()
+				results[key] = Result[V]{Value: value, Err: err}
+				mu.Unlock()
+			}()
+		}
+		wg.Wait()
+
+		return results, nil
+	}
+
+	fetches := make([]*guardedFetch[K], 0, len(keys))
+	for _, key := range keys {
+		fetch := c.guard.begin(key)
+		defer c.guard.end(fetch)
+
+		fetches = append(fetches, fetch)
+	}
+
+	fetched, err := batch.GetMany(ctx, keys)
+	if err != nil {
+		return nil, fmt.Errorf("repo get many: %w", err)
+	}
+
+	results := make(map[K]Result[V], len(keys))
+	found := make([]*guardedFetch[K], 0, len(keys))
+	for i, key := range keys {
+		res, exists := fetched[key]
+		if !exists {
+			results[key] = Result[V]{Err: ErrNotFound}
+		} else if res.Err != nil {
+			results[key] = Result[V]{Err: fmt.Errorf("repo get: %w", asNotFound(res.Err, c.isNotFound))}
+		} else {
+			results[key] = res
+			found = append(found, fetches[i])
+		}
+	}
+
+	if len(found) == 0 {
+		return results, nil
+	}
+
+	// Values fetched before their keys were invalidated are returned, but not cached
+	c.guard.storeMany(found, func(keys []K) {
+		values := make(map[K]V, len(keys))
+		for _, key := range keys {
+			values[key] = results[key].Value
+		}
+
+		if cache, ok := c.cache.(BatchCache[K, V]); ok {
+			if err := cache.SetMany(ctx, values); err != nil {
+				c.reportError(fmt.Errorf("cache set many: %w", err))
+			}
+		} else {
+			for key, value := range values {
+				if err := c.cache.Set(ctx, key, value); err != nil {
+					c.reportError(fmt.Errorf("cache set: %v: %w", key, err))
+				}
+			}
+		}
+	})
+
+	return results, nil
+}
+
+// GetMany fetches multiple keys, returning a result for every unique key.
+// If the cache implements BatchCache, all keys are looked up in a single call.
+// Misses are fetched using a single call if the repository implements
+// BatchRepository, or using concurrent deduplicated fetches otherwise.
+func (c *LookThrough[K, V]) GetMany(ctx context.Context, keys []K) (map[K]Result[V], error) {
+	unique := make([]K, 0, len(keys))
+	seen := make(map[K]struct{}, len(keys))
+	for _, key := range keys {
+		if _, exists := seen[key]; !exists {
+			seen[key] = struct{}{}
+			unique = append(unique, key)
+		}
+	}
+
+	results, misses := c.getManyFromCache(ctx, unique)
+	if len(misses) == 0 {
+		return results, nil
+	}
+
+	fetched, err := c.getManyFromRepo(ctx, misses)
+	if err != nil {
+		return nil, err
+	}
+
+	for key, res := range fetched {
+		results[key] = res
+	}
+
+	return results, nil
+}
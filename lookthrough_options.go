@@ -1,15 +1,44 @@
 package cachehit
 
+import (
+	"fmt"
+	"time"
+)
+
+const (
+	LookThroughDefaultLeasePollInterval = 50 * time.Millisecond
+	LookThroughDefaultLeaseMaxWait      = 5 * time.Second
+)
+
 type lookThroughOptions struct {
+	invalidationBus InvalidationBus
+
+	lease             Lease
+	leasePollInterval time.Duration
+	leaseMaxWait      time.Duration
+
+	isNotFound NotFoundClassifier
+
 	errorCallback ErrorCallback
 }
 
 func (o *lookThroughOptions) Validate() error {
+	if o.leasePollInterval <= time.Duration(0) {
+		return fmt.Errorf("lease poll interval must be positive")
+	}
+
+	if o.leaseMaxWait <= time.Duration(0) {
+		return fmt.Errorf("lease max wait must be positive")
+	}
+
 	return nil
 }
 
 func lookThroughDefaultOptions() *lookThroughOptions {
-	return &lookThroughOptions{}
+	return &lookThroughOptions{
+		leasePollInterval: LookThroughDefaultLeasePollInterval,
+		leaseMaxWait:      LookThroughDefaultLeaseMaxWait,
+	}
 }
 
 func lookThroughCompileOptions(opts ...LookThroughOption) *lookThroughOptions {
@@ -31,3 +60,49 @@ func LookThroughWithErrorCallback(errorCallback ErrorCallback) LookThroughOption
 		o.errorCallback = errorCallback
 	}
 }
+
+// LookThroughWithInvalidationBus configures the look through cache to subscribe
+// to the specified bus, and drop the keys invalidated by any other instance.
+// The cache must implement Deleter. Keys are published encoded using the default
+// codec of the key type.
+func LookThroughWithInvalidationBus(bus InvalidationBus) LookThroughOption {
+	return func(o *lookThroughOptions) {
+		o.invalidationBus = bus
+	}
+}
+
+// LookThroughWithLease configures the look through cache to deduplicate fetches
+// across processes. Only the lease holder fetches a missing key from the repository,
+// while others poll the cache for the value.
+func LookThroughWithLease(lease Lease) LookThroughOption {
+	return func(o *lookThroughOptions) {
+		o.lease = lease
+	}
+}
+
+// LookThroughWithLeasePollInterval configures the look through cache to poll
+// the cache for a value fetched by another lease holder every specified interval.
+func LookThroughWithLeasePollInterval(interval time.Duration) LookThroughOption {
+	return func(o *lookThroughOptions) {
+		o.leasePollInterval = interval
+	}
+}
+
+// LookThroughWithLeaseMaxWait configures the look through cache to stop waiting
+// for a value fetched by another lease holder after the specified amount of time,
+// and fetch it from the repository instead.
+func LookThroughWithLeaseMaxWait(maxWait time.Duration) LookThroughOption {
+	return func(o *lookThroughOptions) {
+		o.leaseMaxWait = maxWait
+	}
+}
+
+// LookThroughWithNotFoundClassifier configures the look through cache to treat errors
+// classified as not found by the specified classifier like ErrNotFound, in addition to it.
+// Such cache errors are handled as clean misses without being reported, and such
+// repository errors are returned wrapping ErrNotFound.
+func LookThroughWithNotFoundClassifier(isNotFound NotFoundClassifier) LookThroughOption {
+	return func(o *lookThroughOptions) {
+		o.isNotFound = isNotFound
+	}
+}
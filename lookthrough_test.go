@@ -1,7 +1,9 @@
 package cachehit
 
 import (
+	"context"
 	"errors"
+	"fmt"
 	"sync"
 	"testing"
 	"time"
@@ -192,3 +194,612 @@ func Test_LookThrough_ErrorCallbackCalled_CacheGet(t *testing.T) {
 	repo.AssertExpectations(t)
 	cache.AssertExpectations(t)
 }
+
+func Test_LookThrough_GetMany_ValuesInBatchCache(t *testing.T) {
+	ctx := t.Context()
+
+	keys := []string{"key1", "key2"}
+	cached := map[string]Result[string]{
+		"key1": {Value: "value1"},
+		"key2": {Value: "value2"},
+	}
+
+	cache := &mockBatchCache[string, string]{}
+	repo := &mockRepo[string, string]{}
+
+	cache.On("GetMany", ctx, keys).Return(cached, nil).Once()
+
+	lt, err := NewLookThrough(cache, repo)
+	require.NoError(t, err)
+
+	actual, err := lt.GetMany(ctx, []string{"key1", "key2", "key1"})
+	require.NoError(t, err)
+	require.Equal(t, cached, actual)
+
+	repo.AssertExpectations(t)
+	cache.AssertExpectations(t)
+}
+
+func Test_LookThrough_GetMany_MissesInBatchRepository(t *testing.T) {
+	ctx := t.Context()
+
+	keys := []string{"key1", "key2", "key3"}
+	misses := []string{"key2", "key3"}
+
+	cache := &mockBatchCache[string, string]{}
+	repo := &mockBatchCache[string, string]{}
+
+	cache.On("GetMany", ctx, keys).Return(map[string]Result[string]{
+		"key1": {Value: "value1"},
+		"key2": {Err: ErrNotFound},
+		"key3": {Err: ErrNotFound},
+	}, nil).Once()
+	repo.On("GetMany", ctx, misses).Return(map[string]Result[string]{
+		"key2": {Value: "value2"},
+		"key3": {Err: ErrNotFound},
+	}, nil).Once()
+	cache.On("SetMany", ctx, map[string]string{"key2": "value2"}).Return(nil).Once()
+
+	lt, err := NewLookThrough(cache, repo)
+	require.NoError(t, err)
+
+	actual, err := lt.GetMany(ctx, keys)
+	require.NoError(t, err)
+	require.Len(t, actual, 3)
+	require.Equal(t, Result[string]{Value: "value1"}, actual["key1"])
+	require.Equal(t, Result[string]{Value: "value2"}, actual["key2"])
+	require.ErrorIs(t, actual["key3"].Err, ErrNotFound)
+
+	repo.AssertExpectations(t)
+	cache.AssertExpectations(t)
+}
+
+func Test_LookThrough_GetMany_MissesInRepository(t *testing.T) {
+	ctx := t.Context()
+
+	cache := &mockCache[string, string]{}
+	repo := &mockRepo[string, string]{}
+
+	cache.On("Get", ctx, "key1").Return("value1", nil).Once()
+	cache.On("Get", ctx, "key2").Return("", ErrNotFound).Once()
+	cache.On("Get", ctx, "key3").Return("", ErrNotFound).Once()
+	repo.On("Get", ctx, "key2").Return("value2", nil).Once()
+	repo.On("Get", ctx, "key3").Return("", ErrNotFound).Once()
+	cache.On("Set", ctx, "key2", "value2").Return(nil).Once()
+
+	lt, err := NewLookThrough(cache, repo)
+	require.NoError(t, err)
+
+	actual, err := lt.GetMany(ctx, []string{"key1", "key2", "key3"})
+	require.NoError(t, err)
+	require.Len(t, actual, 3)
+	require.Equal(t, Result[string]{Value: "value1"}, actual["key1"])
+	require.Equal(t, Result[string]{Value: "value2"}, actual["key2"])
+	require.ErrorIs(t, actual["key3"].Err, ErrNotFound)
+
+	repo.AssertExpectations(t)
+	cache.AssertExpectations(t)
+}
+
+func Test_LookThrough_GetMany_BatchRepositoryError(t *testing.T) {
+	ctx := t.Context()
+
+	keys := []string{"key1"}
+	repoErr := errors.New("failed")
+
+	cache := &mockBatchCache[string, string]{}
+	repo := &mockBatchCache[string, string]{}
+
+	cache.On("GetMany", ctx, keys).Return(map[string]Result[string]{
+		"key1": {Err: ErrNotFound},
+	}, nil).Once()
+	repo.On("GetMany", ctx, keys).Return(nil, repoErr).Once()
+
+	lt, err := NewLookThrough(cache, repo)
+	require.NoError(t, err)
+
+	_, err = lt.GetMany(ctx, keys)
+	require.ErrorIs(t, err, repoErr)
+
+	repo.AssertExpectations(t)
+	cache.AssertExpectations(t)
+}
+
+func Test_LookThrough_GetMany_ErrorCallbackCalled_CacheGetMany(t *testing.T) {
+	ctx := t.Context()
+
+	keys := []string{"key1"}
+	cacheErr := errors.New("failed")
+	cacheSetErr := errors.New("set failed")
+
+	cache := &mockBatchCache[string, string]{}
+	repo := &mockRepo[string, string]{}
+
+	cache.On("GetMany", ctx, keys).Return(nil, cacheErr).Once()
+	repo.On("Get", ctx, "key1").Return("value1", nil).Once()
+	cache.On("Set", ctx, "key1", "value1").Return(cacheSetErr).Once()
+
+	var mu sync.Mutex
+	var capturedErrs []error
+	errorCallback := func(err error) {
+		mu.Lock()
+		defer mu.Unlock()
+		capturedErrs = append(capturedErrs, err)
+	}
+
+	lt, err := NewLookThrough(cache, repo, LookThroughWithErrorCallback(errorCallback))
+	require.NoError(t, err)
+
+	actual, err := lt.GetMany(ctx, keys)
+	require.NoError(t, err)
+	require.Equal(t, Result[string]{Value: "value1"}, actual["key1"])
+
+	require.Len(t, capturedErrs, 2)
+	require.ErrorIs(t, capturedErrs[0], cacheErr)
+	require.ErrorIs(t, capturedErrs[1], cacheSetErr)
+
+	repo.AssertExpectations(t)
+	cache.AssertExpectations(t)
+}
+
+type nonDeletableCache[K comparable, V any] struct {
+	Cache[K, V]
+}
+
+func Test_LookThrough_New_WithInvalidOptions(t *testing.T) {
+	cache := &mockCache[string, string]{}
+	repo := &mockRepo[string, string]{}
+
+	t.Run("zero lease poll interval", func(t *testing.T) {
+		_, err := NewLookThrough(cache, repo, LookThroughWithLeasePollInterval(0))
+		require.Error(t, err)
+		require.Contains(t, err.Error(), "poll interval must be positive")
+	})
+
+	t.Run("zero lease max wait", func(t *testing.T) {
+		_, err := NewLookThrough(cache, repo, LookThroughWithLeaseMaxWait(0))
+		require.Error(t, err)
+		require.Contains(t, err.Error(), "max wait must be positive")
+	})
+
+	t.Run("invalidation bus without cache delete", func(t *testing.T) {
+		bus := NewLocalInvalidationBus()
+		_, err := NewLookThrough(&nonDeletableCache[string, string]{cache}, repo, LookThroughWithInvalidationBus(bus))
+		require.Error(t, err)
+		require.Contains(t, err.Error(), "doesn't support delete")
+	})
+}
+
+func Test_LookThrough_Invalidate(t *testing.T) {
+	ctx := t.Context()
+
+	key := "key"
+
+	cache := &mockCache[string, string]{}
+	repo := &mockRepo[string, string]{}
+
+	bus := NewLocalInvalidationBus()
+
+	lt, err := NewLookThrough(cache, repo, LookThroughWithInvalidationBus(bus))
+	require.NoError(t, err)
+
+	// Dropped locally only, the instance skips its own message on the bus
+	cache.On("Delete", ctx, key).Return(nil).Once()
+
+	require.NoError(t, lt.Invalidate(ctx, key))
+
+	repo.AssertExpectations(t)
+	cache.AssertExpectations(t)
+}
+
+func Test_LookThrough_Invalidate_DuringFetch(t *testing.T) {
+	ctx := t.Context()
+
+	key := "key"
+	value := "value"
+
+	cache := &mockCache[string, string]{}
+	repo := &mockRepo[string, string]{}
+
+	fetching := make(chan struct{})
+	invalidated := make(chan struct{})
+
+	cache.On("Get", ctx, key).Return("", ErrNotFound).Once()
+	cache.On("Delete", ctx, key).Return(nil).Once()
+	repo.On("Get", ctx, key).Run(func(mock.Arguments) {
+		close(fetching)
+		<-invalidated
+	}).Return(value, nil).Once()
+
+	lt, err := NewLookThrough(cache, repo)
+	require.NoError(t, err)
+
+	go func() {
+		<-fetching
+		require.NoError(t, lt.Invalidate(ctx, key))
+		close(invalidated)
+	}()
+
+	// The value fetched before the invalidation is returned, but not cached
+	actual, err := lt.Get(ctx, key)
+	require.NoError(t, err)
+	require.Equal(t, value, actual)
+
+	repo.AssertExpectations(t)
+	cache.AssertExpectations(t)
+}
+
+func Test_LookThrough_Invalidate_FromOtherInstance(t *testing.T) {
+	ctx := t.Context()
+
+	key := "key"
+
+	bus := NewLocalInvalidationBus()
+
+	local := &mockCache[string, string]{}
+	remote := &mockCache[string, string]{}
+
+	lt, err := NewLookThrough(local, &mockRepo[string, string]{}, LookThroughWithInvalidationBus(bus))
+	require.NoError(t, err)
+
+	_, err = NewLookThrough(remote, &mockRepo[string, string]{}, LookThroughWithInvalidationBus(bus))
+	require.NoError(t, err)
+
+	local.On("Delete", ctx, key).Return(nil).Once()
+	remote.On("Delete", mock.Anything, key).Return(nil).Once()
+
+	require.NoError(t, lt.Invalidate(ctx, key))
+
+	local.AssertExpectations(t)
+	remote.AssertExpectations(t)
+}
+
+func Test_LookThrough_ErrorCallbackCalled_InvalidateNotSupported(t *testing.T) {
+	ctx := t.Context()
+
+	key := "key"
+
+	cache := &nonDeletableCache[string, string]{&mockCache[string, string]{}}
+	repo := &mockRepo[string, string]{}
+
+	var capturedErr error
+	errorCallback := func(err error) {
+		capturedErr = err
+	}
+
+	lt, err := NewLookThrough(cache, repo, LookThroughWithErrorCallback(errorCallback))
+	require.NoError(t, err)
+
+	require.NoError(t, lt.Invalidate(ctx, key))
+	require.ErrorContains(t, capturedErr, "not supported")
+}
+
+func Test_LookThrough_Lease_Acquired(t *testing.T) {
+	ctx := t.Context()
+
+	key := "key"
+	value := "value"
+
+	cache := &mockCache[string, string]{}
+	repo := &mockRepo[string, string]{}
+	lease := &mockLease{}
+
+	released := false
+	release := func(err error) {
+		require.NoError(t, err)
+		released = true
+	}
+
+	cache.On("Get", ctx, key).Return("", ErrNotFound).Once()
+	lease.On("Acquire", ctx, key).Return(release, true, nil).Once()
+	repo.On("Get", ctx, key).Return(value, nil).Once()
+	cache.On("Set", ctx, key, value).Return(nil).Once()
+
+	lt, err := NewLookThrough(cache, repo, LookThroughWithLease(lease))
+	require.NoError(t, err)
+
+	actual, err := lt.Get(ctx, key)
+	require.NoError(t, err)
+	require.Equal(t, value, actual)
+	require.True(t, released)
+
+	repo.AssertExpectations(t)
+	cache.AssertExpectations(t)
+	lease.AssertExpectations(t)
+}
+
+func Test_LookThrough_Lease_ReleasedWithOutcome(t *testing.T) {
+	ctx := t.Context()
+
+	key := "key"
+
+	cache := &mockCache[string, string]{}
+	repo := &mockRepo[string, string]{}
+	lease := &mockLease{}
+
+	var outcome error
+	release := func(err error) {
+		outcome = err
+	}
+
+	cache.On("Get", ctx, key).Return("", ErrNotFound).Once()
+	lease.On("Acquire", ctx, key).Return(release, true, nil).Once()
+	repo.On("Get", ctx, key).Return("", ErrNotFound).Once()
+
+	lt, err := NewLookThrough(cache, repo, LookThroughWithLease(lease))
+	require.NoError(t, err)
+
+	_, err = lt.Get(ctx, key)
+	require.ErrorIs(t, err, ErrNotFound)
+	require.ErrorIs(t, outcome, ErrNotFound)
+
+	repo.AssertExpectations(t)
+	cache.AssertExpectations(t)
+	lease.AssertExpectations(t)
+}
+
+func Test_LookThrough_Lease_HolderOutcome(t *testing.T) {
+	ctx := t.Context()
+
+	key := "key"
+
+	tests := []struct {
+		name    string
+		outcome error
+	}{
+		{"NotFound", fmt.Errorf("holder: %w", ErrNotFound)},
+		{"Failed", fmt.Errorf("holder: %w", ErrLeaseFailed)},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			cache := &mockCache[string, string]{}
+			repo := &mockRepo[string, string]{}
+			lease := &mockLease{}
+
+			// The holder releases the lease without a value while others wait
+			cache.On("Get", ctx, key).Return("", ErrNotFound).Twice()
+			lease.On("Acquire", ctx, key).Return(nil, false, nil).Once()
+			lease.On("Acquire", ctx, key).Return(nil, false, test.outcome).Once()
+
+			lt, err := NewLookThrough(cache, repo,
+				LookThroughWithLease(lease),
+				LookThroughWithLeasePollInterval(time.Millisecond))
+			require.NoError(t, err)
+
+			// The outcome is shared, without fetching the key again
+			_, err = lt.Get(ctx, key)
+			require.ErrorIs(t, err, test.outcome)
+
+			repo.AssertExpectations(t)
+			cache.AssertExpectations(t)
+			lease.AssertExpectations(t)
+		})
+	}
+}
+
+func Test_LookThrough_Lease_WaitForHolder(t *testing.T) {
+	ctx := t.Context()
+
+	key := "key"
+	value := "value"
+
+	cache := &mockCache[string, string]{}
+	repo := &mockRepo[string, string]{}
+	lease := &mockLease{}
+
+	cache.On("Get", ctx, key).Return("", ErrNotFound).Twice()
+	lease.On("Acquire", ctx, key).Return(nil, false, nil).Twice()
+	cache.On("Get", ctx, key).Return(value, nil).Once()
+
+	lt, err := NewLookThrough(cache, repo,
+		LookThroughWithLease(lease),
+		LookThroughWithLeasePollInterval(time.Millisecond))
+	require.NoError(t, err)
+
+	actual, err := lt.Get(ctx, key)
+	require.NoError(t, err)
+	require.Equal(t, value, actual)
+
+	repo.AssertExpectations(t)
+	cache.AssertExpectations(t)
+	lease.AssertExpectations(t)
+}
+
+func Test_LookThrough_Lease_HolderCrashed(t *testing.T) {
+	ctx := t.Context()
+
+	key := "key"
+	value := "value"
+
+	cache := &mockCache[string, string]{}
+	repo := &mockRepo[string, string]{}
+	lease := &mockLease{}
+
+	// The lease expires after the first poll, and is taken over
+	cache.On("Get", ctx, key).Return("", ErrNotFound).Times(3)
+	lease.On("Acquire", ctx, key).Return(nil, false, nil).Once()
+	lease.On("Acquire", ctx, key).Return(func(error) {}, true, nil).Once()
+	repo.On("Get", ctx, key).Return(value, nil).Once()
+	cache.On("Set", ctx, key, value).Return(nil).Once()
+
+	lt, err := NewLookThrough(cache, repo,
+		LookThroughWithLease(lease),
+		LookThroughWithLeasePollInterval(time.Millisecond))
+	require.NoError(t, err)
+
+	actual, err := lt.Get(ctx, key)
+	require.NoError(t, err)
+	require.Equal(t, value, actual)
+
+	repo.AssertExpectations(t)
+	cache.AssertExpectations(t)
+	lease.AssertExpectations(t)
+}
+
+func Test_LookThrough_Lease_MaxWait(t *testing.T) {
+	ctx := t.Context()
+
+	key := "key"
+	value := "value"
+
+	cache := &mockCache[string, string]{}
+	repo := &mockRepo[string, string]{}
+	lease := &mockLease{}
+
+	cache.On("Get", ctx, key).Return("", ErrNotFound)
+	lease.On("Acquire", ctx, key).Return(nil, false, nil)
+	repo.On("Get", ctx, key).Return(value, nil).Once()
+	cache.On("Set", ctx, key, value).Return(nil).Once()
+
+	lt, err := NewLookThrough(cache, repo,
+		LookThroughWithLease(lease),
+		LookThroughWithLeasePollInterval(time.Millisecond),
+		LookThroughWithLeaseMaxWait(10*time.Millisecond))
+	require.NoError(t, err)
+
+	actual, err := lt.Get(ctx, key)
+	require.NoError(t, err)
+	require.Equal(t, value, actual)
+
+	repo.AssertExpectations(t)
+	cache.AssertExpectations(t)
+}
+
+func Test_LookThrough_Lease_ContextCanceled(t *testing.T) {
+	ctx, cancel := context.WithCancel(t.Context())
+
+	key := "key"
+
+	cache := &mockCache[string, string]{}
+	repo := &mockRepo[string, string]{}
+	lease := &mockLease{}
+
+	cache.On("Get", ctx, key).Return("", ErrNotFound).Once()
+	lease.On("Acquire", ctx, key).Run(func(args mock.Arguments) {
+		cancel()
+	}).Return(nil, false, nil).Once()
+
+	lt, err := NewLookThrough(cache, repo,
+		LookThroughWithLease(lease),
+		LookThroughWithLeasePollInterval(time.Hour))
+	require.NoError(t, err)
+
+	_, err = lt.Get(ctx, key)
+	require.ErrorIs(t, err, context.Canceled)
+
+	repo.AssertExpectations(t)
+	cache.AssertExpectations(t)
+	lease.AssertExpectations(t)
+}
+
+func Test_LookThrough_ErrorCallbackCalled_LeaseAcquire(t *testing.T) {
+	ctx := t.Context()
+
+	key := "key"
+	value := "value"
+	leaseErr := errors.New("failed")
+
+	cache := &mockCache[string, string]{}
+	repo := &mockRepo[string, string]{}
+	lease := &mockLease{}
+
+	cache.On("Get", ctx, key).Return("", ErrNotFound).Once()
+	lease.On("Acquire", ctx, key).Return(nil, false, leaseErr).Once()
+	repo.On("Get", ctx, key).Return(value, nil).Once()
+	cache.On("Set", ctx, key, value).Return(nil).Once()
+
+	var capturedErr error
+	errorCallback := func(err error) {
+		capturedErr = err
+	}
+
+	lt, err := NewLookThrough(cache, repo,
+		LookThroughWithLease(lease),
+		LookThroughWithErrorCallback(errorCallback))
+	require.NoError(t, err)
+
+	actual, err := lt.Get(ctx, key)
+	require.NoError(t, err)
+	require.Equal(t, value, actual)
+	require.ErrorIs(t, capturedErr, leaseErr)
+
+	repo.AssertExpectations(t)
+	cache.AssertExpectations(t)
+	lease.AssertExpectations(t)
+}
+
+func Test_LookThrough_NotFoundClassifier(t *testing.T) {
+	ctx := t.Context()
+
+	key := "key"
+
+	cache := &mockCache[string, string]{}
+	repo := &mockCache[string, string]{}
+
+	errMissing := errors.New("missing")
+	errNoRows := errors.New("no rows")
+
+	cache.On("Get", ctx, key).Return("", errMissing)
+	repo.On("Get", ctx, key).Return("", errNoRows)
+
+	errorCallback := func(err error) {
+		t.Errorf("unexpected error: %v", err)
+	}
+
+	lt, err := NewLookThrough(cache, repo,
+		LookThroughWithErrorCallback(errorCallback),
+		LookThroughWithNotFoundClassifier(func(err error) bool {
+			return errors.Is(err, errMissing) || errors.Is(err, errNoRows)
+		}))
+	require.NoError(t, err)
+
+	_, err = lt.Get(ctx, key)
+	require.ErrorIs(t, err, ErrNotFound)
+	require.ErrorIs(t, err, errNoRows)
+
+	repo.AssertExpectations(t)
+	cache.AssertExpectations(t)
+}
+
+func Test_LookThrough_GetMany_NotFoundClassifier(t *testing.T) {
+	ctx := t.Context()
+
+	keys := []string{"key1", "key2"}
+
+	cache := &mockBatchCache[string, string]{}
+	repo := &mockBatchCache[string, string]{}
+
+	errMissing := errors.New("missing")
+	errNoRows := errors.New("no rows")
+
+	cache.On("GetMany", ctx, keys).Return(map[string]Result[string]{
+		"key1": {Err: errMissing},
+		"key2": {Err: errMissing},
+	}, nil)
+	repo.On("GetMany", ctx, keys).Return(map[string]Result[string]{
+		"key1": {Value: "value1"},
+		"key2": {Err: errNoRows},
+	}, nil)
+	cache.On("SetMany", ctx, map[string]string{"key1": "value1"}).Return(nil)
+
+	errorCallback := func(err error) {
+		t.Errorf("unexpected error: %v", err)
+	}
+
+	lt, err := NewLookThrough(cache, repo,
+		LookThroughWithErrorCallback(errorCallback),
+		LookThroughWithNotFoundClassifier(func(err error) bool {
+			return errors.Is(err, errMissing) || errors.Is(err, errNoRows)
+		}))
+	require.NoError(t, err)
+
+	actual, err := lt.GetMany(ctx, keys)
+	require.NoError(t, err)
+	require.Equal(t, "value1", actual["key1"].Value)
+	require.ErrorIs(t, actual["key2"].Err, ErrNotFound)
+	require.ErrorIs(t, actual["key2"].Err, errNoRows)
+
+	repo.AssertExpectations(t)
+	cache.AssertExpectations(t)
+}
@@ -2,6 +2,7 @@ package cachehit
 
 import (
 	"context"
+	"time"
 
 	"github.com/stretchr/testify/mock"
 )
@@ -20,6 +21,35 @@ func (m *mockCache[K, V]) Set(ctx context.Context, key K, value V) error {
 	return args.Error(0)
 }
 
+func (m *mockCache[K, V]) Delete(ctx context.Context, key K) error {
+	args := m.Called(ctx, key)
+	return args.Error(0)
+}
+
+type mockBatchCache[K comparable, V any] struct {
+	mockCache[K, V]
+}
+
+func (m *mockBatchCache[K, V]) GetMany(ctx context.Context, keys []K) (map[K]Result[V], error) {
+	args := m.Called(ctx, keys)
+	results, _ := args.Get(0).(map[K]Result[V])
+	return results, args.Error(1)
+}
+
+func (m *mockBatchCache[K, V]) SetMany(ctx context.Context, values map[K]V) error {
+	args := m.Called(ctx, values)
+	return args.Error(0)
+}
+
+type mockTTLCache[K comparable, V any] struct {
+	mockCache[K, V]
+}
+
+func (m *mockTTLCache[K, V]) SetWithTTL(ctx context.Context, key K, value V, ttl time.Duration) error {
+	args := m.Called(ctx, key, value, ttl)
+	return args.Error(0)
+}
+
 type mockRepo[K comparable, V any] struct {
 	mock.Mock
 }
@@ -29,6 +59,16 @@ func (m *mockRepo[K, V]) Get(ctx context.Context, key K) (V, error) {
 	return args.Get(0).(V), args.Error(1)
 }
 
+type mockLease struct {
+	mock.Mock
+}
+
+func (m *mockLease) Acquire(ctx context.Context, key string) (func(error), bool, error) {
+	args := m.Called(ctx, key)
+	release, _ := args.Get(0).(func(error))
+	return release, args.Bool(1), args.Error(2)
+}
+
 type mockSyncMap struct {
 	mock.Mock
 }
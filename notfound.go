@@ -0,0 +1,31 @@
+package cachehit
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/dtrugman/cachehit/internal"
+)
+
+// NotFoundClassifier reports whether an error returned by a cache or a repository
+// means the key doesn't exist, e.g. sql.ErrNoRows or a gRPC NotFound status.
+type NotFoundClassifier = internal.NotFoundClassifier
+
+// isNotFound reports whether err is ErrNotFound, or classified as such by the classifier.
+func isNotFound(err error, classifier NotFoundClassifier) bool {
+	if errors.Is(err, ErrNotFound) {
+		return true
+	}
+
+	return classifier != nil && classifier(err)
+}
+
+// asNotFound wraps errors classified as not found with ErrNotFound,
+// so callers can match them using errors.Is, while keeping the original error.
+func asNotFound(err error, classifier NotFoundClassifier) error {
+	if err == nil || errors.Is(err, ErrNotFound) || !isNotFound(err, classifier) {
+		return err
+	}
+
+	return fmt.Errorf("%w: %w", ErrNotFound, err)
+}
@@ -0,0 +1,277 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	lru "github.com/hashicorp/golang-lru/v2"
+
+	"github.com/dtrugman/cachehit/codec"
+	"github.com/dtrugman/cachehit/internal"
+)
+
+const (
+	DefaultTimeout = 10 * time.Second
+)
+
+// StatusError is returned for responses with unexpected status codes,
+// i.e. ones that aren't mapped to an error using WithStatusError.
+type StatusError struct {
+	StatusCode int
+}
+
+func (e *StatusError) Error() string {
+	return fmt.Sprintf("status: %d", e.StatusCode)
+}
+
+// RequestBuilder builds the request fetching the specified key.
+type RequestBuilder[K comparable] func(ctx context.Context, key K) (*http.Request, error)
+
+// URLTemplate returns a request builder sending GET requests to the specified URL,
+// after replacing every occurrence of {key} with the path escaped key.
+func URLTemplate[K comparable](template string) RequestBuilder[K] {
+	return func(ctx context.Context, key K) (*http.Request, error) {
+		keyStr := url.PathEscape(fmt.Sprintf("%v", key))
+		return http.NewRequestWithContext(ctx, http.MethodGet, strings.ReplaceAll(template, "{key}", keyStr), nil)
+	}
+}
+
+type options struct {
+	client       *http.Client
+	codec        any
+	statusErrors map[int]error
+	etagSize     int
+}
+
+func (o *options) Validate() error {
+	if o.client == nil {
+		return fmt.Errorf("nil client")
+	}
+
+	if o.etagSize < 0 {
+		return fmt.Errorf("etag size must not be negative")
+	}
+
+	return nil
+}
+
+func defaultOptions() *options {
+	return &options{
+		client: &http.Client{Timeout: DefaultTimeout},
+		statusErrors: map[int]error{
+			http.StatusNotFound: internal.ErrNotFound,
+		},
+	}
+}
+
+func compileOptions(opts ...Option) *options {
+	o := defaultOptions()
+
+	for _, opt := range opts {
+		opt(o)
+	}
+
+	return o
+}
+
+type Option func(*options)
+
+// WithClient configures the repository to send requests using the specified client.
+// Defaults to a client with a timeout of DefaultTimeout.
+func WithClient(client *http.Client) Option {
+	return func(o *options) {
+		o.client = client
+	}
+}
+
+// WithCodec configures the repository to decode response bodies using the specified codec.
+// The codec value type must match the repository value type.
+// Defaults to codec.JSON.
+func WithCodec[V any](c codec.Codec[V]) Option {
+	return func(o *options) {
+		o.codec = c
+	}
+}
+
+// WithStatusError configures the repository to return the specified error for responses
+// with the specified status code, e.g. cachehit.ErrNotFound for 410 (Gone).
+// By default, 404 (Not Found) is mapped to cachehit.ErrNotFound, and other
+// unexpected status codes to a StatusError.
+func WithStatusError(statusCode int, err error) Option {
+	return func(o *options) {
+		o.statusErrors[statusCode] = err
+	}
+}
+
+// WithETagRevalidation configures the repository to remember the ETag and value of
+// up to size keys, and revalidate them using If-None-Match, so unchanged values
+// aren't transferred and decoded again.
+func WithETagRevalidation(size int) Option {
+	return func(o *options) {
+		o.etagSize = size
+	}
+}
+
+// Response holds a fetched value, along with its caching metadata.
+type Response[V any] struct {
+	Value V
+	// TTL is the freshness lifetime of the value according to the Cache-Control
+	// header (s-maxage or max-age, minus Age), or zero if unspecified.
+	TTL time.Duration
+}
+
+type etagEntry[V any] struct {
+	etag  string
+	value V
+}
+
+// HTTP is a repository fetching values over HTTP.
+type HTTP[K comparable, V any] struct {
+	client       *http.Client
+	build        RequestBuilder[K]
+	codec        codec.Codec[V]
+	statusErrors map[int]error
+	etags        *lru.Cache[K, etagEntry[V]]
+}
+
+func New[K comparable, V any](build RequestBuilder[K], opts ...Option) (*HTTP[K, V], error) {
+	if build == nil {
+		return nil, fmt.Errorf("nil request builder")
+	}
+
+	o := compileOptions(opts...)
+	if err := o.Validate(); err != nil {
+		return nil, fmt.Errorf("options: %w", err)
+	}
+
+	var valueCodec codec.Codec[V]
+	if o.codec == nil {
+		valueCodec = codec.JSON[V]()
+	} else if c, ok := o.codec.(codec.Codec[V]); ok {
+		valueCodec = c
+	} else {
+		var v V
+		return nil, fmt.Errorf("options: codec: expected codec for %T: found %T", v, o.codec)
+	}
+
+	var etags *lru.Cache[K, etagEntry[V]]
+	if o.etagSize > 0 {
+		var err error
+		etags, err = lru.New[K, etagEntry[V]](o.etagSize)
+		if err != nil {
+			return nil, fmt.Errorf("etags: %w", err)
+		}
+	}
+
+	return &HTTP[K, V]{
+		client:       o.client,
+		build:        build,
+		codec:        valueCodec,
+		statusErrors: o.statusErrors,
+		etags:        etags,
+	}, nil
+}
+
+// ttl returns the freshness lifetime specified by the response headers, or zero.
+func ttl(header http.Header) time.Duration {
+	var maxAge, sharedMaxAge = -1, -1
+	for _, directive := range strings.Split(header.Get("Cache-Control"), ",") {
+		name, value, _ := strings.Cut(strings.TrimSpace(directive), "=")
+
+		seconds, err := strconv.Atoi(strings.Trim(value, `"`))
+		if err != nil || seconds < 0 {
+			continue
+		}
+
+		switch strings.ToLower(name) {
+		case "max-age":
+			maxAge = seconds
+		case "s-maxage":
+			sharedMaxAge = seconds
+		}
+	}
+
+	// s-maxage applies to shared caches, overriding max-age
+	lifetime := maxAge
+	if sharedMaxAge >= 0 {
+		lifetime = sharedMaxAge
+	}
+
+	if lifetime < 0 {
+		return 0
+	}
+
+	if age, err := strconv.Atoi(header.Get("Age")); err == nil && age > 0 {
+		lifetime = max(lifetime-age, 0)
+	}
+
+	return time.Duration(lifetime) * time.Second
+}
+
+// Fetch fetches the specified key, along with its caching metadata.
+func (r *HTTP[K, V]) Fetch(ctx context.Context, key K) (Response[V], error) {
+	var zero Response[V]
+
+	req, err := r.build(ctx, key)
+	if err != nil {
+		return zero, fmt.Errorf("new request: %w", err)
+	}
+
+	var cached etagEntry[V]
+	var revalidate bool
+	if r.etags != nil {
+		if cached, revalidate = r.etags.Get(key); revalidate {
+			req.Header.Set("If-None-Match", cached.etag)
+		}
+	}
+
+	resp, err := r.client.Do(req)
+	if err != nil {
+		return zero, fmt.Errorf("send request: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if revalidate && resp.StatusCode == http.StatusNotModified {
+		return Response[V]{Value: cached.value, TTL: ttl(resp.Header)}, nil
+	}
+
+	if err, ok := r.statusErrors[resp.StatusCode]; ok {
+		if r.etags != nil {
+			r.etags.Remove(key)
+		}
+		return zero, err
+	} else if resp.StatusCode != http.StatusOK {
+		return zero, &StatusError{StatusCode: resp.StatusCode}
+	}
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return zero, fmt.Errorf("read body: %w", err)
+	}
+
+	value, err := r.codec.Decode(body)
+	if err != nil {
+		return zero, fmt.Errorf("decode: %w", err)
+	}
+
+	if r.etags != nil {
+		if etag := resp.Header.Get("ETag"); etag != "" {
+			r.etags.Add(key, etagEntry[V]{etag: etag, value: value})
+		} else {
+			r.etags.Remove(key)
+		}
+	}
+
+	return Response[V]{Value: value, TTL: ttl(resp.Header)}, nil
+}
+
+func (r *HTTP[K, V]) Get(ctx context.Context, key K) (V, error) {
+	resp, err := r.Fetch(ctx, key)
+	return resp.Value, err
+}
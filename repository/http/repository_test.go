@@ -0,0 +1,264 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/dtrugman/cachehit"
+	"github.com/dtrugman/cachehit/codec"
+)
+
+type user struct {
+	Login string `json:"login"`
+	Name  string `json:"name"`
+}
+
+func TestNew_Invalid(t *testing.T) {
+	build := URLTemplate[string]("http://localhost/{key}")
+
+	_, err := New[string, user](nil)
+	require.Error(t, err)
+
+	_, err = New[string, user](build, WithClient(nil))
+	require.ErrorContains(t, err, "client")
+
+	_, err = New[string, user](build, WithETagRevalidation(-1))
+	require.ErrorContains(t, err, "etag size")
+
+	_, err = New[string, user](build, WithCodec(codec.JSON[string]()))
+	require.ErrorContains(t, err, "codec")
+}
+
+func TestHTTP_Repository(t *testing.T) {
+	var _ cachehit.Repository[string, user] = (*HTTP[string, user])(nil)
+}
+
+func TestHTTP_Get(t *testing.T) {
+	ctx := t.Context()
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		require.Equal(t, "/users/a%20b", r.URL.EscapedPath())
+		w.Write([]byte(`{"login":"a b","name":"name"}`))
+	}))
+	t.Cleanup(server.Close)
+
+	repo, err := New[string, user](URLTemplate[string](server.URL + "/users/{key}"))
+	require.NoError(t, err)
+
+	value, err := repo.Get(ctx, "a b")
+	require.NoError(t, err)
+	require.Equal(t, user{Login: "a b", Name: "name"}, value)
+}
+
+func TestHTTP_RequestBuilder(t *testing.T) {
+	ctx := t.Context()
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		require.Equal(t, http.MethodPost, r.Method)
+		require.Equal(t, "Bearer token", r.Header.Get("Authorization"))
+		require.Equal(t, "42", r.URL.Query().Get("id"))
+		w.Write([]byte("value"))
+	}))
+	t.Cleanup(server.Close)
+
+	build := func(ctx context.Context, key int) (*http.Request, error) {
+		req, err := http.NewRequestWithContext(ctx, http.MethodPost, server.URL+"?id="+codecKey(key), nil)
+		if err != nil {
+			return nil, err
+		}
+		req.Header.Set("Authorization", "Bearer token")
+		return req, nil
+	}
+
+	repo, err := New[int, string](build, WithCodec(codec.Default[string]()))
+	require.NoError(t, err)
+
+	value, err := repo.Get(ctx, 42)
+	require.NoError(t, err)
+	require.Equal(t, "value", value)
+}
+
+func codecKey(key int) string {
+	data, _ := codec.Default[int]().Encode(key)
+	return string(data)
+}
+
+func TestHTTP_StatusErrors(t *testing.T) {
+	ctx := t.Context()
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/missing":
+			w.WriteHeader(http.StatusNotFound)
+		case "/gone":
+			w.WriteHeader(http.StatusGone)
+		case "/throttled":
+			w.WriteHeader(http.StatusTooManyRequests)
+		default:
+			w.WriteHeader(http.StatusInternalServerError)
+		}
+	}))
+	t.Cleanup(server.Close)
+
+	errThrottled := errors.New("throttled")
+
+	repo, err := New[string, user](URLTemplate[string](server.URL+"/{key}"),
+		WithStatusError(http.StatusGone, cachehit.ErrNotFound),
+		WithStatusError(http.StatusTooManyRequests, errThrottled))
+	require.NoError(t, err)
+
+	_, err = repo.Get(ctx, "missing")
+	require.ErrorIs(t, err, cachehit.ErrNotFound)
+
+	_, err = repo.Get(ctx, "gone")
+	require.ErrorIs(t, err, cachehit.ErrNotFound)
+
+	_, err = repo.Get(ctx, "throttled")
+	require.ErrorIs(t, err, errThrottled)
+
+	_, err = repo.Get(ctx, "broken")
+	var statusErr *StatusError
+	require.ErrorAs(t, err, &statusErr)
+	require.Equal(t, http.StatusInternalServerError, statusErr.StatusCode)
+}
+
+func TestHTTP_DecodeError(t *testing.T) {
+	ctx := t.Context()
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("not json"))
+	}))
+	t.Cleanup(server.Close)
+
+	repo, err := New[string, user](URLTemplate[string](server.URL + "/{key}"))
+	require.NoError(t, err)
+
+	_, err = repo.Get(ctx, "key")
+	require.ErrorContains(t, err, "decode")
+}
+
+func TestHTTP_RequestError(t *testing.T) {
+	ctx, cancel := context.WithCancel(t.Context())
+	cancel()
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	t.Cleanup(server.Close)
+
+	repo, err := New[string, user](URLTemplate[string](server.URL + "/{key}"))
+	require.NoError(t, err)
+
+	_, err = repo.Get(ctx, "key")
+	require.ErrorIs(t, err, context.Canceled)
+
+	repo, err = New[string, user](URLTemplate[string]("://invalid/{key}"))
+	require.NoError(t, err)
+
+	_, err = repo.Get(t.Context(), "key")
+	require.ErrorContains(t, err, "new request")
+}
+
+func TestHTTP_ETagRevalidation(t *testing.T) {
+	ctx := t.Context()
+
+	var requests, notModified atomic.Int32
+	body := `{"login":"login","name":"v1"}`
+	etag := `"v1"`
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests.Add(1)
+		if r.Header.Get("If-None-Match") == etag {
+			notModified.Add(1)
+			w.WriteHeader(http.StatusNotModified)
+			return
+		}
+		w.Header().Set("ETag", etag)
+		w.Write([]byte(body))
+	}))
+	t.Cleanup(server.Close)
+
+	repo, err := New[string, user](URLTemplate[string](server.URL+"/{key}"), WithETagRevalidation(16))
+	require.NoError(t, err)
+
+	for range 2 {
+		value, err := repo.Get(ctx, "login")
+		require.NoError(t, err)
+		require.Equal(t, "v1", value.Name)
+	}
+	require.EqualValues(t, 2, requests.Load())
+	require.EqualValues(t, 1, notModified.Load())
+
+	// Changed values are transferred again
+	body = `{"login":"login","name":"v2"}`
+	etag = `"v2"`
+
+	value, err := repo.Get(ctx, "login")
+	require.NoError(t, err)
+	require.Equal(t, "v2", value.Name)
+	require.EqualValues(t, 1, notModified.Load())
+}
+
+func TestHTTP_ETagRevalidation_Disabled(t *testing.T) {
+	ctx := t.Context()
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		require.Empty(t, r.Header.Get("If-None-Match"))
+		w.Header().Set("ETag", `"v1"`)
+		w.Write([]byte(`{}`))
+	}))
+	t.Cleanup(server.Close)
+
+	repo, err := New[string, user](URLTemplate[string](server.URL + "/{key}"))
+	require.NoError(t, err)
+
+	for range 2 {
+		_, err := repo.Get(ctx, "key")
+		require.NoError(t, err)
+	}
+}
+
+func TestHTTP_Fetch_TTL(t *testing.T) {
+	ctx := t.Context()
+
+	tests := []struct {
+		cacheControl string
+		age          string
+		expected     time.Duration
+	}{
+		{"", "", 0},
+		{"no-store", "", 0},
+		{"max-age=60", "", time.Minute},
+		{"public, max-age=60", "10", 50 * time.Second},
+		{"max-age=60", "120", 0},
+		{"max-age=60, s-maxage=300", "", 5 * time.Minute},
+		{`max-age="60"`, "", time.Minute},
+		{"max-age=invalid", "", 0},
+	}
+
+	for _, test := range tests {
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if test.cacheControl != "" {
+				w.Header().Set("Cache-Control", test.cacheControl)
+			}
+			if test.age != "" {
+				w.Header().Set("Age", test.age)
+			}
+			w.Write([]byte(`{}`))
+		}))
+
+		repo, err := New[string, user](URLTemplate[string](server.URL + "/{key}"))
+		require.NoError(t, err)
+
+		resp, err := repo.Fetch(ctx, "key")
+		require.NoError(t, err)
+		require.Equal(t, test.expected, resp.TTL, test.cacheControl)
+
+		server.Close()
+	}
+}
@@ -0,0 +1,229 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/dtrugman/cachehit/internal"
+)
+
+const (
+	// KeysPlaceholder is replaced with the key placeholders in batch queries.
+	KeysPlaceholder = "{keys}"
+
+	DefaultMaxBatchSize = 500
+)
+
+// Scanner is implemented by both *sql.Row and *sql.Rows.
+type Scanner interface {
+	Scan(dest ...any) error
+}
+
+// ScanFunc scans a single row into its key and value.
+// The key is only used by batch queries, to match rows to the requested keys.
+type ScanFunc[K comparable, V any] func(row Scanner) (K, V, error)
+
+// Placeholder returns the placeholder of the n-th (1-based) query argument.
+type Placeholder func(n int) string
+
+// QuestionPlaceholder returns ? placeholders, used by MySQL and SQLite.
+func QuestionPlaceholder(n int) string {
+	return "?"
+}
+
+// DollarPlaceholder returns $n placeholders, used by PostgreSQL.
+func DollarPlaceholder(n int) string {
+	return "$" + strconv.Itoa(n)
+}
+
+type options struct {
+	batchQuery   string
+	placeholder  Placeholder
+	maxBatchSize int
+}
+
+func (o *options) Validate() error {
+	if o.batchQuery != "" && !strings.Contains(o.batchQuery, KeysPlaceholder) {
+		return fmt.Errorf("batch query must contain %s", KeysPlaceholder)
+	}
+
+	if o.placeholder == nil {
+		return fmt.Errorf("nil placeholder")
+	}
+
+	if o.maxBatchSize <= 0 {
+		return fmt.Errorf("max batch size must be positive")
+	}
+
+	return nil
+}
+
+func defaultOptions() *options {
+	return &options{
+		placeholder:  QuestionPlaceholder,
+		maxBatchSize: DefaultMaxBatchSize,
+	}
+}
+
+func compileOptions(opts ...Option) *options {
+	o := defaultOptions()
+
+	for _, opt := range opts {
+		opt(o)
+	}
+
+	return o
+}
+
+type Option func(*options)
+
+// WithBatchQuery configures the repository to fetch multiple keys using the specified query,
+// e.g. "SELECT id, name FROM users WHERE id IN ({keys})".
+// KeysPlaceholder is replaced with a placeholder for every key.
+// Without a batch query, multiple keys are fetched one by one.
+func WithBatchQuery(query string) Option {
+	return func(o *options) {
+		o.batchQuery = query
+	}
+}
+
+// WithPlaceholder configures the placeholders used for keys in batch queries.
+// Defaults to QuestionPlaceholder.
+func WithPlaceholder(placeholder Placeholder) Option {
+	return func(o *options) {
+		o.placeholder = placeholder
+	}
+}
+
+// WithMaxBatchSize configures the maximum number of keys fetched by a single batch query.
+// Larger batches are split into multiple queries. Defaults to DefaultMaxBatchSize.
+func WithMaxBatchSize(size int) Option {
+	return func(o *options) {
+		o.maxBatchSize = size
+	}
+}
+
+// SQL is a repository fetching values from a database, using database/sql.
+type SQL[K comparable, V any] struct {
+	db           *sql.DB
+	query        string
+	scan         ScanFunc[K, V]
+	batchQuery   string
+	placeholder  Placeholder
+	maxBatchSize int
+}
+
+// New creates a new repository, fetching a single key using the specified query,
+// e.g. "SELECT id, name FROM users WHERE id = ?". The key is the only query argument.
+func New[K comparable, V any](db *sql.DB, query string, scan ScanFunc[K, V], opts ...Option) (*SQL[K, V], error) {
+	if db == nil {
+		return nil, fmt.Errorf("nil db")
+	}
+
+	if query == "" {
+		return nil, fmt.Errorf("empty query")
+	}
+
+	if scan == nil {
+		return nil, fmt.Errorf("nil scan func")
+	}
+
+	o := compileOptions(opts...)
+	if err := o.Validate(); err != nil {
+		return nil, fmt.Errorf("options: %w", err)
+	}
+
+	return &SQL[K, V]{
+		db:           db,
+		query:        query,
+		scan:         scan,
+		batchQuery:   o.batchQuery,
+		placeholder:  o.placeholder,
+		maxBatchSize: o.maxBatchSize,
+	}, nil
+}
+
+func (r *SQL[K, V]) Get(ctx context.Context, key K) (V, error) {
+	var zero V
+
+	row := r.db.QueryRowContext(ctx, r.query, key)
+
+	_, value, err := r.scan(row)
+	if errors.Is(err, sql.ErrNoRows) {
+		return zero, internal.ErrNotFound
+	} else if err != nil {
+		return zero, fmt.Errorf("query: %v: %w", key, err)
+	}
+
+	return value, nil
+}
+
+// GetMany fetches the specified keys using the batch query, if configured.
+// Keys that don't match any row are returned as ErrNotFound.
+func (r *SQL[K, V]) GetMany(ctx context.Context, keys []K) (map[K]internal.Result[V], error) {
+	results := make(map[K]internal.Result[V], len(keys))
+
+	if r.batchQuery == "" {
+		for _, key := range keys {
+			value, err := r.Get(ctx, key)
+			results[key] = internal.Result[V]{Value: value, Err: err}
+		}
+		return results, nil
+	}
+
+	unique := make([]K, 0, len(keys))
+	for _, key := range keys {
+		if _, ok := results[key]; !ok {
+			results[key] = internal.Result[V]{Err: internal.ErrNotFound}
+			unique = append(unique, key)
+		}
+	}
+
+	for start := 0; start < len(unique); start += r.maxBatchSize {
+		end := min(start+r.maxBatchSize, len(unique))
+		if err := r.getBatch(ctx, unique[start:end], results); err != nil {
+			return nil, err
+		}
+	}
+
+	return results, nil
+}
+
+func (r *SQL[K, V]) getBatch(ctx context.Context, keys []K, results map[K]internal.Result[V]) error {
+	placeholders := make([]string, 0, len(keys))
+	args := make([]any, 0, len(keys))
+	for i, key := range keys {
+		placeholders = append(placeholders, r.placeholder(i+1))
+		args = append(args, key)
+	}
+
+	query := strings.ReplaceAll(r.batchQuery, KeysPlaceholder, strings.Join(placeholders, ", "))
+
+	rows, err := r.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return fmt.Errorf("query: %w", err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		key, value, err := r.scan(rows)
+		if err != nil {
+			return fmt.Errorf("scan: %w", err)
+		}
+
+		// Ignore rows that weren't requested, e.g. due to key conversions
+		if _, ok := results[key]; ok {
+			results[key] = internal.Result[V]{Value: value}
+		}
+	}
+
+	if err := rows.Err(); err != nil {
+		return fmt.Errorf("rows: %w", err)
+	}
+
+	return nil
+}
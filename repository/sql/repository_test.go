@@ -0,0 +1,202 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"testing"
+
+	_ "github.com/mattn/go-sqlite3"
+	"github.com/stretchr/testify/require"
+
+	"github.com/dtrugman/cachehit"
+)
+
+type user struct {
+	ID   int
+	Name string
+}
+
+const (
+	userQuery      = "SELECT id, name FROM users WHERE id = ?"
+	userBatchQuery = "SELECT id, name FROM users WHERE id IN ({keys})"
+)
+
+func scanUser(row Scanner) (int, user, error) {
+	var u user
+	err := row.Scan(&u.ID, &u.Name)
+	return u.ID, u, err
+}
+
+func newDB(t *testing.T, users int) *sql.DB {
+	t.Helper()
+
+	db, err := sql.Open("sqlite3", ":memory:")
+	require.NoError(t, err)
+	t.Cleanup(func() { db.Close() })
+
+	// Every connection opens a separate in-memory database
+	db.SetMaxOpenConns(1)
+
+	_, err = db.Exec("CREATE TABLE users (id INTEGER PRIMARY KEY, name TEXT NOT NULL)")
+	require.NoError(t, err)
+
+	for i := 1; i <= users; i++ {
+		_, err = db.Exec("INSERT INTO users (id, name) VALUES (?, ?)", i, fmt.Sprintf("user%d", i))
+		require.NoError(t, err)
+	}
+
+	return db
+}
+
+func TestNew_Invalid(t *testing.T) {
+	db := newDB(t, 0)
+
+	_, err := New[int, user](nil, userQuery, scanUser)
+	require.Error(t, err)
+
+	_, err = New[int, user](db, "", scanUser)
+	require.Error(t, err)
+
+	_, err = New[int, user](db, userQuery, nil)
+	require.Error(t, err)
+
+	_, err = New(db, userQuery, scanUser, WithBatchQuery("SELECT id, name FROM users"))
+	require.ErrorContains(t, err, KeysPlaceholder)
+
+	_, err = New(db, userQuery, scanUser, WithPlaceholder(nil))
+	require.ErrorContains(t, err, "placeholder")
+
+	_, err = New(db, userQuery, scanUser, WithMaxBatchSize(0))
+	require.ErrorContains(t, err, "max batch size")
+}
+
+func TestSQL_Repository(t *testing.T) {
+	var _ cachehit.Repository[int, user] = (*SQL[int, user])(nil)
+	var _ cachehit.BatchRepository[int, user] = (*SQL[int, user])(nil)
+}
+
+func TestSQL_Get(t *testing.T) {
+	ctx := t.Context()
+
+	repo, err := New(newDB(t, 3), userQuery, scanUser)
+	require.NoError(t, err)
+
+	value, err := repo.Get(ctx, 2)
+	require.NoError(t, err)
+	require.Equal(t, user{ID: 2, Name: "user2"}, value)
+
+	_, err = repo.Get(ctx, 4)
+	require.ErrorIs(t, err, cachehit.ErrNotFound)
+}
+
+func TestSQL_Get_Error(t *testing.T) {
+	ctx := t.Context()
+
+	repo, err := New(newDB(t, 1), "SELECT id, name FROM missing WHERE id = ?", scanUser)
+	require.NoError(t, err)
+
+	_, err = repo.Get(ctx, 1)
+	require.ErrorContains(t, err, "query: 1")
+	require.NotErrorIs(t, err, cachehit.ErrNotFound)
+
+	canceled, cancel := context.WithCancel(ctx)
+	cancel()
+
+	repo, err = New(newDB(t, 1), userQuery, scanUser)
+	require.NoError(t, err)
+
+	_, err = repo.Get(canceled, 1)
+	require.ErrorIs(t, err, context.Canceled)
+}
+
+func TestSQL_GetMany(t *testing.T) {
+	ctx := t.Context()
+
+	for _, size := range []int{1, 2, DefaultMaxBatchSize} {
+		repo, err := New(newDB(t, 5), userQuery, scanUser,
+			WithBatchQuery(userBatchQuery),
+			WithMaxBatchSize(size))
+		require.NoError(t, err)
+
+		results, err := repo.GetMany(ctx, []int{1, 3, 3, 5, 7})
+		require.NoError(t, err)
+		require.Len(t, results, 4)
+
+		for _, key := range []int{1, 3, 5} {
+			require.NoError(t, results[key].Err)
+			require.Equal(t, user{ID: key, Name: fmt.Sprintf("user%d", key)}, results[key].Value)
+		}
+		require.ErrorIs(t, results[7].Err, cachehit.ErrNotFound)
+	}
+}
+
+func TestSQL_GetMany_Empty(t *testing.T) {
+	ctx := t.Context()
+
+	repo, err := New(newDB(t, 1), userQuery, scanUser, WithBatchQuery(userBatchQuery))
+	require.NoError(t, err)
+
+	results, err := repo.GetMany(ctx, nil)
+	require.NoError(t, err)
+	require.Empty(t, results)
+}
+
+func TestSQL_GetMany_NoBatchQuery(t *testing.T) {
+	ctx := t.Context()
+
+	repo, err := New(newDB(t, 2), userQuery, scanUser)
+	require.NoError(t, err)
+
+	results, err := repo.GetMany(ctx, []int{1, 2, 3})
+	require.NoError(t, err)
+	require.Len(t, results, 3)
+	require.Equal(t, "user1", results[1].Value.Name)
+	require.Equal(t, "user2", results[2].Value.Name)
+	require.ErrorIs(t, results[3].Err, cachehit.ErrNotFound)
+}
+
+func TestSQL_GetMany_Placeholder(t *testing.T) {
+	ctx := t.Context()
+
+	// SQLite supports numbered parameters as well
+	numbered := func(n int) string {
+		return fmt.Sprintf("?%d", n)
+	}
+
+	repo, err := New(newDB(t, 3), userQuery, scanUser,
+		WithBatchQuery(userBatchQuery),
+		WithPlaceholder(numbered))
+	require.NoError(t, err)
+
+	results, err := repo.GetMany(ctx, []int{2, 3})
+	require.NoError(t, err)
+	require.Equal(t, "user2", results[2].Value.Name)
+	require.Equal(t, "user3", results[3].Value.Name)
+
+	require.Equal(t, "$3", DollarPlaceholder(3))
+	require.Equal(t, "?", QuestionPlaceholder(3))
+}
+
+func TestSQL_GetMany_Error(t *testing.T) {
+	ctx := t.Context()
+
+	repo, err := New(newDB(t, 1), userQuery, scanUser,
+		WithBatchQuery("SELECT id, name FROM missing WHERE id IN ({keys})"))
+	require.NoError(t, err)
+
+	_, err = repo.GetMany(ctx, []int{1})
+	require.ErrorContains(t, err, "query")
+
+	scanErr := func(row Scanner) (int, user, error) {
+		var id int
+		err := row.Scan(&id)
+		return id, user{}, err
+	}
+
+	repo, err = New(newDB(t, 1), userQuery, scanErr, WithBatchQuery(userBatchQuery))
+	require.NoError(t, err)
+
+	_, err = repo.GetMany(ctx, []int{1})
+	require.ErrorContains(t, err, "scan")
+}
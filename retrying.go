@@ -0,0 +1,103 @@
+package cachehit
+
+import (
+	"context"
+	"fmt"
+	"math/rand/v2"
+	"time"
+)
+
+// Retrying is a repository that retries failed fetches with exponential backoff
+// and jitter. Keys that weren't found are never retried.
+type Retrying[K comparable, V any] struct {
+	repo Repository[K, V]
+
+	maxAttempts int
+
+	backoff    time.Duration
+	maxBackoff time.Duration
+	jitter     float64
+
+	attemptTimeout time.Duration
+
+	isRetryable func(err error) bool
+
+	isNotFound NotFoundClassifier
+}
+
+func NewRetrying[K comparable, V any](repo Repository[K, V], opts ...RetryingOption) (*Retrying[K, V], error) {
+	if repo == nil {
+		return nil, fmt.Errorf("nil repo")
+	}
+
+	o := retryingCompileOptions(opts...)
+	if err := o.Validate(); err != nil {
+		return nil, fmt.Errorf("options: %w", err)
+	}
+
+	return &Retrying[K, V]{
+		repo:           repo,
+		maxAttempts:    o.maxAttempts,
+		backoff:        o.backoff,
+		maxBackoff:     o.maxBackoff,
+		jitter:         o.jitter,
+		attemptTimeout: o.attemptTimeout,
+		isRetryable:    o.isRetryable,
+		isNotFound:     o.isNotFound,
+	}, nil
+}
+
+func (r *Retrying[K, V]) attempt(ctx context.Context, key K) (V, error) {
+	if r.attemptTimeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, r.attemptTimeout)
+		defer cancel()
+	}
+
+	return r.repo.Get(ctx, key)
+}
+
+// wait returns the jittered wait before the specified retry (starting with 1).
+func (r *Retrying[K, V]) wait(retry int) time.Duration {
+	backoff := r.backoff
+	for i := 1; i < retry && backoff < r.maxBackoff; i++ {
+		backoff *= 2
+	}
+	backoff = min(backoff, r.maxBackoff)
+
+	return backoff - time.Duration(r.jitter*rand.Float64()*float64(backoff))
+}
+
+// Get fetches the specified key from the repository, retrying retryable errors
+// until the max attempts are made. Retries that can't start before the deadline
+// of the context aren't made, and the last error is returned instead.
+// Errors classified as not found are returned wrapping ErrNotFound.
+func (r *Retrying[K, V]) Get(ctx context.Context, key K) (V, error) {
+	for attempt := 1; ; attempt++ {
+		value, err := r.attempt(ctx, key)
+		if err == nil {
+			return value, nil
+		} else if isNotFound(err, r.isNotFound) {
+			return value, asNotFound(err, r.isNotFound)
+		} else if !r.isRetryable(err) {
+			return value, err
+		}
+
+		if attempt >= r.maxAttempts || ctx.Err() != nil {
+			return value, err
+		}
+
+		wait := r.wait(attempt)
+		if deadline, ok := ctx.Deadline(); ok && time.Now().Add(wait).After(deadline) {
+			return value, err
+		}
+
+		timer := time.NewTimer(wait)
+		select {
+		case <-timer.C:
+		case <-ctx.Done():
+			timer.Stop()
+			return value, err
+		}
+	}
+}
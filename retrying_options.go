@@ -0,0 +1,138 @@
+package cachehit
+
+import (
+	"errors"
+	"fmt"
+	"time"
+)
+
+const (
+	RetryingDefaultMaxAttempts = 3
+	RetryingDefaultBackoff     = 50 * time.Millisecond
+	RetryingDefaultMaxBackoff  = time.Second
+	RetryingDefaultJitter      = 0.5
+)
+
+type retryingOptions struct {
+	maxAttempts int
+
+	backoff    time.Duration
+	maxBackoff time.Duration
+	jitter     float64
+
+	attemptTimeout time.Duration
+
+	isRetryable func(err error) bool
+
+	isNotFound NotFoundClassifier
+}
+
+func (o *retryingOptions) Validate() error {
+	if o.maxAttempts <= 0 {
+		return fmt.Errorf("max attempts must be positive")
+	}
+
+	if o.backoff <= time.Duration(0) {
+		return fmt.Errorf("backoff must be positive")
+	}
+
+	if o.maxBackoff < o.backoff {
+		return fmt.Errorf("max backoff must be at least backoff")
+	}
+
+	if o.jitter < 0 || o.jitter > 1 {
+		return fmt.Errorf("jitter must be in [0, 1]")
+	}
+
+	if o.attemptTimeout < time.Duration(0) {
+		return fmt.Errorf("attempt timeout must be non-negative")
+	}
+
+	if o.isRetryable == nil {
+		return fmt.Errorf("nil retry classifier")
+	}
+
+	return nil
+}
+
+// isRetryable is the default retry classifier. Retrying while a circuit breaker
+// is open would only fail again.
+func isRetryable(err error) bool {
+	return !errors.Is(err, ErrCircuitOpen)
+}
+
+func retryingDefaultOptions() *retryingOptions {
+	return &retryingOptions{
+		maxAttempts: RetryingDefaultMaxAttempts,
+		backoff:     RetryingDefaultBackoff,
+		maxBackoff:  RetryingDefaultMaxBackoff,
+		jitter:      RetryingDefaultJitter,
+		isRetryable: isRetryable,
+	}
+}
+
+func retryingCompileOptions(opts ...RetryingOption) *retryingOptions {
+	o := retryingDefaultOptions()
+
+	for _, opt := range opts {
+		opt(o)
+	}
+
+	return o
+}
+
+type RetryingOption func(*retryingOptions)
+
+// RetryingWithMaxAttempts configures the retrying repository to fetch each key
+// at most N times, including the first attempt.
+func RetryingWithMaxAttempts(n int) RetryingOption {
+	return func(o *retryingOptions) {
+		o.maxAttempts = n
+	}
+}
+
+// RetryingWithBackoff configures the retrying repository to wait backoff before
+// the first retry, doubling the wait before every following retry, up to maxBackoff.
+func RetryingWithBackoff(backoff time.Duration, maxBackoff time.Duration) RetryingOption {
+	return func(o *retryingOptions) {
+		o.backoff = backoff
+		o.maxBackoff = maxBackoff
+	}
+}
+
+// RetryingWithJitter configures the retrying repository to shorten every wait
+// by a random fraction of it, up to the specified fraction (e.g. 0.5), so callers
+// that failed together don't retry together. Zero disables jitter.
+func RetryingWithJitter(jitter float64) RetryingOption {
+	return func(o *retryingOptions) {
+		o.jitter = jitter
+	}
+}
+
+// RetryingWithAttemptTimeout configures the retrying repository to timeout
+// each attempt after the specified amount of time, so a hanging attempt
+// doesn't use up the whole deadline of the caller. Attempts never outlive the
+// caller's context. Zero disables the timeout.
+func RetryingWithAttemptTimeout(timeout time.Duration) RetryingOption {
+	return func(o *retryingOptions) {
+		o.attemptTimeout = timeout
+	}
+}
+
+// RetryingWithRetryClassifier configures the retrying repository to retry only
+// errors classified as retryable by the specified classifier.
+// ErrNotFound is never retried. By default, all other errors but ErrCircuitOpen are retried.
+func RetryingWithRetryClassifier(isRetryable func(err error) bool) RetryingOption {
+	return func(o *retryingOptions) {
+		o.isRetryable = isRetryable
+	}
+}
+
+// RetryingWithNotFoundClassifier configures the retrying repository to treat errors
+// classified as not found by the specified classifier like ErrNotFound, in addition to it.
+// Such errors are never retried, and are returned wrapping ErrNotFound.
+func RetryingWithNotFoundClassifier(isNotFound NotFoundClassifier) RetryingOption {
+	return func(o *retryingOptions) {
+		o.isNotFound = isNotFound
+	}
+}
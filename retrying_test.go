@@ -0,0 +1,231 @@
+package cachehit
+
+import (
+	"context"
+	"errors"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+// failingRepo fails the first N calls with the specified error
+func failingRepo(calls *atomic.Int32, failures int32, err error) Repository[string, string] {
+	return repoFunc[string, string](func(ctx context.Context, key string) (string, error) {
+		if calls.Add(1) <= failures {
+			return "", err
+		}
+		return "value", nil
+	})
+}
+
+func Test_Retrying_New_WithInvalidOptions(t *testing.T) {
+	repo := &mockRepo[string, string]{}
+
+	_, err := NewRetrying[string, string](nil)
+	require.ErrorContains(t, err, "repo")
+
+	_, err = NewRetrying(repo, RetryingWithMaxAttempts(0))
+	require.ErrorContains(t, err, "max attempts")
+
+	_, err = NewRetrying(repo, RetryingWithBackoff(0, time.Second))
+	require.ErrorContains(t, err, "backoff")
+
+	_, err = NewRetrying(repo, RetryingWithBackoff(time.Second, time.Millisecond))
+	require.ErrorContains(t, err, "max backoff")
+
+	_, err = NewRetrying(repo, RetryingWithJitter(2))
+	require.ErrorContains(t, err, "jitter")
+
+	_, err = NewRetrying(repo, RetryingWithAttemptTimeout(-time.Second))
+	require.ErrorContains(t, err, "attempt timeout")
+
+	_, err = NewRetrying(repo, RetryingWithRetryClassifier(nil))
+	require.ErrorContains(t, err, "classifier")
+}
+
+func Test_Retrying_Get_Success(t *testing.T) {
+	ctx := t.Context()
+
+	var calls atomic.Int32
+	repo := failingRepo(&calls, 2, errors.New("failure"))
+
+	retrying, err := NewRetrying(repo, RetryingWithBackoff(time.Millisecond, time.Millisecond))
+	require.NoError(t, err)
+
+	value, err := retrying.Get(ctx, "key")
+	require.NoError(t, err)
+	require.Equal(t, "value", value)
+	require.Equal(t, int32(3), calls.Load())
+}
+
+func Test_Retrying_Get_MaxAttempts(t *testing.T) {
+	ctx := t.Context()
+
+	repoErr := errors.New("failure")
+
+	var calls atomic.Int32
+	repo := failingRepo(&calls, 10, repoErr)
+
+	retrying, err := NewRetrying(repo,
+		RetryingWithMaxAttempts(4),
+		RetryingWithBackoff(time.Millisecond, time.Millisecond))
+	require.NoError(t, err)
+
+	_, err = retrying.Get(ctx, "key")
+	require.ErrorIs(t, err, repoErr)
+	require.Equal(t, int32(4), calls.Load())
+}
+
+func Test_Retrying_Get_NotRetried(t *testing.T) {
+	ctx := t.Context()
+
+	nonRetryableErr := errors.New("bad request")
+
+	tests := []struct {
+		name string
+		err  error
+	}{
+		{"NotFound", ErrNotFound},
+		{"WrappedNotFound", errors.Join(errors.New("missing"), ErrNotFound)},
+		{"CircuitOpen", ErrCircuitOpen},
+		{"NonRetryable", nonRetryableErr},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			var calls atomic.Int32
+			repo := failingRepo(&calls, 10, test.err)
+
+			retrying, err := NewRetrying(repo,
+				RetryingWithBackoff(time.Millisecond, time.Millisecond),
+				RetryingWithRetryClassifier(func(err error) bool {
+					return !errors.Is(err, nonRetryableErr) && !errors.Is(err, ErrCircuitOpen)
+				}))
+			require.NoError(t, err)
+
+			_, err = retrying.Get(ctx, "key")
+			require.ErrorIs(t, err, test.err)
+			require.Equal(t, int32(1), calls.Load())
+		})
+	}
+}
+
+func Test_Retrying_Get_NotFoundWithClassifier(t *testing.T) {
+	ctx := t.Context()
+
+	var calls atomic.Int32
+	repo := failingRepo(&calls, 10, ErrNotFound)
+
+	retrying, err := NewRetrying(repo,
+		RetryingWithRetryClassifier(func(error) bool { return true }))
+	require.NoError(t, err)
+
+	_, err = retrying.Get(ctx, "key")
+	require.ErrorIs(t, err, ErrNotFound)
+	require.Equal(t, int32(1), calls.Load())
+}
+
+func Test_Retrying_Get_CustomNotFound(t *testing.T) {
+	ctx := t.Context()
+
+	errNoRows := errors.New("no rows")
+
+	var calls atomic.Int32
+	repo := failingRepo(&calls, 10, errNoRows)
+
+	retrying, err := NewRetrying(repo,
+		RetryingWithNotFoundClassifier(func(err error) bool {
+			return errors.Is(err, errNoRows)
+		}))
+	require.NoError(t, err)
+
+	_, err = retrying.Get(ctx, "key")
+	require.ErrorIs(t, err, ErrNotFound)
+	require.ErrorIs(t, err, errNoRows)
+	require.Equal(t, int32(1), calls.Load())
+}
+
+func Test_Retrying_Get_AttemptTimeout(t *testing.T) {
+	ctx := t.Context()
+
+	var calls atomic.Int32
+	repo := repoFunc[string, string](func(ctx context.Context, key string) (string, error) {
+		if calls.Add(1) == 1 {
+			// Hangs until the attempt times out
+			<-ctx.Done()
+			return "", ctx.Err()
+		}
+		return "value", nil
+	})
+
+	retrying, err := NewRetrying(repo,
+		RetryingWithBackoff(time.Millisecond, time.Millisecond),
+		RetryingWithAttemptTimeout(10*time.Millisecond))
+	require.NoError(t, err)
+
+	value, err := retrying.Get(ctx, "key")
+	require.NoError(t, err)
+	require.Equal(t, "value", value)
+	require.Equal(t, int32(2), calls.Load())
+}
+
+func Test_Retrying_Get_Deadline(t *testing.T) {
+	repoErr := errors.New("failure")
+
+	var calls atomic.Int32
+	repo := failingRepo(&calls, 10, repoErr)
+
+	retrying, err := NewRetrying(repo,
+		RetryingWithBackoff(time.Second, time.Second),
+		RetryingWithJitter(0))
+	require.NoError(t, err)
+
+	ctx, cancel := context.WithTimeout(t.Context(), 100*time.Millisecond)
+	defer cancel()
+
+	// The retry can't start before the deadline
+	start := time.Now()
+	_, err = retrying.Get(ctx, "key")
+	require.ErrorIs(t, err, repoErr)
+	require.Equal(t, int32(1), calls.Load())
+	require.Less(t, time.Since(start), 50*time.Millisecond)
+}
+
+func Test_Retrying_Get_ContextCanceled(t *testing.T) {
+	repoErr := errors.New("failure")
+
+	var calls atomic.Int32
+	repo := failingRepo(&calls, 10, repoErr)
+
+	retrying, err := NewRetrying(repo, RetryingWithBackoff(time.Second, time.Second))
+	require.NoError(t, err)
+
+	ctx, cancel := context.WithCancel(t.Context())
+	time.AfterFunc(10*time.Millisecond, cancel)
+
+	_, err = retrying.Get(ctx, "key")
+	require.ErrorIs(t, err, repoErr)
+	require.Equal(t, int32(1), calls.Load())
+}
+
+func Test_Retrying_Wait(t *testing.T) {
+	retrying, err := NewRetrying[string, string](&mockRepo[string, string]{},
+		RetryingWithBackoff(10*time.Millisecond, 50*time.Millisecond),
+		RetryingWithJitter(0))
+	require.NoError(t, err)
+
+	require.Equal(t, 10*time.Millisecond, retrying.wait(1))
+	require.Equal(t, 20*time.Millisecond, retrying.wait(2))
+	require.Equal(t, 40*time.Millisecond, retrying.wait(3))
+	require.Equal(t, 50*time.Millisecond, retrying.wait(4))
+	require.Equal(t, 50*time.Millisecond, retrying.wait(100))
+
+	retrying.jitter = 0.5
+	for retry := range 10 {
+		wait := retrying.wait(retry + 1)
+		require.GreaterOrEqual(t, wait, 5*time.Millisecond)
+		require.LessOrEqual(t, wait, 50*time.Millisecond)
+	}
+}
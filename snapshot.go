@@ -0,0 +1,234 @@
+package cachehit
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"time"
+
+	"github.com/dtrugman/cachehit/codec"
+)
+
+// snapshotMagic starts every snapshot, followed by the format version.
+var snapshotMagic = []byte("CHSWR")
+
+const snapshotVersion = 1
+
+// snapshotMaxFieldSize limits the size of keys and values read from snapshots,
+// so corrupt snapshots can't cause huge allocations.
+const snapshotMaxFieldSize = 1 << 28
+
+func snapshotCodecs[K comparable, V any](o *snapshotOptions) (codec.Codec[K], codec.Codec[V], error) {
+	keyCodec := codec.Default[K]()
+	if o.keyCodec != nil {
+		c, ok := o.keyCodec.(codec.Codec[K])
+		if !ok {
+			var k K
+			return nil, nil, fmt.Errorf("options: key codec: expected codec for %T: found %T", k, o.keyCodec)
+		}
+		keyCodec = c
+	}
+
+	valueCodec := codec.Default[V]()
+	if o.valueCodec != nil {
+		c, ok := o.valueCodec.(codec.Codec[V])
+		if !ok {
+			var v V
+			return nil, nil, fmt.Errorf("options: value codec: expected codec for %T: found %T", v, o.valueCodec)
+		}
+		valueCodec = c
+	}
+
+	return keyCodec, valueCodec, nil
+}
+
+// Snapshot writes all entries, along with their stale and dead times, to the specified writer,
+// e.g. to persist the cache at shutdown and Restore it once the process starts again.
+// Requires the cache to implement Ranger, as the in-memory cache created by NewSWR does.
+func (c *SWR[K, V]) Snapshot(ctx context.Context, w io.Writer, opts ...SnapshotOption) error {
+	ranger, ok := c.cache.(Ranger[K, *SWREntry[V]])
+	if !ok {
+		return fmt.Errorf("cache range: not supported")
+	}
+
+	o := snapshotCompileOptions(opts...)
+	if err := o.Validate(); err != nil {
+		return fmt.Errorf("options: %w", err)
+	}
+
+	keyCodec, valueCodec, err := snapshotCodecs[K, V](o)
+	if err != nil {
+		return err
+	}
+
+	bw := bufio.NewWriter(w)
+
+	header := append(bytes.Clone(snapshotMagic), snapshotVersion)
+	if _, err := bw.Write(header); err != nil {
+		return fmt.Errorf("write: %w", err)
+	}
+
+	var writeErr error
+	buf := make([]byte, 0, 256)
+	err = ranger.Range(ctx, func(key K, entry *SWREntry[V]) bool {
+		rawKey, err := keyCodec.Encode(key)
+		if err != nil {
+			writeErr = fmt.Errorf("marshal key: %v: %w", key, err)
+			return false
+		}
+
+		rawValue, err := valueCodec.Encode(entry.Value)
+		if err != nil {
+			writeErr = fmt.Errorf("marshal value: %v: %w", key, err)
+			return false
+		}
+
+		buf = binary.AppendUvarint(buf[:0], uint64(len(rawKey)))
+		buf = append(buf, rawKey...)
+		buf = binary.AppendVarint(buf, entry.StaleAt.UnixNano())
+		buf = binary.AppendVarint(buf, entry.DeadAt.UnixNano())
+		buf = binary.AppendUvarint(buf, uint64(len(rawValue)))
+		buf = append(buf, rawValue...)
+
+		if _, err := bw.Write(buf); err != nil {
+			writeErr = fmt.Errorf("write: %w", err)
+			return false
+		}
+
+		return ctx.Err() == nil
+	})
+	if err != nil {
+		return fmt.Errorf("cache range: %w", err)
+	} else if writeErr != nil {
+		return writeErr
+	} else if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	if err := bw.Flush(); err != nil {
+		return fmt.Errorf("write: %w", err)
+	}
+
+	return nil
+}
+
+func readSnapshotField(r *bufio.Reader) ([]byte, error) {
+	size, err := binary.ReadUvarint(r)
+	if err != nil {
+		return nil, err
+	}
+
+	if size > snapshotMaxFieldSize {
+		return nil, fmt.Errorf("field size: %d: exceeds limit", size)
+	}
+
+	field := make([]byte, size)
+	if _, err := io.ReadFull(r, field); err != nil {
+		return nil, unexpectedEOF(err)
+	}
+
+	return field, nil
+}
+
+// unexpectedEOF converts io.EOF, returned when a snapshot ends in the middle of an entry.
+func unexpectedEOF(err error) error {
+	if errors.Is(err, io.EOF) {
+		return io.ErrUnexpectedEOF
+	}
+	return err
+}
+
+// Restore reads the entries written by Snapshot into the cache. Entries that became
+// stale while the snapshot was stored are restored as stale, and entries that died
+// are handled according to SnapshotWithRestoreDeadMode.
+// Snapshots must be restored using the same codecs they were written with.
+func (c *SWR[K, V]) Restore(ctx context.Context, r io.Reader, opts ...SnapshotOption) error {
+	o := snapshotCompileOptions(opts...)
+	if err := o.Validate(); err != nil {
+		return fmt.Errorf("options: %w", err)
+	}
+
+	keyCodec, valueCodec, err := snapshotCodecs[K, V](o)
+	if err != nil {
+		return err
+	}
+
+	br := bufio.NewReader(r)
+
+	header := make([]byte, len(snapshotMagic)+1)
+	if _, err := io.ReadFull(br, header); err != nil {
+		return fmt.Errorf("read header: %w", unexpectedEOF(err))
+	}
+
+	if !bytes.Equal(header[:len(snapshotMagic)], snapshotMagic) {
+		return fmt.Errorf("read header: not a snapshot")
+	} else if version := header[len(snapshotMagic)]; version != snapshotVersion {
+		return fmt.Errorf("read header: unsupported version: %d", version)
+	}
+
+	for {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+
+		rawKey, err := readSnapshotField(br)
+		if errors.Is(err, io.EOF) {
+			return nil
+		} else if err != nil {
+			return fmt.Errorf("read key: %w", unexpectedEOF(err))
+		}
+
+		staleAt, err := binary.ReadVarint(br)
+		if err != nil {
+			return fmt.Errorf("read stale at: %w", unexpectedEOF(err))
+		}
+
+		deadAt, err := binary.ReadVarint(br)
+		if err != nil {
+			return fmt.Errorf("read dead at: %w", unexpectedEOF(err))
+		}
+
+		rawValue, err := readSnapshotField(br)
+		if err != nil {
+			return fmt.Errorf("read value: %w", unexpectedEOF(err))
+		}
+
+		key, err := keyCodec.Decode(rawKey)
+		if err != nil {
+			return fmt.Errorf("parse key: %w", err)
+		}
+
+		value, err := valueCodec.Decode(rawValue)
+		if err != nil {
+			return fmt.Errorf("parse value: %v: %w", key, err)
+		}
+
+		entry := &SWREntry[V]{
+			Value:   value,
+			StaleAt: time.Unix(0, staleAt),
+			DeadAt:  time.Unix(0, deadAt),
+		}
+
+		now := time.Now()
+		if !now.Before(entry.DeadAt) {
+			if o.restoreDeadMode != RestoreDeadAsStale {
+				continue
+			}
+
+			// Stale for as long as fresh entries are
+			entry.StaleAt = now
+			entry.DeadAt = now.Add(c.timeToDead - c.timeToStale)
+			if !now.Before(entry.DeadAt) {
+				continue
+			}
+		}
+
+		if err := c.cache.Set(ctx, key, entry); err != nil {
+			return fmt.Errorf("cache set: %v: %w", key, err)
+		}
+	}
+}
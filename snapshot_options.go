@@ -0,0 +1,75 @@
+package cachehit
+
+import (
+	"fmt"
+
+	"github.com/dtrugman/cachehit/codec"
+)
+
+type RestoreDeadMode int
+
+const (
+	// RestoreDeadDrop skips entries that died while the snapshot was stored.
+	RestoreDeadDrop RestoreDeadMode = iota
+
+	// RestoreDeadAsStale restores entries that died while the snapshot was stored as stale,
+	// so they are served while being refreshed in the background.
+	RestoreDeadAsStale
+)
+
+type snapshotOptions struct {
+	keyCodec   any
+	valueCodec any
+
+	restoreDeadMode RestoreDeadMode
+}
+
+func (o *snapshotOptions) Validate() error {
+	if o.restoreDeadMode != RestoreDeadDrop && o.restoreDeadMode != RestoreDeadAsStale {
+		return fmt.Errorf("unknown restore dead mode: %d", o.restoreDeadMode)
+	}
+
+	return nil
+}
+
+func snapshotDefaultOptions() *snapshotOptions {
+	return &snapshotOptions{
+		restoreDeadMode: RestoreDeadDrop,
+	}
+}
+
+func snapshotCompileOptions(opts ...SnapshotOption) *snapshotOptions {
+	o := snapshotDefaultOptions()
+
+	for _, opt := range opts {
+		opt(o)
+	}
+
+	return o
+}
+
+type SnapshotOption func(*snapshotOptions)
+
+// SnapshotWithKeyCodec configures the snapshot to serialize keys using the specified codec.
+// The codec type must match the cache key type. Defaults to codec.Default.
+func SnapshotWithKeyCodec[K comparable](c codec.Codec[K]) SnapshotOption {
+	return func(o *snapshotOptions) {
+		o.keyCodec = c
+	}
+}
+
+// SnapshotWithValueCodec configures the snapshot to serialize values using the specified codec.
+// The codec type must match the cache value type. Defaults to codec.Default.
+func SnapshotWithValueCodec[V any](c codec.Codec[V]) SnapshotOption {
+	return func(o *snapshotOptions) {
+		o.valueCodec = c
+	}
+}
+
+// SnapshotWithRestoreDeadMode configures how Restore handles entries that died
+// while the snapshot was stored. Defaults to RestoreDeadDrop.
+func SnapshotWithRestoreDeadMode(mode RestoreDeadMode) SnapshotOption {
+	return func(o *snapshotOptions) {
+		o.restoreDeadMode = mode
+	}
+}
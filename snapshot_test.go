@@ -0,0 +1,266 @@
+package cachehit
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/dtrugman/cachehit/codec"
+)
+
+func newSnapshotSWR(t *testing.T, timeToStale, timeToDead time.Duration) *SWR[string, string] {
+	t.Helper()
+
+	repo := &mockRepo[string, string]{}
+
+	swr, err := NewSWR(16, repo, timeToStale, timeToDead)
+	require.NoError(t, err)
+	return swr
+}
+
+func Test_SWR_Snapshot_RoundTrip(t *testing.T) {
+	ctx := t.Context()
+
+	repo := &mockRepo[string, string]{}
+	repo.On("Get", ctx, "key1").Return("value1", nil).Once()
+	repo.On("Get", ctx, "key2").Return("value2", nil).Once()
+
+	swr, err := NewSWR(16, repo, time.Minute, 2*time.Minute)
+	require.NoError(t, err)
+
+	for _, key := range []string{"key1", "key2"} {
+		_, err := swr.Get(ctx, key)
+		require.NoError(t, err)
+	}
+
+	var buf bytes.Buffer
+	require.NoError(t, swr.Snapshot(ctx, &buf))
+
+	restored := newSnapshotSWR(t, time.Minute, 2*time.Minute)
+	require.NoError(t, restored.Restore(ctx, &buf))
+
+	for key, expected := range map[string]string{"key1": "value1", "key2": "value2"} {
+		original, err := swr.cache.Get(ctx, key)
+		require.NoError(t, err)
+
+		entry, err := restored.cache.Get(ctx, key)
+		require.NoError(t, err)
+		require.Equal(t, expected, entry.Value)
+		require.True(t, original.StaleAt.Equal(entry.StaleAt))
+		require.True(t, original.DeadAt.Equal(entry.DeadAt))
+
+		// Served from the cache, the repo isn't called again
+		value, err := restored.Get(ctx, key)
+		require.NoError(t, err)
+		require.Equal(t, expected, value)
+	}
+
+	repo.AssertExpectations(t)
+}
+
+func Test_SWR_Snapshot_Codecs(t *testing.T) {
+	ctx := t.Context()
+
+	type user struct {
+		Name string
+	}
+
+	repo := &mockRepo[int, user]{}
+
+	swr, err := NewSWR(16, repo, time.Minute, 2*time.Minute)
+	require.NoError(t, err)
+
+	now := time.Now()
+	entry := &SWREntry[user]{Value: user{Name: "name"}, StaleAt: now.Add(time.Minute), DeadAt: now.Add(time.Hour)}
+	require.NoError(t, swr.cache.Set(ctx, 42, entry))
+
+	opts := []SnapshotOption{
+		SnapshotWithKeyCodec(codec.Gob[int]()),
+		SnapshotWithValueCodec(codec.Gob[user]()),
+	}
+
+	var buf bytes.Buffer
+	require.NoError(t, swr.Snapshot(ctx, &buf, opts...))
+
+	restored, err := NewSWR(16, repo, time.Minute, 2*time.Minute)
+	require.NoError(t, err)
+	require.NoError(t, restored.Restore(ctx, &buf, opts...))
+
+	actual, err := restored.cache.Get(ctx, 42)
+	require.NoError(t, err)
+	require.Equal(t, entry.Value, actual.Value)
+}
+
+func Test_SWR_Snapshot_Expired(t *testing.T) {
+	ctx := t.Context()
+
+	timeToStale := time.Minute
+	timeToDead := 3 * time.Minute
+
+	swr := newSnapshotSWR(t, timeToStale, timeToDead)
+	require.NoError(t, swr.cache.Set(ctx, "alive", makeAliveEntry("alive")))
+	require.NoError(t, swr.cache.Set(ctx, "stale", makeStaleEntry("stale")))
+	require.NoError(t, swr.cache.Set(ctx, "dead", makeDeadEntry("dead")))
+
+	var buf bytes.Buffer
+	require.NoError(t, swr.Snapshot(ctx, &buf))
+	snapshot := buf.Bytes()
+
+	t.Run("Drop", func(t *testing.T) {
+		restored := newSnapshotSWR(t, timeToStale, timeToDead)
+		require.NoError(t, restored.Restore(ctx, bytes.NewReader(snapshot)))
+
+		for _, key := range []string{"alive", "stale"} {
+			_, err := restored.cache.Get(ctx, key)
+			require.NoError(t, err)
+		}
+
+		_, err := restored.cache.Get(ctx, "dead")
+		require.ErrorIs(t, err, ErrNotFound)
+	})
+
+	t.Run("AsStale", func(t *testing.T) {
+		restored := newSnapshotSWR(t, timeToStale, timeToDead)
+		require.NoError(t, restored.Restore(ctx, bytes.NewReader(snapshot),
+			SnapshotWithRestoreDeadMode(RestoreDeadAsStale)))
+
+		now := time.Now()
+
+		entry, err := restored.cache.Get(ctx, "dead")
+		require.NoError(t, err)
+		require.Equal(t, "dead", entry.Value)
+		require.False(t, now.Before(entry.StaleAt))
+		require.True(t, now.Before(entry.DeadAt))
+		require.True(t, entry.DeadAt.Before(now.Add(timeToDead-timeToStale)))
+	})
+}
+
+func Test_SWR_Snapshot_NotSupported(t *testing.T) {
+	ctx := t.Context()
+
+	cache := &mockCache[string, *SWREntry[string]]{}
+	repo := &mockRepo[string, string]{}
+
+	swr, err := newSWR(repo, cache, time.Minute, 2*time.Minute, &sync.Map{})
+	require.NoError(t, err)
+
+	err = swr.Snapshot(ctx, io.Discard)
+	require.ErrorContains(t, err, "not supported")
+}
+
+func Test_SWR_Snapshot_WriteError(t *testing.T) {
+	ctx := t.Context()
+
+	swr := newSnapshotSWR(t, time.Minute, 2*time.Minute)
+	require.NoError(t, swr.cache.Set(ctx, "key", makeAliveEntry("value")))
+
+	writeErr := errors.New("failed")
+	err := swr.Snapshot(ctx, failingWriter{err: writeErr})
+	require.ErrorIs(t, err, writeErr)
+}
+
+func Test_SWR_Snapshot_InvalidOptions(t *testing.T) {
+	ctx := t.Context()
+
+	swr := newSnapshotSWR(t, time.Minute, 2*time.Minute)
+
+	t.Run("key codec type mismatch", func(t *testing.T) {
+		err := swr.Snapshot(ctx, io.Discard, SnapshotWithKeyCodec(codec.Default[int]()))
+		require.ErrorContains(t, err, "key codec")
+
+		err = swr.Restore(ctx, bytes.NewReader(nil), SnapshotWithKeyCodec(codec.Default[int]()))
+		require.ErrorContains(t, err, "key codec")
+	})
+
+	t.Run("value codec type mismatch", func(t *testing.T) {
+		err := swr.Snapshot(ctx, io.Discard, SnapshotWithValueCodec(codec.Default[int]()))
+		require.ErrorContains(t, err, "value codec")
+	})
+
+	t.Run("unknown restore dead mode", func(t *testing.T) {
+		err := swr.Restore(ctx, bytes.NewReader(nil), SnapshotWithRestoreDeadMode(RestoreDeadMode(42)))
+		require.ErrorContains(t, err, "unknown restore dead mode")
+	})
+}
+
+func Test_SWR_Restore_Invalid(t *testing.T) {
+	ctx := t.Context()
+
+	swr := newSnapshotSWR(t, time.Minute, 2*time.Minute)
+	require.NoError(t, swr.cache.Set(ctx, "key", makeAliveEntry("value")))
+
+	var buf bytes.Buffer
+	require.NoError(t, swr.Snapshot(ctx, &buf))
+	snapshot := buf.Bytes()
+
+	t.Run("Empty", func(t *testing.T) {
+		restored := newSnapshotSWR(t, time.Minute, 2*time.Minute)
+
+		err := restored.Restore(ctx, bytes.NewReader(nil))
+		require.ErrorIs(t, err, io.ErrUnexpectedEOF)
+	})
+
+	t.Run("NotSnapshot", func(t *testing.T) {
+		restored := newSnapshotSWR(t, time.Minute, 2*time.Minute)
+
+		err := restored.Restore(ctx, bytes.NewReader([]byte("not a snapshot")))
+		require.ErrorContains(t, err, "not a snapshot")
+	})
+
+	t.Run("UnsupportedVersion", func(t *testing.T) {
+		restored := newSnapshotSWR(t, time.Minute, 2*time.Minute)
+
+		header := append(bytes.Clone(snapshotMagic), snapshotVersion+1)
+		err := restored.Restore(ctx, bytes.NewReader(header))
+		require.ErrorContains(t, err, "unsupported version")
+	})
+
+	t.Run("Truncated", func(t *testing.T) {
+		for size := len(snapshotMagic) + 2; size < len(snapshot); size++ {
+			restored := newSnapshotSWR(t, time.Minute, 2*time.Minute)
+
+			err := restored.Restore(ctx, bytes.NewReader(snapshot[:size]))
+			require.ErrorIs(t, err, io.ErrUnexpectedEOF)
+		}
+	})
+
+	t.Run("HugeField", func(t *testing.T) {
+		restored := newSnapshotSWR(t, time.Minute, 2*time.Minute)
+
+		data := append(bytes.Clone(snapshotMagic), snapshotVersion, 0xff, 0xff, 0xff, 0xff, 0x7f)
+		err := restored.Restore(ctx, bytes.NewReader(data))
+		require.ErrorContains(t, err, "exceeds limit")
+	})
+
+	t.Run("ParseError", func(t *testing.T) {
+		restored, err := NewSWR(16, &mockRepo[string, int]{}, time.Minute, 2*time.Minute)
+		require.NoError(t, err)
+
+		err = restored.Restore(ctx, bytes.NewReader(snapshot))
+		require.ErrorContains(t, err, "parse value")
+	})
+
+	t.Run("Canceled", func(t *testing.T) {
+		restored := newSnapshotSWR(t, time.Minute, 2*time.Minute)
+
+		canceledCtx, cancel := context.WithCancel(ctx)
+		cancel()
+
+		err := restored.Restore(canceledCtx, bytes.NewReader(snapshot))
+		require.ErrorIs(t, err, context.Canceled)
+	})
+}
+
+type failingWriter struct {
+	err error
+}
+
+func (w failingWriter) Write(p []byte) (int, error) {
+	return 0, w.err
+}
@@ -13,14 +13,8 @@ import (
 	"golang.org/x/sync/singleflight"
 )
 
-type entry[V any] struct {
-	staleAt time.Time
-	deadAt  time.Time
-	value   V
-}
-
 type SWR[K comparable, V any] struct {
-	cache Cache[K, *entry[V]]
+	cache Cache[K, *SWREntry[V]]
 	repo  Repository[K, V]
 
 	timeToStale time.Duration
@@ -32,12 +26,22 @@ type SWR[K comparable, V any] struct {
 	refreshTimeout time.Duration
 	refreshKeys    syncMap
 
+	invalidationBus  InvalidationBus
+	invalidationMode InvalidationMode
+	invalidations    *invalidations[K]
+
+	guard *keyGuard[K]
+
+	refreshLease Lease
+
+	isNotFound NotFoundClassifier
+
 	errorCallback ErrorCallback
 }
 
 func newSWR[K comparable, V any](
 	repo Repository[K, V],
-	cache Cache[K, *entry[V]],
+	cache Cache[K, *SWREntry[V]],
 	timeToStale time.Duration,
 	timeToDead time.Duration,
 	syncMap syncMap,
@@ -81,9 +85,23 @@ func newSWR[K comparable, V any](
 		refreshTimeout: o.refreshTimeout,
 		refreshKeys:    syncMap,
 
+		invalidationBus:  o.invalidationBus,
+		invalidationMode: o.invalidationMode,
+		invalidations:    newInvalidations[K](),
+
+		guard: newKeyGuard[K](),
+
+		refreshLease: o.refreshLease,
+
+		isNotFound: o.isNotFound,
+
 		errorCallback: o.errorCallback,
 	}
 
+	if swr.invalidationBus != nil {
+		swr.invalidationBus.Subscribe(swr.onInvalidation)
+	}
+
 	for range o.refreshWorkers {
 		go swr.refreshWorker()
 	}
@@ -98,7 +116,7 @@ func NewSWR[K comparable, V any](
 	timeToDead time.Duration,
 	opts ...SWROption,
 ) (*SWR[K, V], error) {
-	cache, err := lru.New[K, *entry[V]](cacheSize)
+	cache, err := lru.New[K, *SWREntry[V]](cacheSize)
 	if err != nil {
 		return nil, fmt.Errorf("cache: %w", err)
 	}
@@ -109,11 +127,28 @@ func NewSWR[K comparable, V any](
 	return newSWR(repo, adapter, timeToStale, timeToDead, syncMap, opts...)
 }
 
+// NewSWRFromCache creates a new SWR cache over the specified cache, instead of
+// a private in-memory one. When the cache is shared between processes (e.g. Redis),
+// the stale and dead times are shared as well, see SWRWithRefreshLease.
+func NewSWRFromCache[K comparable, V any](
+	cache Cache[K, *SWREntry[V]],
+	repo Repository[K, V],
+	timeToStale time.Duration,
+	timeToDead time.Duration,
+	opts ...SWROption,
+) (*SWR[K, V], error) {
+	syncMap := &sync.Map{}
+
+	return newSWR(repo, cache, timeToStale, timeToDead, syncMap, opts...)
+}
+
 func (c *SWR[K, V]) refreshWorker() {
 	for key := range c.refreshChan {
 		ctx, cancel := context.WithTimeout(context.Background(), c.refreshTimeout)
 
-		if _, err := c.get(ctx, key); err != nil {
+		if c.refreshLease != nil {
+			c.refreshWithLease(ctx, key)
+		} else if _, err := c.get(ctx, key); c.refreshFailed(err) {
 			c.reportError(fmt.Errorf("refresh: %v: %w", key, err))
 		}
 
@@ -123,6 +158,34 @@ func (c *SWR[K, V]) refreshWorker() {
 	}
 }
 
+func (c *SWR[K, V]) refreshWithLease(ctx context.Context, key K) {
+	release, acquired, err := c.refreshLease.Acquire(ctx, fmt.Sprintf("%v", key))
+	if c.notFound(err) || errors.Is(err, ErrLeaseFailed) {
+		// Another process just failed to refresh the key, keep serving the stale value
+		return
+	} else if err != nil {
+		c.reportError(fmt.Errorf("lease acquire: %v: %w", key, err))
+		release = func(error) {}
+	} else if !acquired {
+		// Another process is refreshing the key, keep serving the stale value
+		return
+	} else {
+		// The previous holder might have just refreshed the key
+		entry, err := c.cache.Get(ctx, key)
+		if err == nil && time.Now().Before(entry.StaleAt) {
+			release(nil)
+			return
+		}
+	}
+
+	_, err = c.get(ctx, key)
+	release(err)
+
+	if c.refreshFailed(err) {
+		c.reportError(fmt.Errorf("refresh: %v: %w", key, err))
+	}
+}
+
 func (c *SWR[K, V]) refreshKey(key K) {
 	if _, exists := c.refreshKeys.LoadOrStore(key, struct{}{}); exists {
 		return
@@ -144,27 +207,118 @@ func (c *SWR[K, V]) reportError(err error) {
 	}
 }
 
+func (c *SWR[K, V]) notFound(err error) bool {
+	return isNotFound(err, c.isNotFound)
+}
+
+// refreshFailed reports whether a refresh error should be reported.
+// Refreshes rejected by an open circuit aren't failures.
+func (c *SWR[K, V]) refreshFailed(err error) bool {
+	return err != nil && !errors.Is(err, ErrCircuitOpen)
+}
+
+func (c *SWR[K, V]) invalidate(ctx context.Context, key K) {
+	// Make sure fetches that started before the invalidation don't store their values,
+	// and that later calls don't join them
+	c.guard.invalidate(key)
+	c.dedup.Forget(fmt.Sprintf("%v", key))
+
+	if c.invalidationMode == InvalidationMarkStale {
+		current, err := c.cache.Get(ctx, key)
+		if c.notFound(err) {
+			return
+		} else if err != nil {
+			c.reportError(fmt.Errorf("cache get: %v: %w", key, err))
+			return
+		}
+
+		now := time.Now()
+		if !now.Before(current.StaleAt) {
+			return
+		}
+
+		stale := &SWREntry[V]{
+			StaleAt: now,
+			DeadAt:  current.DeadAt,
+			Value:   current.Value,
+		}
+		if err := c.cache.Set(ctx, key, stale); err != nil {
+			c.reportError(fmt.Errorf("cache set: %v: %w", key, err))
+		}
+		return
+	}
+
+	deleter, ok := c.cache.(Deleter[K])
+	if !ok {
+		c.reportError(fmt.Errorf("cache delete: %v: not supported", key))
+		return
+	}
+
+	if err := deleter.Delete(ctx, key); err != nil {
+		c.reportError(fmt.Errorf("cache delete: %v: %w", key, err))
+	}
+}
+
+func (c *SWR[K, V]) onInvalidation(message string) {
+	key, remote, err := c.invalidations.decode(message)
+	if err != nil {
+		c.reportError(fmt.Errorf("invalidation: %q: %w", message, err))
+		return
+	}
+
+	// Keys invalidated by this instance were already invalidated by Invalidate
+	if remote {
+		c.invalidate(context.Background(), key)
+	}
+}
+
+// Invalidate drops (or marks as stale, see SWRWithInvalidationBus) the specified key,
+// and if an invalidation bus is configured, broadcasts the invalidation to
+// all other instances sharing it.
+func (c *SWR[K, V]) Invalidate(ctx context.Context, key K) error {
+	c.invalidate(ctx, key)
+
+	if c.invalidationBus != nil {
+		message, err := c.invalidations.encode(key)
+		if err != nil {
+			return fmt.Errorf("encode: %v: %w", key, err)
+		}
+
+		if err := c.invalidationBus.Publish(ctx, message); err != nil {
+			return fmt.Errorf("publish: %w", err)
+		}
+	}
+
+	return nil
+}
+
 func (c *SWR[K, V]) get(ctx context.Context, key K) (V, error) {
 	k := fmt.Sprintf("%v", key)
 	res, err, _ := c.dedup.Do(k, func() (interface{}, error) {
+		fetch := c.guard.begin(key)
+		defer c.guard.end(fetch)
+
 		value, err := c.repo.Get(ctx, key)
 		if err != nil {
-			return nil, fmt.Errorf("repo get: %w", err)
+			return nil, fmt.Errorf("repo get: %w", asNotFound(err, c.isNotFound))
 		}
 
 		now := time.Now()
 		staleAt := now.Add(c.timeToStale)
 		deadAt := now.Add(c.timeToDead)
 
-		entry := &entry[V]{
-			staleAt: staleAt,
-			deadAt:  deadAt,
-			value:   value,
+		entry := &SWREntry[V]{
+			StaleAt: staleAt,
+			DeadAt:  deadAt,
+			Value:   value,
 		}
 
-		if err := c.cache.Set(ctx, key, entry); err != nil {
-			c.reportError(fmt.Errorf("cache set: %v: %w", key, err))
-		}
+		// A value fetched before the key was invalidated is returned, but not cached
+		c.guard.store(fetch, func() {
+			if err := c.cache.Set(ctx, key, entry); err != nil {
+				c.reportError(fmt.Errorf("cache set: %v: %w", key, err))
+			}
+		})
 		return value, nil
 	})
 
@@ -184,7 +338,7 @@ func (c *SWR[K, V]) get(ctx context.Context, key K) (V, error) {
 
 func (c *SWR[K, V]) Get(ctx context.Context, key K) (V, error) {
 	entry, err := c.cache.Get(ctx, key)
-	if errors.Is(err, ErrNotFound) {
+	if c.notFound(err) {
 		return c.get(ctx, key)
 	} else if err != nil {
 		c.reportError(fmt.Errorf("cache get: %v: %w", key, err))
@@ -192,12 +346,17 @@ func (c *SWR[K, V]) Get(ctx context.Context, key K) (V, error) {
 	}
 
 	now := time.Now()
-	if now.Before(entry.staleAt) {
-		return entry.value, nil
-	} else if now.Before(entry.deadAt) {
+	if now.Before(entry.StaleAt) {
+		return entry.Value, nil
+	} else if now.Before(entry.DeadAt) {
 		c.refreshKey(key)
-		return entry.value, nil
+		return entry.Value, nil
 	} else {
-		return c.get(ctx, key)
+		value, err := c.get(ctx, key)
+		if errors.Is(err, ErrCircuitOpen) {
+			// The repository is unavailable, a dead value is better than none
+			return entry.Value, nil
+		}
+		return value, err
 	}
 }
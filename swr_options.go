@@ -16,6 +16,13 @@ type swrOptions struct {
 	refreshBufferSize int
 	refreshTimeout    time.Duration
 
+	invalidationBus  InvalidationBus
+	invalidationMode InvalidationMode
+
+	refreshLease Lease
+
+	isNotFound NotFoundClassifier
+
 	errorCallback ErrorCallback
 }
 
@@ -32,6 +39,10 @@ func (o *swrOptions) Validate() error {
 		return fmt.Errorf("timeout must be positive")
 	}
 
+	if o.invalidationMode != InvalidationDrop && o.invalidationMode != InvalidationMarkStale {
+		return fmt.Errorf("unknown invalidation mode: %d", o.invalidationMode)
+	}
+
 	return nil
 }
 
@@ -86,3 +97,35 @@ func SWRWithErrorCallback(errorCallback ErrorCallback) SWROption {
 		o.errorCallback = errorCallback
 	}
 }
+
+// SWRWithInvalidationBus configures the SWR cache to subscribe to the specified bus,
+// and either drop or mark as stale the keys invalidated by any other instance.
+// Keys are published encoded using the default codec of the key type.
+func SWRWithInvalidationBus(bus InvalidationBus, mode InvalidationMode) SWROption {
+	return func(o *swrOptions) {
+		o.invalidationBus = bus
+		o.invalidationMode = mode
+	}
+}
+
+// SWRWithRefreshLease configures the SWR cache to take the specified lease before
+// refreshing a stale key in the background. If another process holds the lease,
+// the refresh is skipped and the stale value keeps being served until the holder
+// updates the shared cache. Only useful with a cache shared between processes,
+// see NewSWRFromCache.
+func SWRWithRefreshLease(lease Lease) SWROption {
+	return func(o *swrOptions) {
+		o.refreshLease = lease
+	}
+}
+
+// SWRWithNotFoundClassifier configures the SWR cache to treat errors classified
+// as not found by the specified classifier like ErrNotFound, in addition to it.
+// Such cache errors are handled as clean misses without being reported, and such
+// repository errors are returned wrapping ErrNotFound. Like ErrNotFound, they're
+// still reported to the error callback when returned by background refreshes.
+func SWRWithNotFoundClassifier(isNotFound NotFoundClassifier) SWROption {
+	return func(o *swrOptions) {
+		o.isNotFound = isNotFound
+	}
+}
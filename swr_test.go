@@ -5,6 +5,7 @@ import (
 	"errors"
 	"reflect"
 	"sync"
+	"sync/atomic"
 	"testing"
 	"time"
 
@@ -12,7 +13,7 @@ import (
 	"github.com/stretchr/testify/require"
 )
 
-var nilEntry *entry[string]
+var nilEntry *SWREntry[string]
 
 func isTimeoutContext(ctx context.Context) bool {
 	ref, cancel := context.WithTimeout(context.Background(), time.Second)
@@ -20,41 +21,41 @@ func isTimeoutContext(ctx context.Context) bool {
 	return reflect.TypeOf(ctx) == reflect.TypeOf(ref)
 }
 
-func makeAliveEntry(value string) *entry[string] {
+func makeAliveEntry(value string) *SWREntry[string] {
 	now := time.Now()
-	return &entry[string]{
-		staleAt: now.Add(time.Hour),
-		deadAt:  now.Add(2 * time.Hour),
-		value:   value,
+	return &SWREntry[string]{
+		StaleAt: now.Add(time.Hour),
+		DeadAt:  now.Add(2 * time.Hour),
+		Value:   value,
 	}
 }
 
-func makeStaleEntry(value string) *entry[string] {
+func makeStaleEntry(value string) *SWREntry[string] {
 	now := time.Now()
-	return &entry[string]{
-		staleAt: now.Add(-time.Hour),
-		deadAt:  now.Add(time.Hour),
-		value:   value,
+	return &SWREntry[string]{
+		StaleAt: now.Add(-time.Hour),
+		DeadAt:  now.Add(time.Hour),
+		Value:   value,
 	}
 }
 
-func makeDeadEntry(value string) *entry[string] {
+func makeDeadEntry(value string) *SWREntry[string] {
 	now := time.Now()
-	return &entry[string]{
-		staleAt: now.Add(-2 * time.Hour),
-		deadAt:  now.Add(-time.Hour),
-		value:   value,
+	return &SWREntry[string]{
+		StaleAt: now.Add(-2 * time.Hour),
+		DeadAt:  now.Add(-time.Hour),
+		Value:   value,
 	}
 }
 
-type entryMatcher func(entry *entry[string]) bool
+type entryMatcher func(entry *SWREntry[string]) bool
 
 func getEntryMatcher(expected string, timeToStale, timeToDead time.Duration) entryMatcher {
-	return func(entry *entry[string]) bool {
+	return func(entry *SWREntry[string]) bool {
 		now := time.Now()
-		expectedStaleAt := now.Add(timeToStale).After(entry.staleAt)
-		expectedDeadAt := now.Add(timeToDead).After(entry.deadAt)
-		expectedValue := entry.value == expected
+		expectedStaleAt := now.Add(timeToStale).After(entry.StaleAt)
+		expectedDeadAt := now.Add(timeToDead).After(entry.DeadAt)
+		expectedValue := entry.Value == expected
 		return expectedStaleAt && expectedDeadAt && expectedValue
 	}
 }
@@ -70,8 +71,30 @@ func Test_SWR_New(t *testing.T) {
 	require.NotNil(t, swr)
 }
 
+func Test_SWR_NewFromCache(t *testing.T) {
+	ctx := t.Context()
+
+	key := "key"
+	expected := "value"
+
+	cache := &mockCache[string, *SWREntry[string]]{}
+	repo := &mockRepo[string, string]{}
+
+	cache.On("Get", ctx, key).Return(makeAliveEntry(expected), nil)
+
+	swr, err := NewSWRFromCache(cache, repo, time.Minute, 2*time.Minute)
+	require.NoError(t, err)
+
+	actual, err := swr.Get(ctx, key)
+	require.NoError(t, err)
+	require.Equal(t, expected, actual)
+
+	repo.AssertExpectations(t)
+	cache.AssertExpectations(t)
+}
+
 func Test_SWR_New_WithAllOptions(t *testing.T) {
-	cache := &mockCache[string, *entry[string]]{}
+	cache := &mockCache[string, *SWREntry[string]]{}
 	repo := &mockRepo[string, string]{}
 
 	swr, err := newSWR(repo, cache, time.Minute, 2*time.Minute, &sync.Map{},
@@ -84,7 +107,7 @@ func Test_SWR_New_WithAllOptions(t *testing.T) {
 }
 
 func Test_SWR_New_WithInvalidOptions(t *testing.T) {
-	cache := &mockCache[string, *entry[string]]{}
+	cache := &mockCache[string, *SWREntry[string]]{}
 	repo := &mockRepo[string, string]{}
 
 	t.Run("zero refresh workers", func(t *testing.T) {
@@ -158,6 +181,14 @@ func Test_SWR_New_WithInvalidOptions(t *testing.T) {
 		require.Error(t, err)
 		require.Contains(t, err.Error(), "nil repo")
 	})
+
+	t.Run("unknown invalidation mode", func(t *testing.T) {
+		bus := NewLocalInvalidationBus()
+		_, err := newSWR(repo, cache, time.Minute, 2*time.Minute, &sync.Map{},
+			SWRWithInvalidationBus(bus, InvalidationMode(42)))
+		require.Error(t, err)
+		require.Contains(t, err.Error(), "invalidation mode")
+	})
 }
 
 func Test_SWR_ValueMissing_NotInRepository(t *testing.T) {
@@ -165,7 +196,7 @@ func Test_SWR_ValueMissing_NotInRepository(t *testing.T) {
 
 	key := "key"
 
-	cache := &mockCache[string, *entry[string]]{}
+	cache := &mockCache[string, *SWREntry[string]]{}
 	repo := &mockRepo[string, string]{}
 
 	cache.On("Get", ctx, key).Return(nilEntry, ErrNotFound)
@@ -192,7 +223,7 @@ func Test_SWR_ValueMissing_InRepository(t *testing.T) {
 
 	entryMatcher := getEntryMatcher(expected, timeToStale, timeToDead)
 
-	cache := &mockCache[string, *entry[string]]{}
+	cache := &mockCache[string, *SWREntry[string]]{}
 	repo := &mockRepo[string, string]{}
 
 	cache.On("Get", ctx, key).Return(nilEntry, ErrNotFound)
@@ -229,7 +260,7 @@ func Test_SWR_ValueStale_InRepository(t *testing.T) {
 
 	entryMatcher := getEntryMatcher(newValue, timeToStale, timeToDead)
 
-	cache := &mockCache[string, *entry[string]]{}
+	cache := &mockCache[string, *SWREntry[string]]{}
 	repo := &mockRepo[string, string]{}
 
 	cache.On("Get", ctx, key).Return(staleEntry, nil)
@@ -266,7 +297,7 @@ func Test_SWR_ValueAlive(t *testing.T) {
 
 	aliveEntry := makeAliveEntry(expected)
 
-	cache := &mockCache[string, *entry[string]]{}
+	cache := &mockCache[string, *SWREntry[string]]{}
 	repo := &mockRepo[string, string]{}
 
 	cache.On("Get", ctx, key).Return(aliveEntry, nil)
@@ -295,7 +326,7 @@ func Test_SWR_ParallelFetchWhenMissing(t *testing.T) {
 
 	n := 50
 
-	cache := &mockCache[string, *entry[string]]{}
+	cache := &mockCache[string, *SWREntry[string]]{}
 	repo := &mockRepo[string, string]{}
 
 	cache.On("Get", ctx, key).Return(nilEntry, ErrNotFound).Times(n)
@@ -356,7 +387,7 @@ func Test_SWR_ParallelFetchWhenStale(t *testing.T) {
 
 	n := 50
 
-	cache := &mockCache[string, *entry[string]]{}
+	cache := &mockCache[string, *SWREntry[string]]{}
 	repo := &mockRepo[string, string]{}
 
 	cache.On("Get", ctx, key).Return(staleEntry, nil).Times(n)
@@ -418,7 +449,7 @@ func Test_SWR_ValueDead_RefreshFromRepository(t *testing.T) {
 
 	entryMatcher := getEntryMatcher(newValue, timeToStale, timeToDead)
 
-	cache := &mockCache[string, *entry[string]]{}
+	cache := &mockCache[string, *SWREntry[string]]{}
 	cache.On("Get", ctx, key).Return(deadEntry, nil)
 	cache.On("Set", ctx, key, mock.MatchedBy(entryMatcher)).Return(nil)
 
@@ -442,7 +473,7 @@ func Test_SWR_RefreshKey_GracefulHandleFullChannel(t *testing.T) {
 	timeToStale := time.Millisecond
 	timeToDead := time.Hour
 
-	cache := &mockCache[string, *entry[string]]{}
+	cache := &mockCache[string, *SWREntry[string]]{}
 	repo := &mockRepo[string, string]{}
 	syncMap := &mockSyncMap{}
 
@@ -544,7 +575,7 @@ func Test_SWR_ErrorCallback_CacheSetError(t *testing.T) {
 
 	entryMatcher := getEntryMatcher(expected, timeToStale, timeToDead)
 
-	cache := &mockCache[string, *entry[string]]{}
+	cache := &mockCache[string, *SWREntry[string]]{}
 	repo := &mockRepo[string, string]{}
 
 	cache.On("Get", ctx, key).Return(nilEntry, ErrNotFound)
@@ -586,7 +617,7 @@ func Test_SWR_ErrorCallback_CacheGetError(t *testing.T) {
 
 	entryMatcher := getEntryMatcher(expected, timeToStale, timeToDead)
 
-	cache := &mockCache[string, *entry[string]]{}
+	cache := &mockCache[string, *SWREntry[string]]{}
 	repo := &mockRepo[string, string]{}
 
 	cacheGetErr := errors.New("failure")
@@ -631,7 +662,7 @@ func Test_SWR_ErrorCallback_RefreshWorkerError(t *testing.T) {
 
 	errorCaptured := make(chan struct{})
 
-	cache := &mockCache[string, *entry[string]]{}
+	cache := &mockCache[string, *SWREntry[string]]{}
 	repo := &mockRepo[string, string]{}
 
 	cache.On("Get", ctx, key).Return(staleEntry, nil)
@@ -669,3 +700,573 @@ func Test_SWR_ErrorCallback_RefreshWorkerError(t *testing.T) {
 	repo.AssertExpectations(t)
 	cache.AssertExpectations(t)
 }
+
+func Test_SWR_Invalidate_Drop(t *testing.T) {
+	ctx := t.Context()
+
+	key := "key"
+
+	cache := &mockCache[string, *SWREntry[string]]{}
+	repo := &mockRepo[string, string]{}
+
+	cache.On("Delete", ctx, key).Return(nil).Once()
+
+	swr, err := newSWR(repo, cache, time.Minute, 2*time.Minute, &sync.Map{})
+	require.NoError(t, err)
+
+	require.NoError(t, swr.Invalidate(ctx, key))
+
+	repo.AssertExpectations(t)
+	cache.AssertExpectations(t)
+}
+
+func Test_SWR_Invalidate_MarkStale(t *testing.T) {
+	ctx := t.Context()
+
+	key := "key"
+	value := "value"
+
+	aliveEntry := makeAliveEntry(value)
+
+	cache := &mockCache[string, *SWREntry[string]]{}
+	repo := &mockRepo[string, string]{}
+
+	cache.On("Get", mock.Anything, key).Return(aliveEntry, nil).Once()
+	cache.On("Set", mock.Anything, key, mock.MatchedBy(func(e *SWREntry[string]) bool {
+		return !time.Now().Before(e.StaleAt) && e.DeadAt.Equal(aliveEntry.DeadAt) && e.Value == value
+	})).Return(nil).Once()
+
+	bus := NewLocalInvalidationBus()
+
+	_, err := newSWR(repo, cache, time.Minute, 2*time.Minute, &sync.Map{},
+		SWRWithInvalidationBus(bus, InvalidationMarkStale))
+	require.NoError(t, err)
+
+	// Published by another instance
+	message, err := newInvalidations[string]().encode(key)
+	require.NoError(t, err)
+	require.NoError(t, bus.Publish(ctx, message))
+
+	repo.AssertExpectations(t)
+	cache.AssertExpectations(t)
+}
+
+func Test_SWR_Invalidate_MarkStale_AlreadyStale(t *testing.T) {
+	ctx := t.Context()
+
+	key := "key"
+
+	cache := &mockCache[string, *SWREntry[string]]{}
+	repo := &mockRepo[string, string]{}
+
+	cache.On("Get", mock.Anything, key).Return(makeStaleEntry("value"), nil).Once()
+	cache.On("Get", mock.Anything, "missing").Return(nilEntry, ErrNotFound).Once()
+
+	bus := NewLocalInvalidationBus()
+
+	swr, err := newSWR(repo, cache, time.Minute, 2*time.Minute, &sync.Map{},
+		SWRWithInvalidationBus(bus, InvalidationMarkStale))
+	require.NoError(t, err)
+
+	require.NoError(t, swr.Invalidate(ctx, key))
+	require.NoError(t, swr.Invalidate(ctx, "missing"))
+
+	repo.AssertExpectations(t)
+	cache.AssertExpectations(t)
+}
+
+func Test_SWR_Invalidate_BroadcastsToAllInstances(t *testing.T) {
+	ctx := t.Context()
+
+	key := "key"
+
+	bus := NewLocalInvalidationBus()
+
+	caches := make([]*mockCache[string, *SWREntry[string]], 0, 3)
+	instances := make([]*SWR[string, string], 0, 3)
+	for range 3 {
+		cache := &mockCache[string, *SWREntry[string]]{}
+		repo := &mockRepo[string, string]{}
+
+		swr, err := newSWR(repo, cache, time.Minute, 2*time.Minute, &sync.Map{},
+			SWRWithInvalidationBus(bus, InvalidationDrop))
+		require.NoError(t, err)
+
+		caches = append(caches, cache)
+		instances = append(instances, swr)
+	}
+
+	// The publishing instance drops the key locally, and skips its own message
+	caches[0].On("Delete", ctx, key).Return(nil).Once()
+	caches[1].On("Delete", mock.Anything, key).Return(nil).Once()
+	caches[2].On("Delete", mock.Anything, key).Return(nil).Once()
+
+	require.NoError(t, instances[0].Invalidate(ctx, key))
+
+	for _, cache := range caches {
+		cache.AssertExpectations(t)
+	}
+}
+
+func Test_SWR_Invalidate_DuringFetch(t *testing.T) {
+	ctx := t.Context()
+
+	key := "key"
+	value := "value"
+
+	cache := &mockCache[string, *SWREntry[string]]{}
+	repo := &mockRepo[string, string]{}
+
+	fetching := make(chan struct{})
+	invalidated := make(chan struct{})
+
+	cache.On("Get", ctx, key).Return(nilEntry, ErrNotFound).Once()
+	cache.On("Delete", ctx, key).Return(nil).Once()
+	repo.On("Get", ctx, key).Run(func(mock.Arguments) {
+		close(fetching)
+		<-invalidated
+	}).Return(value, nil).Once()
+
+	swr, err := newSWR(repo, cache, time.Minute, 2*time.Minute, &sync.Map{})
+	require.NoError(t, err)
+
+	go func() {
+		<-fetching
+		require.NoError(t, swr.Invalidate(ctx, key))
+		close(invalidated)
+	}()
+
+	// The value fetched before the invalidation is returned, but not cached
+	actual, err := swr.Get(ctx, key)
+	require.NoError(t, err)
+	require.Equal(t, value, actual)
+
+	repo.AssertExpectations(t)
+	cache.AssertExpectations(t)
+}
+
+func Test_SWR_Invalidate_MalformedMessage(t *testing.T) {
+	ctx := t.Context()
+
+	cache := &mockCache[string, *SWREntry[string]]{}
+	repo := &mockRepo[string, string]{}
+
+	var capturedErr error
+	errorCallback := func(err error) {
+		capturedErr = err
+	}
+
+	bus := NewLocalInvalidationBus()
+
+	_, err := newSWR(repo, cache, time.Minute, 2*time.Minute, &sync.Map{},
+		SWRWithInvalidationBus(bus, InvalidationDrop),
+		SWRWithErrorCallback(errorCallback))
+	require.NoError(t, err)
+
+	require.NoError(t, bus.Publish(ctx, "malformed"))
+	require.ErrorContains(t, capturedErr, "malformed")
+
+	repo.AssertExpectations(t)
+	cache.AssertExpectations(t)
+}
+
+func Test_SWR_ErrorCallback_InvalidateError(t *testing.T) {
+	ctx := t.Context()
+
+	key := "key"
+	cacheDeleteErr := errors.New("failed")
+
+	cache := &mockCache[string, *SWREntry[string]]{}
+	repo := &mockRepo[string, string]{}
+
+	cache.On("Delete", ctx, key).Return(cacheDeleteErr).Once()
+
+	var capturedErr error
+	errorCallback := func(err error) {
+		capturedErr = err
+	}
+
+	swr, err := newSWR(repo, cache, time.Minute, 2*time.Minute, &sync.Map{},
+		SWRWithErrorCallback(errorCallback))
+	require.NoError(t, err)
+
+	require.NoError(t, swr.Invalidate(ctx, key))
+	require.ErrorIs(t, capturedErr, cacheDeleteErr)
+	require.ErrorContains(t, capturedErr, key)
+
+	repo.AssertExpectations(t)
+	cache.AssertExpectations(t)
+}
+
+func Test_SWR_RefreshLease_Acquired(t *testing.T) {
+	ctx := t.Context()
+	timeout := time.Second
+
+	key := "key"
+	oldValue := "value"
+	newValue := "new"
+
+	timeToStale := time.Minute
+	timeToDead := 2 * time.Minute
+
+	released := make(chan struct{})
+
+	staleEntry := makeStaleEntry(oldValue)
+
+	entryMatcher := getEntryMatcher(newValue, timeToStale, timeToDead)
+
+	cache := &mockCache[string, *SWREntry[string]]{}
+	repo := &mockRepo[string, string]{}
+	lease := &mockLease{}
+
+	cache.On("Get", ctx, key).Return(staleEntry, nil).Once()
+	lease.On("Acquire", mock.MatchedBy(isTimeoutContext), key).
+		Return(func(error) { close(released) }, true, nil).Once()
+	cache.On("Get", mock.MatchedBy(isTimeoutContext), key).Return(staleEntry, nil).Once()
+	repo.On("Get", mock.MatchedBy(isTimeoutContext), key).Return(newValue, nil).Once()
+	cache.On("Set", mock.MatchedBy(isTimeoutContext), key, mock.MatchedBy(entryMatcher)).Return(nil).Once()
+
+	swr, err := newSWR(repo, cache, timeToStale, timeToDead, &sync.Map{},
+		SWRWithRefreshLease(lease))
+	require.NoError(t, err)
+
+	actual, err := swr.Get(ctx, key)
+	require.NoError(t, err)
+	require.Equal(t, oldValue, actual)
+
+	select {
+	case <-released: // Background refresh completed
+	case <-time.After(timeout): // Background refresh failed, expectations should fail
+	}
+
+	repo.AssertExpectations(t)
+	cache.AssertExpectations(t)
+	lease.AssertExpectations(t)
+}
+
+func Test_SWR_RefreshLease_AlreadyRefreshed(t *testing.T) {
+	ctx := t.Context()
+	timeout := time.Second
+
+	key := "key"
+	oldValue := "value"
+	newValue := "new"
+
+	released := make(chan struct{})
+
+	cache := &mockCache[string, *SWREntry[string]]{}
+	repo := &mockRepo[string, string]{}
+	lease := &mockLease{}
+
+	cache.On("Get", ctx, key).Return(makeStaleEntry(oldValue), nil).Once()
+	lease.On("Acquire", mock.MatchedBy(isTimeoutContext), key).
+		Return(func(error) { close(released) }, true, nil).Once()
+	cache.On("Get", mock.MatchedBy(isTimeoutContext), key).Return(makeAliveEntry(newValue), nil).Once()
+
+	swr, err := newSWR(repo, cache, time.Minute, 2*time.Minute, &sync.Map{},
+		SWRWithRefreshLease(lease))
+	require.NoError(t, err)
+
+	actual, err := swr.Get(ctx, key)
+	require.NoError(t, err)
+	require.Equal(t, oldValue, actual)
+
+	select {
+	case <-released: // Background refresh completed
+	case <-time.After(timeout): // Background refresh failed, expectations should fail
+	}
+
+	repo.AssertExpectations(t)
+	cache.AssertExpectations(t)
+	lease.AssertExpectations(t)
+}
+
+func Test_SWR_RefreshLease_NotAcquired(t *testing.T) {
+	ctx := t.Context()
+	timeout := time.Second
+
+	key := "key"
+	value := "value"
+
+	acquireCalled := make(chan struct{})
+
+	cache := &mockCache[string, *SWREntry[string]]{}
+	repo := &mockRepo[string, string]{}
+	lease := &mockLease{}
+
+	cache.On("Get", ctx, key).Return(makeStaleEntry(value), nil).Once()
+	lease.On("Acquire", mock.MatchedBy(isTimeoutContext), key).
+		Run(func(args mock.Arguments) {
+			close(acquireCalled)
+		}).
+		Return(nil, false, nil).Once()
+
+	swr, err := newSWR(repo, cache, time.Minute, 2*time.Minute, &sync.Map{},
+		SWRWithRefreshLease(lease))
+	require.NoError(t, err)
+
+	actual, err := swr.Get(ctx, key)
+	require.NoError(t, err)
+	require.Equal(t, value, actual)
+
+	select {
+	case <-acquireCalled: // Another process holds the lease
+	case <-time.After(timeout): // Background refresh failed, expectations should fail
+	}
+
+	repo.AssertExpectations(t)
+	cache.AssertExpectations(t)
+	lease.AssertExpectations(t)
+}
+
+func Test_SWR_RefreshLease_HolderFailed(t *testing.T) {
+	ctx := t.Context()
+	timeout := time.Second
+
+	key := "key"
+	value := "value"
+
+	acquireCalled := make(chan struct{})
+
+	cache := &mockCache[string, *SWREntry[string]]{}
+	repo := &mockRepo[string, string]{}
+	lease := &mockLease{}
+
+	cache.On("Get", ctx, key).Return(makeStaleEntry(value), nil).Once()
+	lease.On("Acquire", mock.MatchedBy(isTimeoutContext), key).
+		Run(func(args mock.Arguments) {
+			close(acquireCalled)
+		}).
+		Return(nil, false, ErrLeaseFailed).Once()
+
+	swr, err := newSWR(repo, cache, time.Minute, 2*time.Minute, &sync.Map{},
+		SWRWithRefreshLease(lease))
+	require.NoError(t, err)
+
+	actual, err := swr.Get(ctx, key)
+	require.NoError(t, err)
+	require.Equal(t, value, actual)
+
+	select {
+	case <-acquireCalled: // Another process just failed to refresh the key
+	case <-time.After(timeout): // Background refresh failed, expectations should fail
+	}
+
+	repo.AssertExpectations(t)
+	cache.AssertExpectations(t)
+	lease.AssertExpectations(t)
+}
+
+func Test_SWR_NotFoundClassifier_CacheGet(t *testing.T) {
+	ctx := t.Context()
+
+	key := "key"
+	expected := "value"
+
+	timeToStale := time.Minute
+	timeToDead := 2 * time.Minute
+
+	entryMatcher := getEntryMatcher(expected, timeToStale, timeToDead)
+
+	cache := &mockCache[string, *SWREntry[string]]{}
+	repo := &mockRepo[string, string]{}
+
+	errMissing := errors.New("missing")
+	cache.On("Get", ctx, key).Return(nilEntry, errMissing)
+
+	repo.On("Get", ctx, key).Return(expected, nil)
+	cache.On("Set", ctx, key, mock.MatchedBy(entryMatcher)).Return(nil)
+
+	errorCallback := func(err error) {
+		t.Errorf("unexpected error: %v", err)
+	}
+
+	swr, err := newSWR(repo, cache, timeToStale, timeToDead, &sync.Map{},
+		SWRWithErrorCallback(errorCallback),
+		SWRWithNotFoundClassifier(func(err error) bool {
+			return errors.Is(err, errMissing)
+		}),
+	)
+	require.NoError(t, err)
+
+	actual, err := swr.Get(ctx, key)
+	require.NoError(t, err)
+	require.Equal(t, expected, actual)
+
+	repo.AssertExpectations(t)
+	cache.AssertExpectations(t)
+}
+
+func Test_SWR_NotFoundClassifier_Repository(t *testing.T) {
+	ctx := t.Context()
+
+	key := "key"
+
+	cache := &mockCache[string, *SWREntry[string]]{}
+	repo := &mockRepo[string, string]{}
+
+	errNoRows := errors.New("no rows")
+	cache.On("Get", ctx, key).Return(nilEntry, ErrNotFound)
+	repo.On("Get", ctx, key).Return("", errNoRows)
+
+	swr, err := newSWR(repo, cache, time.Minute, 2*time.Minute, &sync.Map{},
+		SWRWithNotFoundClassifier(func(err error) bool {
+			return errors.Is(err, errNoRows)
+		}),
+	)
+	require.NoError(t, err)
+
+	_, err = swr.Get(ctx, key)
+	require.ErrorIs(t, err, ErrNotFound)
+	require.ErrorIs(t, err, errNoRows)
+
+	// Unclassified errors are returned as is
+	other := errors.New("failure")
+	repo.ExpectedCalls = nil
+	repo.On("Get", ctx, key).Return("", other)
+
+	_, err = swr.Get(ctx, key)
+	require.ErrorIs(t, err, other)
+	require.NotErrorIs(t, err, ErrNotFound)
+
+	repo.AssertExpectations(t)
+	cache.AssertExpectations(t)
+}
+
+func Test_SWR_NotFoundClassifier_RefreshWorker(t *testing.T) {
+	ctx := t.Context()
+
+	key := "key"
+	value := "value"
+
+	cache := &mockCache[string, *SWREntry[string]]{}
+	repo := &mockRepo[string, string]{}
+
+	cache.On("Get", ctx, key).Return(makeStaleEntry(value), nil)
+
+	errNoRows := errors.New("no rows")
+	repo.On("Get", mock.MatchedBy(isTimeoutContext), key).Return("", errNoRows)
+
+	var reported atomic.Value
+	errorCallback := func(err error) {
+		reported.Store(err)
+	}
+
+	syncMap := &sync.Map{}
+
+	swr, err := newSWR(repo, cache, time.Minute, 2*time.Minute, syncMap,
+		SWRWithErrorCallback(errorCallback),
+		SWRWithNotFoundClassifier(func(err error) bool {
+			return errors.Is(err, errNoRows)
+		}),
+	)
+	require.NoError(t, err)
+
+	actual, err := swr.Get(ctx, key)
+	require.NoError(t, err)
+	require.Equal(t, value, actual)
+
+	// The key is released once the refresh completes
+	require.Eventually(t, func() bool {
+		_, refreshing := syncMap.Load(key)
+		return !refreshing
+	}, time.Second, 10*time.Millisecond)
+
+	// Keys removed from the repository are reported, like ErrNotFound is
+	refreshErr, _ := reported.Load().(error)
+	require.ErrorIs(t, refreshErr, ErrNotFound)
+	require.ErrorIs(t, refreshErr, errNoRows)
+
+	repo.AssertExpectations(t)
+	cache.AssertExpectations(t)
+}
+
+func Test_SWR_CircuitOpen_ValueDead(t *testing.T) {
+	ctx := t.Context()
+
+	key := "key"
+	value := "dead_value"
+
+	cache := &mockCache[string, *SWREntry[string]]{}
+	cache.On("Get", ctx, key).Return(makeDeadEntry(value), nil)
+
+	repo := &mockRepo[string, string]{}
+	repo.On("Get", ctx, key).Return("", ErrCircuitOpen)
+
+	swr, err := newSWR(repo, cache, time.Minute, 2*time.Minute, &sync.Map{})
+	require.NoError(t, err)
+
+	actual, err := swr.Get(ctx, key)
+	require.NoError(t, err)
+	require.Equal(t, value, actual)
+
+	repo.AssertExpectations(t)
+	cache.AssertExpectations(t)
+}
+
+func Test_SWR_CircuitOpen_ValueMissing(t *testing.T) {
+	ctx := t.Context()
+
+	key := "key"
+
+	cache := &mockCache[string, *SWREntry[string]]{}
+	cache.On("Get", ctx, key).Return((*SWREntry[string])(nil), ErrNotFound)
+
+	repo := &mockRepo[string, string]{}
+	repo.On("Get", ctx, key).Return("", ErrCircuitOpen)
+
+	swr, err := newSWR(repo, cache, time.Minute, 2*time.Minute, &sync.Map{})
+	require.NoError(t, err)
+
+	_, err = swr.Get(ctx, key)
+	require.ErrorIs(t, err, ErrCircuitOpen)
+
+	repo.AssertExpectations(t)
+	cache.AssertExpectations(t)
+}
+
+func Test_SWR_CircuitOpen_RefreshWorker(t *testing.T) {
+	ctx := t.Context()
+
+	key := "key"
+	value := "value"
+
+	cache := &mockCache[string, *SWREntry[string]]{}
+	cache.On("Get", ctx, key).Return(makeStaleEntry(value), nil)
+
+	refreshed := make(chan struct{})
+	repo := &mockRepo[string, string]{}
+	repo.On("Get", mock.MatchedBy(isTimeoutContext), key).
+		Return("", ErrCircuitOpen).
+		Run(func(mock.Arguments) { close(refreshed) })
+
+	errorCallback := func(err error) {
+		t.Errorf("unexpected error: %v", err)
+	}
+
+	syncMap := &sync.Map{}
+
+	swr, err := newSWR(repo, cache, time.Minute, 2*time.Minute, syncMap,
+		SWRWithErrorCallback(errorCallback),
+	)
+	require.NoError(t, err)
+
+	actual, err := swr.Get(ctx, key)
+	require.NoError(t, err)
+	require.Equal(t, value, actual)
+
+	select {
+	case <-refreshed:
+	case <-time.After(time.Second):
+		t.Fatal("key was not refreshed")
+	}
+
+	// Let the refresh worker handle the error
+	require.Eventually(t, func() bool {
+		_, refreshing := syncMap.Load(key)
+		return !refreshing
+	}, time.Second, time.Millisecond)
+
+	repo.AssertExpectations(t)
+	cache.AssertExpectations(t)
+}
@@ -0,0 +1,215 @@
+package cachehit
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"golang.org/x/sync/singleflight"
+)
+
+const (
+	// TierRepository is reported for values fetched from the repository.
+	TierRepository = -1
+)
+
+// WritePolicy controls how values found in lower tiers (or the repository)
+// are written back to a tier.
+type WritePolicy int
+
+const (
+	// WriteSync writes values to the tier before returning them.
+	WriteSync WritePolicy = iota
+
+	// WriteAsync writes values to the tier in the background.
+	WriteAsync
+
+	// WriteSkip never writes values to the tier, e.g. for read-only tiers.
+	WriteSkip
+)
+
+// Tier is a single cache tier of a tiered cache.
+type Tier[K comparable, V any] struct {
+	Cache Cache[K, V]
+
+	// TTL is the expiration of values written to the tier. If zero, the cache's
+	// own expiration is used, otherwise the cache must implement TTLSetter.
+	TTL time.Duration
+
+	WritePolicy WritePolicy
+}
+
+// Tiered is a cache made of multiple tiers, usually ordered from the fastest
+// (e.g. in-memory) to the slowest (e.g. Redis). Tiers are read in order,
+// and a value found in a tier is written to all the tiers above it.
+// Values missing from all tiers are fetched from the repository,
+// and written to all the tiers.
+type Tiered[K comparable, V any] struct {
+	repo  Repository[K, V]
+	tiers []Tier[K, V]
+
+	dedup *singleflight.Group
+
+	asyncWriteTimeout time.Duration
+
+	hitCallback func(tier int)
+
+	isNotFound NotFoundClassifier
+
+	errorCallback ErrorCallback
+}
+
+func NewTiered[K comparable, V any](
+	repo Repository[K, V],
+	tiers []Tier[K, V],
+	opts ...TieredOption,
+) (*Tiered[K, V], error) {
+	if repo == nil {
+		return nil, fmt.Errorf("nil repo")
+	}
+
+	if len(tiers) == 0 {
+		return nil, fmt.Errorf("no tiers")
+	}
+
+	for i, tier := range tiers {
+		if tier.Cache == nil {
+			return nil, fmt.Errorf("tier %d: nil cache", i)
+		}
+
+		if tier.WritePolicy != WriteSync && tier.WritePolicy != WriteAsync && tier.WritePolicy != WriteSkip {
+			return nil, fmt.Errorf("tier %d: unknown write policy: %d", i, tier.WritePolicy)
+		}
+
+		if tier.TTL < time.Duration(0) {
+			return nil, fmt.Errorf("tier %d: ttl must not be negative", i)
+		}
+
+		if _, ok := tier.Cache.(TTLSetter[K, V]); tier.TTL > 0 && !ok {
+			return nil, fmt.Errorf("tier %d: ttl: cache doesn't support ttl", i)
+		}
+	}
+
+	o := tieredCompileOptions(opts...)
+	if err := o.Validate(); err != nil {
+		return nil, fmt.Errorf("options: %w", err)
+	}
+
+	return &Tiered[K, V]{
+		repo:  repo,
+		tiers: append([]Tier[K, V](nil), tiers...),
+
+		dedup: new(singleflight.Group),
+
+		asyncWriteTimeout: o.asyncWriteTimeout,
+
+		hitCallback: o.hitCallback,
+
+		isNotFound: o.isNotFound,
+
+		errorCallback: o.errorCallback,
+	}, nil
+}
+
+func (c *Tiered[K, V]) reportError(err error) {
+	if c.errorCallback != nil {
+		c.errorCallback(err)
+	}
+}
+
+func (c *Tiered[K, V]) hit(tier int) {
+	if c.hitCallback != nil {
+		c.hitCallback(tier)
+	}
+}
+
+func (c *Tiered[K, V]) notFound(err error) bool {
+	return isNotFound(err, c.isNotFound)
+}
+
+func (c *Tiered[K, V]) set(ctx context.Context, i int, key K, value V) {
+	tier := c.tiers[i]
+
+	var err error
+	if tier.TTL > 0 {
+		err = tier.Cache.(TTLSetter[K, V]).SetWithTTL(ctx, key, value, tier.TTL)
+	} else {
+		err = tier.Cache.Set(ctx, key, value)
+	}
+
+	if err != nil {
+		c.reportError(fmt.Errorf("tier %d set: %v: %w", i, key, err))
+	}
+}
+
+// backfill writes the value to the first n tiers, according to their write policies.
+func (c *Tiered[K, V]) backfill(ctx context.Context, n int, key K, value V) {
+	for i := range n {
+		switch c.tiers[i].WritePolicy {
+		case WriteSync:
+			c.set(ctx, i, key, value)
+
+		case WriteAsync:
+			go func() {
+				ctx, cancel := context.WithTimeout(context.WithoutCancel(ctx), c.asyncWriteTimeout)
+				defer cancel()
+
+				c.set(ctx, i, key, value)
+			}()
+		}
+	}
+}
+
+func (c *Tiered[K, V]) fetch(ctx context.Context, key K) (V, error) {
+	k := fmt.Sprintf("%v", key)
+	res, err, _ := c.dedup.Do(k, func() (interface{}, error) {
+		value, err := c.repo.Get(ctx, key)
+		if err != nil {
+			return nil, fmt.Errorf("repo get: %w", asNotFound(err, c.isNotFound))
+		}
+
+		c.backfill(ctx, len(c.tiers), key, value)
+		return value, nil
+	})
+
+	if err != nil {
+		var v V
+		return v, err
+	}
+
+	value, ok := res.(V)
+	if !ok {
+		var v V
+		return v, fmt.Errorf("value type: expected %T: found %T", v, res)
+	}
+
+	return value, nil
+}
+
+// GetWithTier returns the value of the specified key, along with the index of
+// the tier that served it, or TierRepository if it was fetched from the repository.
+func (c *Tiered[K, V]) GetWithTier(ctx context.Context, key K) (V, int, error) {
+	for i, tier := range c.tiers {
+		value, err := tier.Cache.Get(ctx, key)
+		if err == nil {
+			c.backfill(ctx, i, key, value)
+			c.hit(i)
+			return value, i, nil
+		} else if !c.notFound(err) {
+			c.reportError(fmt.Errorf("tier %d get: %v: %w", i, key, err))
+		}
+	}
+
+	value, err := c.fetch(ctx, key)
+	if err != nil {
+		return value, TierRepository, err
+	}
+
+	c.hit(TierRepository)
+	return value, TierRepository, nil
+}
+
+func (c *Tiered[K, V]) Get(ctx context.Context, key K) (V, error) {
+	value, _, err := c.GetWithTier(ctx, key)
+	return value, err
+}
@@ -0,0 +1,82 @@
+package cachehit
+
+import (
+	"fmt"
+	"time"
+)
+
+const (
+	TieredDefaultAsyncWriteTimeout = 15 * time.Second
+)
+
+type tieredOptions struct {
+	asyncWriteTimeout time.Duration
+
+	hitCallback func(tier int)
+
+	isNotFound NotFoundClassifier
+
+	errorCallback ErrorCallback
+}
+
+func (o *tieredOptions) Validate() error {
+	if o.asyncWriteTimeout <= time.Duration(0) {
+		return fmt.Errorf("async write timeout must be positive")
+	}
+
+	return nil
+}
+
+func tieredDefaultOptions() *tieredOptions {
+	return &tieredOptions{
+		asyncWriteTimeout: TieredDefaultAsyncWriteTimeout,
+	}
+}
+
+func tieredCompileOptions(opts ...TieredOption) *tieredOptions {
+	o := tieredDefaultOptions()
+
+	for _, opt := range opts {
+		opt(o)
+	}
+
+	return o
+}
+
+type TieredOption func(*tieredOptions)
+
+// TieredWithErrorCallback configures the tiered cache to call the specified
+// callback synchronously when an error happens during internal operations.
+// Errors of asynchronous writes are reported from the writing goroutine.
+func TieredWithErrorCallback(errorCallback ErrorCallback) TieredOption {
+	return func(o *tieredOptions) {
+		o.errorCallback = errorCallback
+	}
+}
+
+// TieredWithAsyncWriteTimeout configures the tiered cache to timeout
+// asynchronous writes (see WriteAsync) after the specified amount of time.
+func TieredWithAsyncWriteTimeout(timeout time.Duration) TieredOption {
+	return func(o *tieredOptions) {
+		o.asyncWriteTimeout = timeout
+	}
+}
+
+// TieredWithHitCallback configures the tiered cache to call the specified callback
+// synchronously with the tier that served every successful Get, or TierRepository.
+// Useful for collecting per-tier hit ratios.
+func TieredWithHitCallback(hitCallback func(tier int)) TieredOption {
+	return func(o *tieredOptions) {
+		o.hitCallback = hitCallback
+	}
+}
+
+// TieredWithNotFoundClassifier configures the tiered cache to treat errors
+// classified as not found by the specified classifier like ErrNotFound, in addition to it.
+// Such tier errors are handled as clean misses without being reported, and such
+// repository errors are returned wrapping ErrNotFound.
+func TieredWithNotFoundClassifier(isNotFound NotFoundClassifier) TieredOption {
+	return func(o *tieredOptions) {
+		o.isNotFound = isNotFound
+	}
+}
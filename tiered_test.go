@@ -0,0 +1,318 @@
+package cachehit
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+func Test_Tiered_New_WithInvalidArgs(t *testing.T) {
+	repo := &mockRepo[string, string]{}
+	cache := &mockCache[string, string]{}
+
+	tests := []struct {
+		name     string
+		repo     Repository[string, string]
+		tiers    []Tier[string, string]
+		opts     []TieredOption
+		contains string
+	}{
+		{"NilRepo", nil, []Tier[string, string]{{Cache: cache}}, nil, "repo"},
+		{"NoTiers", repo, nil, nil, "no tiers"},
+		{"NilCache", repo, []Tier[string, string]{{}}, nil, "nil cache"},
+		{"UnknownWritePolicy", repo, []Tier[string, string]{{Cache: cache, WritePolicy: WritePolicy(42)}}, nil, "write policy"},
+		{"NegativeTTL", repo, []Tier[string, string]{{Cache: &mockTTLCache[string, string]{}, TTL: -time.Second}}, nil, "ttl"},
+		{"TTLNotSupported", repo, []Tier[string, string]{{Cache: cache, TTL: time.Second}}, nil, "doesn't support ttl"},
+		{"AsyncWriteTimeout", repo, []Tier[string, string]{{Cache: cache}}, []TieredOption{TieredWithAsyncWriteTimeout(0)}, "async write timeout"},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			_, err := NewTiered(test.repo, test.tiers, test.opts...)
+			require.ErrorContains(t, err, test.contains)
+		})
+	}
+}
+
+func Test_Tiered_ValueInFirstTier(t *testing.T) {
+	ctx := t.Context()
+
+	key := "key"
+	expected := "value"
+
+	tier0 := &mockCache[string, string]{}
+	tier1 := &mockCache[string, string]{}
+	repo := &mockRepo[string, string]{}
+
+	tier0.On("Get", ctx, key).Return(expected, nil)
+
+	tiered, err := NewTiered(repo, []Tier[string, string]{{Cache: tier0}, {Cache: tier1}})
+	require.NoError(t, err)
+
+	actual, tier, err := tiered.GetWithTier(ctx, key)
+	require.NoError(t, err)
+	require.Equal(t, expected, actual)
+	require.Equal(t, 0, tier)
+
+	repo.AssertExpectations(t)
+	tier0.AssertExpectations(t)
+	tier1.AssertExpectations(t)
+}
+
+func Test_Tiered_ValueInLowerTier(t *testing.T) {
+	ctx := t.Context()
+
+	key := "key"
+	expected := "value"
+
+	tier0 := &mockTTLCache[string, string]{}
+	tier1 := &mockCache[string, string]{}
+	tier2 := &mockCache[string, string]{}
+	repo := &mockRepo[string, string]{}
+
+	tier0.On("Get", ctx, key).Return("", ErrNotFound)
+	tier1.On("Get", ctx, key).Return("", ErrNotFound)
+	tier2.On("Get", ctx, key).Return(expected, nil)
+
+	// Upper tiers are back-filled, using their TTLs
+	tier0.On("SetWithTTL", ctx, key, expected, time.Minute).Return(nil)
+	tier1.On("Set", ctx, key, expected).Return(nil)
+
+	tiered, err := NewTiered(repo, []Tier[string, string]{
+		{Cache: tier0, TTL: time.Minute},
+		{Cache: tier1},
+		{Cache: tier2},
+	})
+	require.NoError(t, err)
+
+	actual, tier, err := tiered.GetWithTier(ctx, key)
+	require.NoError(t, err)
+	require.Equal(t, expected, actual)
+	require.Equal(t, 2, tier)
+
+	repo.AssertExpectations(t)
+	tier0.AssertExpectations(t)
+	tier1.AssertExpectations(t)
+	tier2.AssertExpectations(t)
+}
+
+func Test_Tiered_ValueInRepository(t *testing.T) {
+	ctx := t.Context()
+
+	key := "key"
+	expected := "value"
+
+	tier0 := &mockCache[string, string]{}
+	tier1 := &mockCache[string, string]{}
+	repo := &mockRepo[string, string]{}
+
+	tier0.On("Get", ctx, key).Return("", ErrNotFound)
+	tier1.On("Get", ctx, key).Return("", ErrNotFound)
+	repo.On("Get", ctx, key).Return(expected, nil)
+	tier0.On("Set", ctx, key, expected).Return(nil)
+	tier1.On("Set", ctx, key, expected).Return(nil)
+
+	var hits []int
+	tiered, err := NewTiered(repo, []Tier[string, string]{{Cache: tier0}, {Cache: tier1}},
+		TieredWithHitCallback(func(tier int) {
+			hits = append(hits, tier)
+		}))
+	require.NoError(t, err)
+
+	actual, tier, err := tiered.GetWithTier(ctx, key)
+	require.NoError(t, err)
+	require.Equal(t, expected, actual)
+	require.Equal(t, TierRepository, tier)
+	require.Equal(t, []int{TierRepository}, hits)
+
+	repo.AssertExpectations(t)
+	tier0.AssertExpectations(t)
+	tier1.AssertExpectations(t)
+}
+
+func Test_Tiered_ValueMissing(t *testing.T) {
+	ctx := t.Context()
+
+	key := "key"
+
+	tier0 := &mockCache[string, string]{}
+	repo := &mockRepo[string, string]{}
+
+	tier0.On("Get", ctx, key).Return("", ErrNotFound)
+	repo.On("Get", ctx, key).Return("", ErrNotFound)
+
+	var hits []int
+	tiered, err := NewTiered(repo, []Tier[string, string]{{Cache: tier0}},
+		TieredWithHitCallback(func(tier int) {
+			hits = append(hits, tier)
+		}))
+	require.NoError(t, err)
+
+	_, err = tiered.Get(ctx, key)
+	require.ErrorIs(t, err, ErrNotFound)
+	require.Empty(t, hits)
+
+	repo.AssertExpectations(t)
+	tier0.AssertExpectations(t)
+}
+
+func Test_Tiered_WritePolicies(t *testing.T) {
+	ctx := t.Context()
+
+	key := "key"
+	expected := "value"
+
+	tier0 := &mockCache[string, string]{}
+	tier1 := &mockCache[string, string]{}
+	tier2 := &mockCache[string, string]{}
+	repo := &mockRepo[string, string]{}
+
+	written := make(chan struct{})
+
+	tier0.On("Get", ctx, key).Return("", ErrNotFound)
+	tier1.On("Get", ctx, key).Return("", ErrNotFound)
+	tier2.On("Get", ctx, key).Return("", ErrNotFound)
+	repo.On("Get", ctx, key).Return(expected, nil)
+
+	// Async writes outlive the request context
+	tier0.On("Set", mock.MatchedBy(func(ctx context.Context) bool {
+		_, ok := ctx.Deadline()
+		return ok
+	}), key, expected).Return(nil).Run(func(mock.Arguments) {
+		close(written)
+	})
+	tier1.On("Set", ctx, key, expected).Return(nil)
+
+	tiered, err := NewTiered(repo, []Tier[string, string]{
+		{Cache: tier0, WritePolicy: WriteAsync},
+		{Cache: tier1, WritePolicy: WriteSync},
+		{Cache: tier2, WritePolicy: WriteSkip},
+	})
+	require.NoError(t, err)
+
+	actual, err := tiered.Get(ctx, key)
+	require.NoError(t, err)
+	require.Equal(t, expected, actual)
+
+	select {
+	case <-written:
+	case <-time.After(time.Second):
+		t.Fatal("async write didn't happen")
+	}
+
+	repo.AssertExpectations(t)
+	tier0.AssertExpectations(t)
+	tier1.AssertExpectations(t)
+	tier2.AssertExpectations(t)
+}
+
+func Test_Tiered_ErrorCallback_TierErrors(t *testing.T) {
+	ctx := t.Context()
+
+	key := "key"
+	expected := "value"
+
+	tier0 := &mockCache[string, string]{}
+	tier1 := &mockCache[string, string]{}
+	repo := &mockRepo[string, string]{}
+
+	getErr := errors.New("get failure")
+	setErr := errors.New("set failure")
+
+	tier0.On("Get", ctx, key).Return("", getErr)
+	tier1.On("Get", ctx, key).Return(expected, nil)
+	tier0.On("Set", ctx, key, expected).Return(setErr)
+
+	var capturedErrs []error
+	tiered, err := NewTiered(repo, []Tier[string, string]{{Cache: tier0}, {Cache: tier1}},
+		TieredWithErrorCallback(func(err error) {
+			capturedErrs = append(capturedErrs, err)
+		}))
+	require.NoError(t, err)
+
+	actual, tier, err := tiered.GetWithTier(ctx, key)
+	require.NoError(t, err)
+	require.Equal(t, expected, actual)
+	require.Equal(t, 1, tier)
+
+	require.Len(t, capturedErrs, 2)
+	require.ErrorIs(t, capturedErrs[0], getErr)
+	require.ErrorContains(t, capturedErrs[0], "tier 0 get")
+	require.ErrorIs(t, capturedErrs[1], setErr)
+	require.ErrorContains(t, capturedErrs[1], "tier 0 set")
+
+	repo.AssertExpectations(t)
+	tier0.AssertExpectations(t)
+	tier1.AssertExpectations(t)
+}
+
+func Test_Tiered_NotFoundClassifier(t *testing.T) {
+	ctx := t.Context()
+
+	key := "key"
+
+	tier0 := &mockCache[string, string]{}
+	repo := &mockRepo[string, string]{}
+
+	errMissing := errors.New("missing")
+	errNoRows := errors.New("no rows")
+
+	tier0.On("Get", ctx, key).Return("", errMissing)
+	repo.On("Get", ctx, key).Return("", errNoRows)
+
+	tiered, err := NewTiered(repo, []Tier[string, string]{{Cache: tier0}},
+		TieredWithErrorCallback(func(err error) {
+			t.Errorf("unexpected error: %v", err)
+		}),
+		TieredWithNotFoundClassifier(func(err error) bool {
+			return errors.Is(err, errMissing) || errors.Is(err, errNoRows)
+		}))
+	require.NoError(t, err)
+
+	_, err = tiered.Get(ctx, key)
+	require.ErrorIs(t, err, ErrNotFound)
+	require.ErrorIs(t, err, errNoRows)
+
+	repo.AssertExpectations(t)
+	tier0.AssertExpectations(t)
+}
+
+func Test_Tiered_ParallelFetch(t *testing.T) {
+	ctx := t.Context()
+
+	key := "key"
+	expected := "value"
+
+	n := 10
+
+	tier0 := &mockCache[string, string]{}
+	repo := &mockRepo[string, string]{}
+
+	tier0.On("Get", ctx, key).Return("", ErrNotFound).Times(n)
+	repo.On("Get", ctx, key).Return(expected, nil).After(100 * time.Millisecond).Once()
+	tier0.On("Set", ctx, key, expected).Return(nil).Once()
+
+	tiered, err := NewTiered(repo, []Tier[string, string]{{Cache: tier0}})
+	require.NoError(t, err)
+
+	var wg sync.WaitGroup
+	for range n {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+
+			actual, err := tiered.Get(ctx, key)
+			require.NoError(t, err)
+			require.Equal(t, expected, actual)
+		}()
+	}
+	wg.Wait()
+
+	repo.AssertExpectations(t)
+	tier0.AssertExpectations(t)
+}
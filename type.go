@@ -2,12 +2,24 @@ package cachehit
 
 import (
 	"context"
+	"errors"
+	"time"
 
 	"github.com/dtrugman/cachehit/internal"
 )
 
 var (
 	ErrNotFound = internal.ErrNotFound
+
+	// ErrLeaseFailed is returned by leases whose recent holder failed to fetch the key,
+	// see Lease.
+	ErrLeaseFailed = internal.ErrLeaseFailed
+
+	// ErrClosed is returned by constructs (and adapters) that were closed.
+	ErrClosed = internal.ErrClosed
+
+	// ErrCircuitOpen is returned by circuit breakers that don't let requests through.
+	ErrCircuitOpen = errors.New("circuit open")
 )
 
 type Repository[K comparable, V any] interface {
@@ -20,6 +32,60 @@ type Cache[K comparable, V any] interface {
 	Set(ctx context.Context, key K, value V) error
 }
 
+// Writer is implemented by repositories that support storing values.
+type Writer[K comparable, V any] interface {
+	Write(ctx context.Context, key K, value V) error
+}
+
+// BatchWriter is implemented by writers that can store multiple values at once.
+type BatchWriter[K comparable, V any] interface {
+	WriteMany(ctx context.Context, values map[K]V) error
+}
+
+// Store is a repository that supports writes, e.g. the backing database of a write behind cache.
+type Store[K comparable, V any] interface {
+	Repository[K, V]
+	Writer[K, V]
+}
+
+// Deleter is implemented by caches that support removing keys.
+type Deleter[K comparable] interface {
+	Delete(ctx context.Context, key K) error
+}
+
+// TTLSetter is implemented by caches that support storing values with a custom expiration.
+type TTLSetter[K comparable, V any] interface {
+	SetWithTTL(ctx context.Context, key K, value V, ttl time.Duration) error
+}
+
+// Ranger is implemented by caches that support iterating over their entries.
+type Ranger[K comparable, V any] interface {
+	// Range calls fn for every entry, until fn returns false.
+	Range(ctx context.Context, fn func(key K, value V) bool) error
+}
+
+// Result holds the outcome of fetching a single key as part of a batch.
+type Result[V any] = internal.Result[V]
+
+// BatchRepository is implemented by repositories that can fetch multiple keys at once.
+// The returned map holds a result for every requested key, with ErrNotFound
+// for missing ones. A non-nil error means the whole batch failed.
+type BatchRepository[K comparable, V any] interface {
+	GetMany(ctx context.Context, keys []K) (map[K]Result[V], error)
+}
+
+// BatchCache is implemented by caches that can fetch and store multiple keys at once.
+type BatchCache[K comparable, V any] interface {
+	Cache[K, V]
+	BatchRepository[K, V]
+
+	SetMany(ctx context.Context, values map[K]V) error
+}
+
+// SWREntry is a value stored by the SWR cache, along with the times at which
+// it becomes stale and dead. Exported so it can be persisted by shared caches.
+type SWREntry[V any] = internal.Entry[V]
+
 type ErrorCallback func(err error)
 
 type syncMap interface {
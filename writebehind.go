@@ -0,0 +1,318 @@
+package cachehit
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"maps"
+	"sync"
+	"time"
+)
+
+// WriteBehind is a cache whose writes land in the cache immediately, and are
+// flushed to the backing store asynchronously, in batches. Repeated writes to
+// the same key are coalesced, so only the latest value is written to the store.
+// Pending writes are flushed periodically, once enough keys are pending, and on Close.
+type WriteBehind[K comparable, V any] struct {
+	cache Cache[K, V]
+	store Store[K, V]
+
+	batchSize     int
+	flushInterval time.Duration
+	flushTimeout  time.Duration
+
+	maxRetries   int
+	retryBackoff time.Duration
+
+	errorCallback ErrorCallback
+
+	// flushing holds the values being flushed, and isn't modified until the flush completes
+	mu       sync.Mutex
+	pending  map[K]V
+	flushing map[K]V
+	closed   bool
+
+	// guard keeps reads from storing values fetched from the store before a write
+	guard *keyGuard[K]
+
+	// writes serializes writes of the same key, so the cache holds the last queued value
+	writes *keyLock[K]
+
+	// Serializes flushes, so writes to the same key reach the store in order
+	flushMu sync.Mutex
+
+	flushChan chan struct{}
+	done      chan struct{}
+	wg        sync.WaitGroup
+}
+
+func NewWriteBehind[K comparable, V any](
+	cache Cache[K, V],
+	store Store[K, V],
+	opts ...WriteBehindOption,
+) (*WriteBehind[K, V], error) {
+	if cache == nil {
+		return nil, fmt.Errorf("nil cache")
+	}
+
+	if store == nil {
+		return nil, fmt.Errorf("nil store")
+	}
+
+	o := writeBehindCompileOptions(opts...)
+	if err := o.Validate(); err != nil {
+		return nil, fmt.Errorf("options: %w", err)
+	}
+
+	wb := &WriteBehind[K, V]{
+		cache: cache,
+		store: store,
+
+		batchSize:     o.batchSize,
+		flushInterval: o.flushInterval,
+		flushTimeout:  o.flushTimeout,
+
+		maxRetries:   o.maxRetries,
+		retryBackoff: o.retryBackoff,
+
+		errorCallback: o.errorCallback,
+
+		pending: make(map[K]V),
+
+		guard:  newKeyGuard[K](),
+		writes: newKeyLock[K](),
+
+		flushChan: make(chan struct{}, 1),
+		done:      make(chan struct{}),
+	}
+
+	wb.wg.Add(1)
+	go wb.flushWorker()
+
+	return wb, nil
+}
+
+func (c *WriteBehind[K, V]) reportError(err error) {
+	if c.errorCallback != nil {
+		c.errorCallback(err)
+	}
+}
+
+func (c *WriteBehind[K, V]) flushWorker() {
+	defer c.wg.Done()
+
+	ticker := time.NewTicker(c.flushInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-c.done:
+			return
+		case <-ticker.C:
+		case <-c.flushChan:
+		}
+
+		ctx, cancel := context.WithTimeout(context.Background(), c.flushTimeout)
+		if err := c.Flush(ctx); err != nil {
+			c.reportError(err)
+		}
+		cancel()
+	}
+}
+
+// lookup returns the value of a write that wasn't flushed to the store yet, if any.
+func (c *WriteBehind[K, V]) lookup(key K) (V, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if value, ok := c.pending[key]; ok {
+		return value, true
+	}
+
+	value, ok := c.flushing[key]
+	return value, ok
+}
+
+func (c *WriteBehind[K, V]) Get(ctx context.Context, key K) (V, error) {
+	value, err := c.cache.Get(ctx, key)
+	if err == nil {
+		return value, nil
+	} else if !errors.Is(err, ErrNotFound) {
+		c.reportError(fmt.Errorf("cache get: %v: %w", key, err))
+	}
+
+	// Writes from now on keep the value fetched from the store out of the cache
+	fetch := c.guard.begin(key)
+	defer c.guard.end(fetch)
+
+	// The value might have been evicted from the cache before being flushed
+	if value, ok := c.lookup(key); ok {
+		return value, nil
+	}
+
+	value, err = c.store.Get(ctx, key)
+	if err != nil {
+		var v V
+		return v, fmt.Errorf("store get: %w", err)
+	}
+
+	// Don't overwrite a value written while fetching
+	stored := c.guard.store(fetch, func() {
+		if err := c.cache.Set(ctx, key, value); err != nil {
+			c.reportError(fmt.Errorf("cache set: %v: %w", key, err))
+		}
+	})
+	if !stored {
+		if pending, ok := c.lookup(key); ok {
+			return pending, nil
+		}
+	}
+	return value, nil
+}
+
+// Set writes the value to the cache, and queues it to be written to the store.
+// Concurrent writes of the same key are serialized, so the cache holds the value
+// that is written to the store last.
+func (c *WriteBehind[K, V]) Set(ctx context.Context, key K, value V) error {
+	defer c.writes.lock(key)()
+
+	c.mu.Lock()
+	if c.closed {
+		c.mu.Unlock()
+		return ErrClosed
+	}
+
+	c.pending[key] = value
+	full := len(c.pending) >= c.batchSize
+	c.mu.Unlock()
+
+	if full {
+		select {
+		case c.flushChan <- struct{}{}:
+		default:
+			// A flush is already pending
+		}
+	}
+
+	// Waits for reads that are storing values fetched before the write
+	c.guard.invalidate(key)
+
+	// The value still reaches the store, so cache failures aren't fatal
+	if err := c.cache.Set(ctx, key, value); err != nil {
+		c.reportError(fmt.Errorf("cache set: %v: %w", key, err))
+	}
+
+	return nil
+}
+
+// Flush writes all pending values to the store, in batches. Values that failed
+// to be written after all retries are queued again, unless they were overwritten.
+func (c *WriteBehind[K, V]) Flush(ctx context.Context) error {
+	c.flushMu.Lock()
+	defer c.flushMu.Unlock()
+
+	c.mu.Lock()
+	c.flushing = c.pending
+	c.pending = make(map[K]V)
+	values := maps.Clone(c.flushing)
+	c.mu.Unlock()
+
+	defer func() {
+		c.mu.Lock()
+		c.flushing = nil
+		c.mu.Unlock()
+	}()
+
+	for len(values) > 0 {
+		batch := make(map[K]V, min(len(values), c.batchSize))
+		for key, value := range values {
+			if len(batch) == c.batchSize {
+				break
+			}
+			batch[key] = value
+			delete(values, key)
+		}
+
+		if err := c.write(ctx, batch); err != nil {
+			c.requeue(batch)
+			c.requeue(values)
+			return fmt.Errorf("flush: %d values: %w", len(batch)+len(values), err)
+		}
+	}
+
+	return nil
+}
+
+func (c *WriteBehind[K, V]) requeue(values map[K]V) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	for key, value := range values {
+		if _, overwritten := c.pending[key]; !overwritten {
+			c.pending[key] = value
+		}
+	}
+}
+
+// write writes the values to the store, retrying failures with exponential backoff.
+// Values written successfully are removed from the map.
+func (c *WriteBehind[K, V]) write(ctx context.Context, values map[K]V) error {
+	backoff := c.retryBackoff
+
+	for attempt := 0; ; attempt++ {
+		err := c.writeOnce(ctx, values)
+		if err == nil || attempt >= c.maxRetries {
+			return err
+		}
+
+		timer := time.NewTimer(backoff)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return err
+		case <-timer.C:
+		}
+
+		backoff *= 2
+	}
+}
+
+func (c *WriteBehind[K, V]) writeOnce(ctx context.Context, values map[K]V) error {
+	if batch, ok := c.store.(BatchWriter[K, V]); ok {
+		if err := batch.WriteMany(ctx, values); err != nil {
+			return fmt.Errorf("store write many: %w", err)
+		}
+		clear(values)
+		return nil
+	}
+
+	for key, value := range values {
+		if err := c.store.Write(ctx, key, value); err != nil {
+			return fmt.Errorf("store write: %v: %w", key, err)
+		}
+		delete(values, key)
+	}
+
+	return nil
+}
+
+// Close stops the background flushes, and flushes all pending values to the store,
+// bounded by the flush timeout. Values that couldn't be written are lost,
+// and reported using the returned error. Later writes fail with ErrClosed.
+func (c *WriteBehind[K, V]) Close() error {
+	c.mu.Lock()
+	if c.closed {
+		c.mu.Unlock()
+		return nil
+	}
+	c.closed = true
+	c.mu.Unlock()
+
+	close(c.done)
+	c.wg.Wait()
+
+	ctx, cancel := context.WithTimeout(context.Background(), c.flushTimeout)
+	defer cancel()
+
+	return c.Flush(ctx)
+}
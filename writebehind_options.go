@@ -0,0 +1,114 @@
+package cachehit
+
+import (
+	"fmt"
+	"time"
+)
+
+const (
+	WriteBehindDefaultBatchSize     = 100
+	WriteBehindDefaultFlushInterval = time.Second
+	WriteBehindDefaultFlushTimeout  = 15 * time.Second
+	WriteBehindDefaultMaxRetries    = 3
+	WriteBehindDefaultRetryBackoff  = 100 * time.Millisecond
+)
+
+type writeBehindOptions struct {
+	batchSize     int
+	flushInterval time.Duration
+	flushTimeout  time.Duration
+
+	maxRetries   int
+	retryBackoff time.Duration
+
+	errorCallback ErrorCallback
+}
+
+func (o *writeBehindOptions) Validate() error {
+	if o.batchSize <= 0 {
+		return fmt.Errorf("batch size must be positive")
+	}
+
+	if o.flushInterval <= time.Duration(0) {
+		return fmt.Errorf("flush interval must be positive")
+	}
+
+	if o.flushTimeout <= time.Duration(0) {
+		return fmt.Errorf("flush timeout must be positive")
+	}
+
+	if o.maxRetries < 0 {
+		return fmt.Errorf("max retries must not be negative")
+	}
+
+	if o.retryBackoff <= time.Duration(0) {
+		return fmt.Errorf("retry backoff must be positive")
+	}
+
+	return nil
+}
+
+func writeBehindDefaultOptions() *writeBehindOptions {
+	return &writeBehindOptions{
+		batchSize:     WriteBehindDefaultBatchSize,
+		flushInterval: WriteBehindDefaultFlushInterval,
+		flushTimeout:  WriteBehindDefaultFlushTimeout,
+		maxRetries:    WriteBehindDefaultMaxRetries,
+		retryBackoff:  WriteBehindDefaultRetryBackoff,
+	}
+}
+
+func writeBehindCompileOptions(opts ...WriteBehindOption) *writeBehindOptions {
+	o := writeBehindDefaultOptions()
+
+	for _, opt := range opts {
+		opt(o)
+	}
+
+	return o
+}
+
+type WriteBehindOption func(*writeBehindOptions)
+
+// WriteBehindWithErrorCallback configures the write behind cache to call the
+// specified callback synchronously when an error happens during internal operations,
+// including background flushes that failed after all retries.
+func WriteBehindWithErrorCallback(errorCallback ErrorCallback) WriteBehindOption {
+	return func(o *writeBehindOptions) {
+		o.errorCallback = errorCallback
+	}
+}
+
+// WriteBehindWithBatchSize configures the write behind cache to flush pending
+// writes once N distinct keys are pending, and to write up to N values at once.
+func WriteBehindWithBatchSize(size int) WriteBehindOption {
+	return func(o *writeBehindOptions) {
+		o.batchSize = size
+	}
+}
+
+// WriteBehindWithFlushInterval configures the write behind cache to flush
+// pending writes every specified interval, regardless of their number.
+func WriteBehindWithFlushInterval(interval time.Duration) WriteBehindOption {
+	return func(o *writeBehindOptions) {
+		o.flushInterval = interval
+	}
+}
+
+// WriteBehindWithFlushTimeout configures the write behind cache to timeout
+// background flushes, including retries, after the specified amount of time.
+func WriteBehindWithFlushTimeout(timeout time.Duration) WriteBehindOption {
+	return func(o *writeBehindOptions) {
+		o.flushTimeout = timeout
+	}
+}
+
+// WriteBehindWithRetries configures the write behind cache to retry failed writes
+// up to maxRetries times, waiting backoff before the first retry, and doubling
+// the wait before every following one.
+func WriteBehindWithRetries(maxRetries int, backoff time.Duration) WriteBehindOption {
+	return func(o *writeBehindOptions) {
+		o.maxRetries = maxRetries
+		o.retryBackoff = backoff
+	}
+}
@@ -0,0 +1,590 @@
+package cachehit
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+
+	lru_adapter "github.com/dtrugman/cachehit/adapter/hashicorp/golang-lru/v2"
+	lru "github.com/hashicorp/golang-lru/v2"
+)
+
+type fakeStore struct {
+	mu       sync.Mutex
+	values   map[string]int
+	writes   []map[string]int
+	failures int
+}
+
+func newFakeStore() *fakeStore {
+	return &fakeStore{values: make(map[string]int)}
+}
+
+func (s *fakeStore) Get(_ context.Context, key string) (int, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	value, ok := s.values[key]
+	if !ok {
+		return 0, ErrNotFound
+	}
+	return value, nil
+}
+
+func (s *fakeStore) Write(ctx context.Context, key string, value int) error {
+	return s.WriteMany(ctx, map[string]int{key: value})
+}
+
+func (s *fakeStore) Delete(_ context.Context, key string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	delete(s.values, key)
+	return nil
+}
+
+// fail makes the next n writes fail, or all of them if n is negative.
+func (s *fakeStore) fail(n int) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.failures = n
+}
+
+func (s *fakeStore) WriteMany(_ context.Context, values map[string]int) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.failures != 0 {
+		s.failures--
+		return errors.New("failure")
+	}
+
+	batch := make(map[string]int, len(values))
+	for key, value := range values {
+		s.values[key] = value
+		batch[key] = value
+	}
+	s.writes = append(s.writes, batch)
+	return nil
+}
+
+func (s *fakeStore) snapshot() (map[string]int, []map[string]int) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	values := make(map[string]int, len(s.values))
+	for key, value := range s.values {
+		values[key] = value
+	}
+	return values, append([]map[string]int(nil), s.writes...)
+}
+
+// writer hides WriteMany, so values are written one by one
+type writer struct {
+	*fakeStore
+}
+
+func (w writer) WriteMany() {}
+
+// slowBatchStore blocks the first batch write until released, before writing it
+type slowBatchStore struct {
+	*fakeStore
+	writing chan struct{}
+	release chan struct{}
+	once    sync.Once
+}
+
+func (s *slowBatchStore) WriteMany(ctx context.Context, values map[string]int) error {
+	s.once.Do(func() {
+		close(s.writing)
+		<-s.release
+	})
+	return s.fakeStore.WriteMany(ctx, values)
+}
+
+func newWriteBehind(t *testing.T, store Store[string, int], opts ...WriteBehindOption) *WriteBehind[string, int] {
+	t.Helper()
+
+	cache, err := lru.New[string, int](128)
+	require.NoError(t, err)
+
+	wb, err := NewWriteBehind(lru_adapter.From(cache), store, opts...)
+	require.NoError(t, err)
+
+	t.Cleanup(func() {
+		wb.Close()
+	})
+
+	return wb
+}
+
+func Test_WriteBehind_New_WithInvalidOptions(t *testing.T) {
+	cache := &mockCache[string, int]{}
+	store := newFakeStore()
+
+	_, err := NewWriteBehind(nil, Store[string, int](store))
+	require.ErrorContains(t, err, "cache")
+
+	_, err = NewWriteBehind[string, int](cache, nil)
+	require.ErrorContains(t, err, "store")
+
+	tests := []struct {
+		name     string
+		opt      WriteBehindOption
+		contains string
+	}{
+		{"BatchSize", WriteBehindWithBatchSize(0), "batch size"},
+		{"FlushInterval", WriteBehindWithFlushInterval(0), "flush interval"},
+		{"FlushTimeout", WriteBehindWithFlushTimeout(0), "flush timeout"},
+		{"MaxRetries", WriteBehindWithRetries(-1, time.Second), "max retries"},
+		{"RetryBackoff", WriteBehindWithRetries(1, 0), "retry backoff"},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			_, err := NewWriteBehind(cache, Store[string, int](store), test.opt)
+			require.ErrorContains(t, err, test.contains)
+		})
+	}
+}
+
+func Test_WriteBehind_SetAndFlush(t *testing.T) {
+	ctx := t.Context()
+
+	store := newFakeStore()
+	wb := newWriteBehind(t, store, WriteBehindWithFlushInterval(time.Hour))
+
+	require.NoError(t, wb.Set(ctx, "key", 1))
+
+	value, err := wb.Get(ctx, "key")
+	require.NoError(t, err)
+	require.Equal(t, 1, value)
+
+	values, _ := store.snapshot()
+	require.Empty(t, values)
+
+	require.NoError(t, wb.Flush(ctx))
+
+	values, _ = store.snapshot()
+	require.Equal(t, map[string]int{"key": 1}, values)
+}
+
+func Test_WriteBehind_Coalescing(t *testing.T) {
+	ctx := t.Context()
+
+	store := newFakeStore()
+	wb := newWriteBehind(t, store, WriteBehindWithFlushInterval(time.Hour))
+
+	for i := range 10 {
+		require.NoError(t, wb.Set(ctx, "counter", i))
+	}
+	require.NoError(t, wb.Set(ctx, "other", 42))
+
+	require.NoError(t, wb.Flush(ctx))
+
+	_, writes := store.snapshot()
+	require.Equal(t, []map[string]int{{"counter": 9, "other": 42}}, writes)
+}
+
+func Test_WriteBehind_FlushBySize(t *testing.T) {
+	ctx := t.Context()
+
+	store := newFakeStore()
+	wb := newWriteBehind(t, store,
+		WriteBehindWithFlushInterval(time.Hour),
+		WriteBehindWithBatchSize(3))
+
+	require.NoError(t, wb.Set(ctx, "key1", 1))
+	require.NoError(t, wb.Set(ctx, "key2", 2))
+
+	time.Sleep(50 * time.Millisecond)
+	values, _ := store.snapshot()
+	require.Empty(t, values)
+
+	require.NoError(t, wb.Set(ctx, "key3", 3))
+
+	require.Eventually(t, func() bool {
+		values, _ := store.snapshot()
+		return len(values) == 3
+	}, time.Second, 10*time.Millisecond)
+}
+
+func Test_WriteBehind_FlushByTime(t *testing.T) {
+	ctx := t.Context()
+
+	store := newFakeStore()
+	wb := newWriteBehind(t, store, WriteBehindWithFlushInterval(10*time.Millisecond))
+
+	require.NoError(t, wb.Set(ctx, "key", 1))
+
+	require.Eventually(t, func() bool {
+		values, _ := store.snapshot()
+		return values["key"] == 1
+	}, time.Second, 10*time.Millisecond)
+}
+
+func Test_WriteBehind_Batches(t *testing.T) {
+	ctx := t.Context()
+
+	store := newFakeStore()
+	wb := newWriteBehind(t, store,
+		WriteBehindWithFlushInterval(time.Hour),
+		WriteBehindWithBatchSize(4))
+
+	// Hold the flush lock, so the size triggered flush waits for all values
+	wb.flushMu.Lock()
+	for i, key := range []string{"a", "b", "c", "d", "e", "f", "g", "h", "i", "j"} {
+		require.NoError(t, wb.Set(ctx, key, i))
+	}
+	wb.flushMu.Unlock()
+
+	require.NoError(t, wb.Flush(ctx))
+
+	values, writes := store.snapshot()
+	require.Len(t, values, 10)
+	for _, batch := range writes {
+		require.LessOrEqual(t, len(batch), 4)
+	}
+}
+
+func Test_WriteBehind_Writer(t *testing.T) {
+	ctx := t.Context()
+
+	store := newFakeStore()
+	wb := newWriteBehind(t, writer{store}, WriteBehindWithFlushInterval(time.Hour))
+
+	require.NoError(t, wb.Set(ctx, "key1", 1))
+	require.NoError(t, wb.Set(ctx, "key2", 2))
+	require.NoError(t, wb.Flush(ctx))
+
+	values, writes := store.snapshot()
+	require.Equal(t, map[string]int{"key1": 1, "key2": 2}, values)
+	require.Len(t, writes, 2)
+}
+
+func Test_WriteBehind_Retry(t *testing.T) {
+	ctx := t.Context()
+
+	store := newFakeStore()
+	wb := newWriteBehind(t, store,
+		WriteBehindWithFlushInterval(time.Hour),
+		WriteBehindWithRetries(3, time.Millisecond))
+
+	store.fail(3)
+
+	require.NoError(t, wb.Set(ctx, "key", 1))
+	require.NoError(t, wb.Flush(ctx))
+
+	values, _ := store.snapshot()
+	require.Equal(t, map[string]int{"key": 1}, values)
+}
+
+func Test_WriteBehind_RetryExhausted(t *testing.T) {
+	ctx := t.Context()
+
+	store := newFakeStore()
+	wb := newWriteBehind(t, store,
+		WriteBehindWithFlushInterval(time.Hour),
+		WriteBehindWithRetries(1, time.Millisecond))
+
+	store.fail(2)
+
+	require.NoError(t, wb.Set(ctx, "key1", 1))
+	require.NoError(t, wb.Set(ctx, "key2", 2))
+
+	err := wb.Flush(ctx)
+	require.ErrorContains(t, err, "flush: 2 values")
+
+	// Failed values are queued again, unless overwritten
+	require.NoError(t, wb.Set(ctx, "key2", 3))
+	require.NoError(t, wb.Flush(ctx))
+
+	values, _ := store.snapshot()
+	require.Equal(t, map[string]int{"key1": 1, "key2": 3}, values)
+}
+
+func Test_WriteBehind_ErrorCallback_BackgroundFlush(t *testing.T) {
+	ctx := t.Context()
+
+	store := newFakeStore()
+	store.fail(-1)
+
+	errorCaptured := make(chan error, 1)
+	wb := newWriteBehind(t, store,
+		WriteBehindWithFlushInterval(10*time.Millisecond),
+		WriteBehindWithRetries(0, time.Millisecond),
+		WriteBehindWithErrorCallback(func(err error) {
+			select {
+			case errorCaptured <- err:
+			default:
+			}
+		}))
+
+	require.NoError(t, wb.Set(ctx, "key", 1))
+
+	select {
+	case err := <-errorCaptured:
+		require.ErrorContains(t, err, "store write many")
+	case <-time.After(time.Second):
+		t.Fatal("error callback was not called")
+	}
+
+	store.fail(0)
+	require.Eventually(t, func() bool {
+		values, _ := store.snapshot()
+		return values["key"] == 1
+	}, time.Second, 10*time.Millisecond)
+}
+
+func Test_WriteBehind_Close(t *testing.T) {
+	ctx := t.Context()
+
+	store := newFakeStore()
+	wb := newWriteBehind(t, store, WriteBehindWithFlushInterval(time.Hour))
+
+	require.NoError(t, wb.Set(ctx, "key", 1))
+	require.NoError(t, wb.Close())
+
+	values, _ := store.snapshot()
+	require.Equal(t, map[string]int{"key": 1}, values)
+
+	require.ErrorIs(t, wb.Set(ctx, "key", 2), ErrClosed)
+	require.NoError(t, wb.Close())
+}
+
+func Test_WriteBehind_Close_Error(t *testing.T) {
+	ctx := t.Context()
+
+	store := newFakeStore()
+	store.fail(-1)
+
+	wb := newWriteBehind(t, store,
+		WriteBehindWithFlushInterval(time.Hour),
+		WriteBehindWithRetries(0, time.Millisecond))
+
+	require.NoError(t, wb.Set(ctx, "key", 1))
+	require.ErrorContains(t, wb.Close(), "flush")
+}
+
+func Test_WriteBehind_Get_Pending(t *testing.T) {
+	ctx := t.Context()
+
+	// The cache lost the value, e.g. due to eviction
+	cache := &mockCache[string, int]{}
+	cache.On("Set", ctx, "key", 1).Return(nil)
+	cache.On("Get", ctx, "key").Return(0, ErrNotFound)
+
+	store := newFakeStore()
+
+	wb, err := NewWriteBehind(cache, Store[string, int](store), WriteBehindWithFlushInterval(time.Hour))
+	require.NoError(t, err)
+	t.Cleanup(func() { wb.Close() })
+
+	require.NoError(t, wb.Set(ctx, "key", 1))
+
+	value, err := wb.Get(ctx, "key")
+	require.NoError(t, err)
+	require.Equal(t, 1, value)
+
+	cache.AssertExpectations(t)
+}
+
+func Test_WriteBehind_Get_FromStore(t *testing.T) {
+	ctx := t.Context()
+
+	cache := &mockCache[string, int]{}
+	cache.On("Get", ctx, "key").Return(0, ErrNotFound)
+	cache.On("Get", ctx, "missing").Return(0, ErrNotFound)
+	cache.On("Set", ctx, "key", 1).Return(nil)
+
+	store := newFakeStore()
+	require.NoError(t, store.Write(ctx, "key", 1))
+
+	wb, err := NewWriteBehind(cache, Store[string, int](store), WriteBehindWithFlushInterval(time.Hour))
+	require.NoError(t, err)
+	t.Cleanup(func() { wb.Close() })
+
+	value, err := wb.Get(ctx, "key")
+	require.NoError(t, err)
+	require.Equal(t, 1, value)
+
+	_, err = wb.Get(ctx, "missing")
+	require.ErrorIs(t, err, ErrNotFound)
+
+	cache.AssertExpectations(t)
+}
+
+func Test_WriteBehind_ErrorCallback_CacheSet(t *testing.T) {
+	ctx := t.Context()
+
+	cacheSetErr := errors.New("failure")
+	cache := &mockCache[string, int]{}
+	cache.On("Set", mock.Anything, "key", 1).Return(cacheSetErr)
+
+	store := newFakeStore()
+
+	var capturedErr error
+	wb, err := NewWriteBehind(cache, Store[string, int](store),
+		WriteBehindWithFlushInterval(time.Hour),
+		WriteBehindWithErrorCallback(func(err error) {
+			capturedErr = err
+		}))
+	require.NoError(t, err)
+
+	// The value still reaches the store
+	require.NoError(t, wb.Set(ctx, "key", 1))
+	require.ErrorIs(t, capturedErr, cacheSetErr)
+
+	require.NoError(t, wb.Close())
+
+	values, _ := store.snapshot()
+	require.Equal(t, map[string]int{"key": 1}, values)
+
+	cache.AssertExpectations(t)
+}
+
+func Test_WriteBehind_Get_WhileFlushing(t *testing.T) {
+	ctx := t.Context()
+
+	// The cache lost the values, e.g. due to eviction
+	cache := &mockCache[string, int]{}
+	cache.On("Set", mock.Anything, mock.Anything, mock.Anything).Return(nil)
+	cache.On("Get", mock.Anything, mock.Anything).Return(0, ErrNotFound)
+
+	store := &slowBatchStore{
+		fakeStore: newFakeStore(),
+		writing:   make(chan struct{}),
+		release:   make(chan struct{}),
+	}
+
+	wb, err := NewWriteBehind(cache, Store[string, int](store),
+		WriteBehindWithBatchSize(1),
+		WriteBehindWithFlushInterval(time.Hour))
+	require.NoError(t, err)
+	t.Cleanup(func() { wb.Close() })
+
+	// The first write triggers a background flush
+	values := map[string]int{"key1": 1, "key2": 2, "key3": 3}
+	for key, value := range values {
+		require.NoError(t, wb.Set(ctx, key, value))
+	}
+	<-store.writing
+
+	// Values being flushed are still visible, while batches are taken from them
+	var wg sync.WaitGroup
+	for range 4 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+
+			for range 100 {
+				for key, expected := range values {
+					value, err := wb.Get(ctx, key)
+					require.NoError(t, err)
+					require.Equal(t, expected, value)
+				}
+			}
+		}()
+	}
+
+	wg.Wait()
+	close(store.release)
+
+	require.NoError(t, wb.Flush(ctx))
+	stored, _ := store.snapshot()
+	require.Equal(t, values, stored)
+}
+
+func Test_WriteBehind_Set_Concurrent(t *testing.T) {
+	ctx := t.Context()
+
+	lruCache, err := lru.New[string, int](128)
+	require.NoError(t, err)
+	cache := &slowCache{
+		LRU:     lru_adapter.From(lruCache),
+		slow:    1,
+		setting: make(chan struct{}),
+		release: make(chan struct{}),
+	}
+
+	store := newFakeStore()
+
+	wb, err := NewWriteBehind[string, int](cache, store, WriteBehindWithFlushInterval(time.Hour))
+	require.NoError(t, err)
+	t.Cleanup(func() { wb.Close() })
+
+	first := make(chan error)
+	go func() {
+		first <- wb.Set(ctx, "key", 1)
+	}()
+
+	// The first write is queued, but didn't update the cache yet
+	<-cache.setting
+
+	second := make(chan error, 1)
+	go func() {
+		second <- wb.Set(ctx, "key", 2)
+	}()
+
+	// The second write waits for the first one to complete
+	require.Never(t, func() bool {
+		return len(second) > 0
+	}, 50*time.Millisecond, 5*time.Millisecond)
+
+	close(cache.release)
+	require.NoError(t, <-first)
+	require.NoError(t, <-second)
+
+	// The cache holds the value written to the store last
+	require.NoError(t, wb.Flush(ctx))
+	stored, _ := store.snapshot()
+	require.Equal(t, map[string]int{"key": 2}, stored)
+
+	cached, err := cache.Get(ctx, "key")
+	require.NoError(t, err)
+	require.Equal(t, 2, cached)
+}
+
+func Test_WriteBehind_Get_RacingWithSetAndFlush(t *testing.T) {
+	ctx := t.Context()
+
+	store := &slowStore{
+		fakeStore: newFakeStore(),
+		fetched:   make(chan struct{}),
+		release:   make(chan struct{}),
+	}
+	require.NoError(t, store.Write(ctx, "key", 1))
+
+	lruCache, err := lru.New[string, int](128)
+	require.NoError(t, err)
+	cache := lru_adapter.From(lruCache)
+
+	wb, err := NewWriteBehind[string, int](cache, store, WriteBehindWithFlushInterval(time.Hour))
+	require.NoError(t, err)
+	t.Cleanup(func() { wb.Close() })
+
+	read := make(chan int)
+	go func() {
+		value, err := wb.Get(ctx, "key")
+		require.NoError(t, err)
+		read <- value
+	}()
+
+	// The read fetched the old value, while a newer one is written and flushed
+	<-store.fetched
+	require.NoError(t, wb.Set(ctx, "key", 2))
+	require.NoError(t, wb.Flush(ctx))
+	close(store.release)
+	<-read
+
+	// The old value doesn't overwrite the cache
+	cached, err := cache.Get(ctx, "key")
+	require.NoError(t, err)
+	require.Equal(t, 2, cached)
+}